@@ -0,0 +1,56 @@
+package main
+
+import (
+	"flag"
+	"log"
+
+	"haoma/internal/application/services"
+	"haoma/internal/infrastructure/auth"
+	"haoma/internal/infrastructure/cache"
+	"haoma/internal/infrastructure/mail"
+	"haoma/internal/infrastructure/persistence"
+)
+
+// Create-admin bootstraps the first organizer account. If a player with the
+// email already exists they are promoted to admin instead.
+//
+//	go run ./cmd/create-admin -name "Organizer" -email admin@haoma.dev -password s3cret
+func main() {
+	name := flag.String("name", "Organizer", "Display name for a new admin account")
+	email := flag.String("email", "", "Email of the admin account")
+	password := flag.String("password", "", "Password for a new admin account")
+	flag.Parse()
+
+	if *email == "" {
+		log.Fatal("An -email is required")
+	}
+	if *password == "" {
+		log.Fatal("A -password is required")
+	}
+
+	passwordHasher, err := auth.PasswordHasherFromEnv()
+	if err != nil {
+		log.Fatal("Invalid password hashing settings:", err)
+	}
+
+	db, err := persistence.NewDatabase()
+	if err != nil {
+		log.Fatal("Failed to initialize database:", err)
+	}
+	defer db.Close()
+
+	tokenRepo := persistence.NewTokenRepository(db.DB)
+	revocations, err := cache.NewRevocationCache(tokenRepo)
+	if err != nil {
+		log.Fatal("Failed to load revocation list:", err)
+	}
+	authService := services.NewAuthService(persistence.NewPlayerRepository(db.DB), passwordHasher, tokenRepo, revocations, mail.NewLogMailer(), services.SignupPolicy{})
+
+	admin, err := authService.BootstrapAdmin(*name, *email, *password)
+	if err != nil {
+		log.Fatal("Failed to create admin:", err)
+	}
+
+	log.Printf("👑 %s (%s) is now an admin", admin.Name, admin.Email)
+	log.Println("🔐 Set up two-factor authentication at /api/v1/auth/mfa/totp/setup before using admin routes")
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"haoma/internal/application/services"
+	"haoma/internal/infrastructure/export"
+	"haoma/internal/infrastructure/persistence"
+)
+
+// Export writes the leaderboard or full results to a CSV or XLSX file.
+//
+//	go run ./cmd/export -report results -format xlsx -out results.xlsx
+func main() {
+	report := flag.String("report", "results", "What to export: leaderboard or results")
+	format := flag.String("format", export.FormatCSV, "Output format: csv or xlsx")
+	out := flag.String("out", "", "Output file (defaults to stdout)")
+	flag.Parse()
+
+	db, err := persistence.NewDatabase()
+	if err != nil {
+		log.Fatal("Failed to initialize database:", err)
+	}
+	defer db.Close()
+
+	service := services.NewCarnivalService(
+		persistence.NewSessionRepository(db.DB),
+		persistence.NewQuestionRepository(db.DB),
+		persistence.NewPlayerRepository(db.DB),
+		persistence.NewLeaderboardRepository(db.DB),
+		persistence.NewEventRepository(db.DB),
+		persistence.NewNodeRepository(db.DB),
+		persistence.NewAuditRepository(db.DB),
+		services.SignupPolicy{},
+	)
+
+	var table *services.ExportTable
+	switch *report {
+	case "leaderboard":
+		table, err = service.ExportLeaderboard()
+	case "results":
+		table, err = service.ExportResults()
+	default:
+		log.Fatalf("Unknown report %q (expected leaderboard or results)", *report)
+	}
+	if err != nil {
+		log.Fatal("Failed to build export:", err)
+	}
+
+	output := os.Stdout
+	if *out != "" {
+		output, err = os.Create(*out)
+		if err != nil {
+			log.Fatal("Failed to create output file:", err)
+		}
+		defer output.Close()
+	}
+
+	if err := export.Write(output, *format, table.Name, table.Headers, table.Rows); err != nil {
+		log.Fatal("Failed to write export:", err)
+	}
+
+	if *out != "" {
+		log.Printf("📜 Exported %d %s rows to %s", len(table.Rows), *report, *out)
+	}
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+
+	"haoma/internal/infrastructure/sso/mockidp"
+)
+
+// Mock-idp runs a throwaway OpenID Connect provider for trying single sign-on
+// locally. Point the server at it with OIDC_ISSUER_URL=http://localhost:9000.
+//
+//	go run ./cmd/mock-idp -addr :9000
+func main() {
+	addr := flag.String("addr", ":9000", "Address to listen on")
+	issuer := flag.String("issuer", "http://localhost:9000", "Issuer URL the provider is reachable at")
+	flag.Parse()
+
+	idp, err := mockidp.New(*issuer)
+	if err != nil {
+		log.Fatal("Failed to create mock IdP:", err)
+	}
+
+	log.Printf("🎭 Mock identity provider listening on %s (issuer %s)", *addr, *issuer)
+	log.Fatal(http.ListenAndServe(*addr, idp.Handler()))
+}
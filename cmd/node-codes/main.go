@@ -0,0 +1,117 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"haoma/internal/config"
+	"haoma/internal/domain/nodecode"
+	"haoma/internal/infrastructure/export"
+	"haoma/internal/infrastructure/persistence"
+	"haoma/internal/infrastructure/qr"
+)
+
+// Node-codes prints a signed QR payload for every active node in the
+// registry, signed with NODE_CODE_SECRET. Codes for the current event only
+// work during it. Each node also gets the link for its station screen, which
+// shows a rotating code instead when the node is listed in ROTATING_NODES.
+//
+//	go run ./cmd/node-codes -event current -out node-codes.csv
+func main() {
+	eventFlag := flag.String("event", "", "Tie the codes to an event: current, an event ID, or empty for any event")
+	fromFlag := flag.String("from", "", "Codes work from this time (RFC 3339; defaults to the event start)")
+	untilFlag := flag.String("until", "", "Codes work until this time (RFC 3339; defaults to the event end)")
+	baseURL := flag.String("base-url", fmt.Sprintf("http://localhost:%d", config.DEFAULT_PORT), "Server address used in station screen links")
+	format := flag.String("format", export.FormatCSV, "Output format: csv or xlsx")
+	out := flag.String("out", "", "Output file (defaults to stdout)")
+	flag.Parse()
+
+	signer, signed, err := qr.SignerFromEnv()
+	if err != nil {
+		log.Fatal("Invalid NODE_CODE_SECRET:", err)
+	}
+	if !signed {
+		log.Fatal("NODE_CODE_SECRET must be set to the same value the server uses")
+	}
+
+	db, err := persistence.NewDatabase()
+	if err != nil {
+		log.Fatal("Failed to initialize database:", err)
+	}
+	defer db.Close()
+
+	nodes, err := persistence.NewNodeRepository(db.DB).GetAll()
+	if err != nil {
+		log.Fatal("Failed to load the node registry:", err)
+	}
+
+	var payload nodecode.Payload
+	switch *eventFlag {
+	case "":
+	case "current":
+		current, err := persistence.NewEventRepository(db.DB).FindCurrent()
+		if err != nil {
+			log.Fatal("No current event:", err)
+		}
+		payload.EventID = &current.ID
+		payload.NotBefore, payload.NotAfter = current.StartsAt, current.EndsAt
+	default:
+		eventID, err := uuid.Parse(*eventFlag)
+		if err != nil {
+			log.Fatalf("Invalid event %q (expected current or an event ID)", *eventFlag)
+		}
+		payload.EventID = &eventID
+	}
+
+	if payload.NotBefore, err = parseTime(*fromFlag, payload.NotBefore); err != nil {
+		log.Fatal("Invalid -from:", err)
+	}
+	if payload.NotAfter, err = parseTime(*untilFlag, payload.NotAfter); err != nil {
+		log.Fatal("Invalid -until:", err)
+	}
+
+	var rows [][]interface{}
+	for _, n := range nodes {
+		if !n.Active {
+			continue
+		}
+		payload.Node = n.Number
+		key := signer.StationKey(n.Number)
+		// The key goes in the fragment, which browsers never send to the server
+		station := fmt.Sprintf("%s/station?node=%d#%s", strings.TrimRight(*baseURL, "/"), n.Number, url.Values{
+			"key": {key},
+		}.Encode())
+		rows = append(rows, []interface{}{n.Number, n.Name, signer.Sign(payload), key, station})
+	}
+
+	output := os.Stdout
+	if *out != "" {
+		output, err = os.Create(*out)
+		if err != nil {
+			log.Fatal("Failed to create output file:", err)
+		}
+		defer output.Close()
+	}
+
+	if err := export.Write(output, *format, "Node codes", []string{"Node", "Name", "Code", "Station key", "Station screen"}, rows); err != nil {
+		log.Fatal("Failed to write node codes:", err)
+	}
+
+	if *out != "" {
+		fmt.Fprintf(os.Stderr, "🔏 Wrote %d signed node codes to %s\n", len(rows), *out)
+	}
+}
+
+func parseTime(value string, fallback time.Time) (time.Time, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
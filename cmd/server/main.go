@@ -29,7 +29,10 @@ func main() {
 	}
 
 	// Initialize HTTP server
-	router := web.NewRouter()
+	router, err := web.NewRouter()
+	if err != nil {
+		log.Fatal("Invalid TRUSTED_PROXIES:", err)
+	}
 
 	// Initialize handlers
 	http.RegisterRoutes(router, db)
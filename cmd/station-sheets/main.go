@@ -0,0 +1,93 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"haoma/internal/application/services"
+	"haoma/internal/domain/nodecode"
+	"haoma/internal/infrastructure/persistence"
+	"haoma/internal/infrastructure/qr"
+)
+
+// Station-sheets renders printable signs for the nodes, using the same
+// NODE_CODE_SECRET and ROTATING_NODES as the server so the printed codes
+// are the ones it accepts: a PDF with one page per station, or bare PNG or
+// SVG QR codes (zipped unless -node picks one).
+//
+//	go run ./cmd/station-sheets -event current -out stations.pdf
+//	go run ./cmd/station-sheets -format svg -node 3 -out node-3.svg
+func main() {
+	eventFlag := flag.String("event", "", "Tie signed codes to an event: current, or empty for any event")
+	format := flag.String("format", qr.FormatPDF, "Output format: pdf, png or svg")
+	nodeFlag := flag.Int("node", 0, "Only this node (0 for all)")
+	out := flag.String("out", "", "Output file (defaults to stdout)")
+	flag.Parse()
+
+	if *eventFlag != "" && *eventFlag != "current" {
+		log.Fatalf("Invalid event %q (expected current or empty)", *eventFlag)
+	}
+
+	db, err := persistence.NewDatabase()
+	if err != nil {
+		log.Fatal("Failed to initialize database:", err)
+	}
+	defer db.Close()
+
+	service := services.NewCarnivalService(
+		persistence.NewSessionRepository(db.DB),
+		persistence.NewQuestionRepository(db.DB),
+		persistence.NewPlayerRepository(db.DB),
+		persistence.NewLeaderboardRepository(db.DB),
+		persistence.NewEventRepository(db.DB),
+		persistence.NewNodeRepository(db.DB),
+		persistence.NewAuditRepository(db.DB),
+		services.SignupPolicy{},
+	)
+
+	signer, signed, err := qr.SignerFromEnv()
+	if err != nil {
+		log.Fatal("Invalid NODE_CODE_SECRET:", err)
+	}
+	if signed {
+		service.UseSignedNodeCodes(signer)
+	}
+	rotating, rotateAll, err := qr.RotatingNodesFromEnv()
+	if err != nil {
+		log.Fatal("Invalid ROTATING_NODES:", err)
+	}
+	if len(rotating) > 0 || rotateAll {
+		service.UseRotatingNodeCodes(rotating, rotateAll, qr.RotationPeriodFromEnv())
+	}
+
+	stations, err := service.Stations(*eventFlag == "current")
+	if err != nil {
+		log.Fatal("Failed to build station sheets:", err)
+	}
+	if *nodeFlag != 0 {
+		station, ok := nodecode.FindStation(stations, *nodeFlag)
+		if !ok {
+			log.Fatalf("Unknown node %d", *nodeFlag)
+		}
+		stations = []nodecode.Station{station}
+	}
+
+	output := os.Stdout
+	if *out != "" {
+		output, err = os.Create(*out)
+		if err != nil {
+			log.Fatal("Failed to create output file:", err)
+		}
+		defer output.Close()
+	}
+
+	if err := qr.WriteStations(output, *format, stations); err != nil {
+		log.Fatal("Failed to render station sheets:", err)
+	}
+
+	if *out != "" {
+		fmt.Fprintf(os.Stderr, "🖨️ Wrote %d stations to %s\n", len(stations), *out)
+	}
+}
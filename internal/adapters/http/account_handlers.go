@@ -0,0 +1,183 @@
+package http
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"haoma/internal/application/services"
+)
+
+// UpdateProfileRequest represents a change to the player's public profile
+type UpdateProfileRequest struct {
+	Name string `json:"name" binding:"required,min=2,max=50" example:"Rostam Dastan"`
+}
+
+// ChangePasswordRequest represents replacing a known password
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required" example:"cyber_guardian_2024"`
+	NewPassword     string `json:"new_password" binding:"required,min=6" example:"simorgh_feather_2025"`
+}
+
+// DeleteAccountRequest confirms an account deletion
+type DeleteAccountRequest struct {
+	Password string `json:"password,omitempty" example:"cyber_guardian_2024"` // Not needed for guests and single sign-on accounts that never set a password
+}
+
+// UpdateProfile godoc
+// @Summary Update the player's profile
+// @Description Change the display name. Names appear on the public leaderboard, so names with blocked words or invisible characters are refused. Existing leaderboard entries are renamed too.
+// @Tags Authentication
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body UpdateProfileRequest true "New profile"
+// @Success 200 {object} PlayerInfo
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /auth/profile [patch]
+func (h *CarnivalHandler) UpdateProfile(c *gin.Context) {
+	playerID, exists := c.Get("player_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Player not authenticated"})
+		return
+	}
+
+	var req UpdateProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+		return
+	}
+
+	p, err := h.accountService.UpdateProfile(playerID.(uuid.UUID), req.Name)
+	if err != nil {
+		switch err.Error() {
+		case "name not allowed":
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Please choose a different name"})
+		case "player not found":
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Player not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update profile"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, newPlayerInfo(p))
+}
+
+// ChangePassword godoc
+// @Summary Change the password
+// @Description Replace the password after confirming the current one. Every existing login is ended, including this one; the response carries fresh tokens. Wrong current passwords count towards login throttling.
+// @Tags Authentication
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body ChangePasswordRequest true "Current and new password"
+// @Success 200 {object} LoginResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 429 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /auth/password [post]
+func (h *CarnivalHandler) ChangePassword(c *gin.Context) {
+	playerID, exists := c.Get("player_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Player not authenticated"})
+		return
+	}
+
+	var req ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+		return
+	}
+
+	p, err := h.accountService.ChangePassword(playerID.(uuid.UUID), req.CurrentPassword, req.NewPassword)
+	if err != nil {
+		var throttled *services.LoginThrottledError
+		if errors.As(err, &throttled) {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many password attempts. Please try again later."})
+			return
+		}
+		switch err.Error() {
+		case "invalid password":
+			c.JSON(http.StatusForbidden, gin.H{"error": "Current password is incorrect"})
+		case "player is a guest":
+			c.JSON(http.StatusConflict, gin.H{"error": "Guests have no password; claim your account first"})
+		case "player not found":
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Player not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change password"})
+		}
+		return
+	}
+
+	response, err := h.issueLogin(c, p, secondFactorFromClaims(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Password changed, but failed to generate tokens"})
+		return
+	}
+	response.Message = "🔐 Password changed. Other devices have been logged out."
+
+	c.JSON(http.StatusOK, response)
+}
+
+// DeleteAccount godoc
+// @Summary Delete the player's account
+// @Description Remove the player's personal data and log them out everywhere. Scores stay on the leaderboard as "Deleted player" so event statistics remain intact. Accounts with a password must confirm with it, including single sign-on accounts that also have one. Wrong passwords count towards login throttling.
+// @Tags Authentication
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body DeleteAccountRequest false "Password confirmation"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 429 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /auth/account [delete]
+func (h *CarnivalHandler) DeleteAccount(c *gin.Context) {
+	playerID, exists := c.Get("player_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Player not authenticated"})
+		return
+	}
+
+	var req DeleteAccountRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+			return
+		}
+	}
+
+	if err := h.accountService.DeleteAccount(playerID.(uuid.UUID), req.Password); err != nil {
+		var throttled *services.LoginThrottledError
+		if errors.As(err, &throttled) {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many password attempts. Please try again later."})
+			return
+		}
+		switch err.Error() {
+		case "invalid password":
+			c.JSON(http.StatusForbidden, gin.H{"error": "Password is incorrect"})
+		case "player not found":
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Player not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete account"})
+		}
+		return
+	}
+
+	h.cookies.ClearTokens(c)
+	c.JSON(http.StatusOK, gin.H{"message": "🎪 Your account has been deleted. Farewell, traveler."})
+}
@@ -0,0 +1,216 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"haoma/internal/domain/leaderboard"
+	"haoma/internal/domain/player"
+)
+
+// ChangeRoleRequest represents assigning a role to a player
+type ChangeRoleRequest struct {
+	Role string `json:"role" binding:"required" example:"staff"`
+}
+
+// RevealStepResponse represents one uncovered rank change during the reveal
+type RevealStepResponse struct {
+	Done       bool   `json:"done" example:"false"`
+	PlayerName string `json:"player_name,omitempty" example:"Rostam"`
+	FinalScore int    `json:"final_score,omitempty" example:"850"`
+	FromRank   int    `json:"from_rank,omitempty" example:"7"`
+	ToRank     int    `json:"to_rank,omitempty" example:"2"`
+}
+
+// GetLiveLeaderboard godoc
+// @Summary Get the live leaderboard
+// @Description Retrieve the real ranking even while the public board is frozen
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} LeaderboardResponse
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /admin/leaderboard [get]
+func (h *CarnivalHandler) GetLiveLeaderboard(c *gin.Context) {
+	view, err := h.service.GetLeaderboard(true)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, newLeaderboardResponse(view))
+}
+
+// UnfreezeLeaderboard godoc
+// @Summary Unfreeze the public leaderboard
+// @Description Reveal every hidden rank change at once
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /admin/leaderboard/unfreeze [post]
+func (h *CarnivalHandler) UnfreezeLeaderboard(c *gin.Context) {
+	if err := h.service.UnfreezeLeaderboard(); err != nil {
+		respondFreezeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "🎪 The leaderboard has been unveiled!"})
+}
+
+// RevealLeaderboardStep godoc
+// @Summary Reveal the next frozen rank change
+// @Description Step through hidden rank changes from the bottom of the frozen board upward. The board unfreezes once nothing is left to reveal.
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} RevealStepResponse
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /admin/leaderboard/reveal [post]
+func (h *CarnivalHandler) RevealLeaderboardStep(c *gin.Context) {
+	step, err := h.service.RevealNextLeaderboardChange()
+	if err != nil {
+		respondFreezeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newRevealStepResponse(step))
+}
+
+// RebuildLeaderboardCache godoc
+// @Summary Rebuild the leaderboard cache
+// @Description Compare the in-memory ranking with the database and reload it, reporting how many entries had drifted
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /admin/leaderboard/rebuild [post]
+func (h *CarnivalHandler) RebuildLeaderboardCache(c *gin.Context) {
+	drift, err := h.leaderboardCache.Rebuild()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"drifted_entries": drift})
+}
+
+// RevokePlayerTokens godoc
+// @Summary Revoke all of a player's tokens
+// @Description Immediately reject every access and refresh token the player holds, e.g. after catching them cheating. The player can log in again afterwards.
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Player ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /admin/players/{id}/revoke-tokens [post]
+func (h *CarnivalHandler) RevokePlayerTokens(c *gin.Context) {
+	playerID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid player ID"})
+		return
+	}
+
+	if err := h.authService.RevokeAllPlayerTokens(playerID); err != nil {
+		if err.Error() == "player not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Player not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke tokens"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "All tokens for this player have been revoked"})
+}
+
+// ChangePlayerRole godoc
+// @Summary Change a player's role
+// @Description Grant a player the player, staff or admin role. Their existing tokens are revoked so the new role applies from their next login.
+// @Tags Admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Player ID"
+// @Param request body ChangeRoleRequest true "New role"
+// @Success 200 {object} PlayerInfo
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /admin/players/{id}/role [put]
+func (h *CarnivalHandler) ChangePlayerRole(c *gin.Context) {
+	playerID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid player ID"})
+		return
+	}
+
+	var req ChangeRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	role, err := player.ParseRole(req.Role)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Role must be one of player, staff or admin"})
+		return
+	}
+
+	p, err := h.authService.ChangeRole(playerID, role)
+	if err != nil {
+		if err.Error() == "player not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Player not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change role"})
+		return
+	}
+
+	c.JSON(http.StatusOK, newPlayerInfo(p))
+}
+
+func newRevealStepResponse(step *leaderboard.RevealStep) RevealStepResponse {
+	if step == nil {
+		return RevealStepResponse{Done: true}
+	}
+
+	return RevealStepResponse{
+		PlayerName: step.Entry.PlayerName,
+		FinalScore: step.Entry.FinalScore,
+		FromRank:   step.FromRank,
+		ToRank:     step.ToRank,
+	}
+}
+
+func respondFreezeError(c *gin.Context, err error) {
+	switch err.Error() {
+	case "event not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": "No event has started yet"})
+	case "leaderboard not frozen":
+		c.JSON(http.StatusConflict, gin.H{"error": "The leaderboard is not frozen"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
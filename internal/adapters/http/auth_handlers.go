@@ -1,11 +1,18 @@
 package http
 
 import (
+	"errors"
+	"io"
+	"log"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 
+	"haoma/internal/application/services"
 	"haoma/internal/config"
 	"haoma/internal/domain/player"
 	"haoma/internal/infrastructure/auth"
@@ -20,10 +27,11 @@ type SignupRequest struct {
 
 // SignupResponse represents the response after successful registration
 type SignupResponse struct {
-	ID      uuid.UUID `json:"id" example:"550e8400-e29b-41d4-a716-446655440000"`
-	Name    string    `json:"name" example:"Rostam Dastan"`
-	Email   string    `json:"email" example:"rostam@haoma.dev"`
-	Message string    `json:"message" example:"Welcome to Haoma's carnival! Your account has been created."`
+	ID            uuid.UUID `json:"id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Name          string    `json:"name" example:"Rostam Dastan"`
+	Email         string    `json:"email" example:"rostam@haoma.dev"`
+	EmailVerified bool      `json:"email_verified" example:"false"`
+	Message       string    `json:"message" example:"Welcome to Haoma's carnival! Your account has been created."`
 }
 
 // LoginRequest represents the user login request
@@ -32,31 +40,56 @@ type LoginRequest struct {
 	Password string `json:"password" binding:"required" example:"cyber_guardian_2024"`
 }
 
-// LoginResponse represents the response after successful login
+// LoginResponse represents the response after successful login. In cookie
+// mode the tokens are left out and csrf_token is set instead.
 type LoginResponse struct {
-	Player      PlayerInfo `json:"player"`
-	AccessToken string     `json:"access_token" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
-	TokenType   string     `json:"token_type" example:"Bearer"`
-	ExpiresIn   int        `json:"expires_in" example:"86400"` // seconds
-	Message     string     `json:"message" example:"Welcome back to the carnival!"`
+	Player           PlayerInfo `json:"player"`
+	AccessToken      string     `json:"access_token,omitempty" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+	RefreshToken     string     `json:"refresh_token,omitempty" example:"q3Zb1u2y8Xk0vJ4lWcT7sN9aRfE5dHgP6mYoK1iLzQw"`
+	CSRFToken        string     `json:"csrf_token,omitempty" example:"mV3k9QpZ2xYr7LwT4bNc8dFh1jKs6uEa0oGi5yRt3Xw"` // Echo in X-CSRF-Token
+	TokenType        string     `json:"token_type" example:"Bearer"`
+	ExpiresIn        int        `json:"expires_in" example:"900"`            // seconds
+	RefreshExpiresIn int        `json:"refresh_expires_in" example:"604800"` // seconds
+	Message          string     `json:"message" example:"Welcome back to the carnival!"`
+}
+
+// RefreshRequest represents exchanging a refresh token for new tokens. In
+// cookie mode the token comes from the refresh cookie instead.
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" example:"q3Zb1u2y8Xk0vJ4lWcT7sN9aRfE5dHgP6mYoK1iLzQw"`
+}
+
+// TokenResponse represents a rotated access and refresh token pair
+type TokenResponse struct {
+	AccessToken      string `json:"access_token,omitempty" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+	RefreshToken     string `json:"refresh_token,omitempty" example:"Xy7pQ2wE9rT4uI1oP6aS3dF8gH5jK0lZ"`
+	CSRFToken        string `json:"csrf_token,omitempty" example:"mV3k9QpZ2xYr7LwT4bNc8dFh1jKs6uEa0oGi5yRt3Xw"` // Echo in X-CSRF-Token
+	TokenType        string `json:"token_type" example:"Bearer"`
+	ExpiresIn        int    `json:"expires_in" example:"900"`            // seconds
+	RefreshExpiresIn int    `json:"refresh_expires_in" example:"604800"` // seconds
 }
 
 // PlayerInfo represents basic player information (no sensitive data)
 type PlayerInfo struct {
-	ID    uuid.UUID `json:"id" example:"550e8400-e29b-41d4-a716-446655440000"`
-	Name  string    `json:"name" example:"Rostam Dastan"`
-	Email string    `json:"email" example:"rostam@haoma.dev"`
+	ID            uuid.UUID `json:"id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Name          string    `json:"name" example:"Rostam Dastan"`
+	Email         string    `json:"email" example:"rostam@haoma.dev"`
+	Role          string    `json:"role" example:"player"`
+	EmailVerified bool      `json:"email_verified" example:"true"`
+	Guest         bool      `json:"guest" example:"false"`
+	MFAEnabled    bool      `json:"mfa_enabled" example:"false"`
 }
 
 // Signup godoc
 // @Summary Register a new player for the carnival
-// @Description Create a new player account with name, email and password
+// @Description Create a new player account with name, email and password. When verification is required, a confirmation link is emailed and the player cannot start sessions until they follow it. Signing up with a pre-registered roster address leaves that account untouched until the link is followed; only then does the new password take effect.
 // @Tags Authentication
 // @Accept json
 // @Produce json
 // @Param request body SignupRequest true "Player registration information"
 // @Success 201 {object} SignupResponse
 // @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
 // @Failure 409 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
 // @Router /auth/signup [post]
@@ -67,39 +100,58 @@ func (h *CarnivalHandler) Signup(c *gin.Context) {
 		return
 	}
 
-	newPlayer, err := player.NewPlayer(req.Name, req.Email, req.Password)
+	newPlayer, err := h.authService.Register(req.Name, req.Email, req.Password, getEmailVerificationURL())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create player"})
+		switch err.Error() {
+		case "player already exists":
+			c.JSON(http.StatusConflict, gin.H{"error": "Email already registered"})
+		case "email domain not allowed":
+			c.JSON(http.StatusForbidden, gin.H{"error": "Please sign up with your university email address"})
+		case "name not allowed":
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Please choose a different name"})
+		case "email not on roster":
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only enrolled students can sign up for this event"})
+		case "activation code required":
+			c.JSON(http.StatusConflict, gin.H{"error": "Your account was pre-registered; activate it with your activation code"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create player"})
+		}
 		return
 	}
 
-	if err := h.service.CreatePlayer(newPlayer); err != nil {
-		if err.Error() == "player already exists" {
-			c.JSON(http.StatusConflict, gin.H{"error": "Email already registered"})
-			return
+	message := "🎪 Welcome to Haoma's carnival! Your account has been created."
+	if newPlayer.IsInvited() {
+		// The claim's verification email has gone out already
+		message = "🎪 Welcome to Haoma's carnival! Check your email to confirm it is you and finish claiming your pre-registered account."
+	} else if !newPlayer.IsVerified() {
+		// The player can ask for another email, so a failed send does not fail signup
+		if err := h.authService.SendVerificationEmail(newPlayer.ID, getEmailVerificationURL()); err != nil {
+			log.Printf("Failed to send verification email: %v", err)
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save player"})
-		return
+		message = "🎪 Welcome to Haoma's carnival! Check your email to verify your account before playing."
 	}
 
 	c.JSON(http.StatusCreated, SignupResponse{
-		ID:      newPlayer.ID,
-		Name:    newPlayer.Name,
-		Email:   newPlayer.Email,
-		Message: "🎪 Welcome to Haoma's carnival! Your account has been created.",
+		ID:            newPlayer.ID,
+		Name:          newPlayer.Name,
+		Email:         newPlayer.Email,
+		EmailVerified: newPlayer.IsVerified(),
+		Message:       message,
 	})
 }
 
 // Login godoc
 // @Summary Authenticate a player
-// @Description Login with email and password to verify player credentials
+// @Description Login with email and password to verify player credentials. Repeated failures for an account or client IP back off exponentially and then lock out temporarily (429 with Retry-After). Accounts with two-factor authentication get a challenge token instead (202), to be completed at /auth/mfa/verify.
 // @Tags Authentication
 // @Accept json
 // @Produce json
 // @Param request body LoginRequest true "Player login credentials"
 // @Success 200 {object} LoginResponse
+// @Success 202 {object} MFAChallengeResponse
 // @Failure 400 {object} map[string]interface{}
 // @Failure 401 {object} map[string]interface{}
+// @Failure 429 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
 // @Router /auth/login [post]
 func (h *CarnivalHandler) Login(c *gin.Context) {
@@ -109,60 +161,233 @@ func (h *CarnivalHandler) Login(c *gin.Context) {
 		return
 	}
 
-	p, err := h.service.GetPlayerByEmail(req.Email)
+	p, err := h.loginService.Login(req.Email, req.Password, c.ClientIP(), c.Request.UserAgent())
 	if err != nil {
+		var throttled *services.LoginThrottledError
+		if errors.As(err, &throttled) {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many login attempts. Please try again later."})
+			return
+		}
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
 		return
 	}
 
-	if !p.ValidatePassword(req.Password) {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+	if p.HasTOTP() {
+		challenge, err := h.issueChallenge(p)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate tokens"})
+			return
+		}
+		c.JSON(http.StatusAccepted, challenge)
+		return
+	}
+
+	response, err := h.issueLogin(c, p, false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate tokens"})
 		return
 	}
 
-	jwtService := auth.NewJWTService(getJWTSecret())
-	accessToken, err := jwtService.GeneratePlayerToken(p.ID, p.Name, p.Email)
+	c.JSON(http.StatusOK, response)
+}
+
+// issueLogin starts a new token family for an authenticated player.
+// secondFactor says whether the login passed two-factor authentication.
+// Clients in cookie mode get the tokens as cookies instead of in the body.
+func (h *CarnivalHandler) issueLogin(c *gin.Context, p *player.Player, secondFactor bool) (*LoginResponse, error) {
+	refresh, err := h.authService.IssueRefreshToken(p.ID, secondFactor)
+	if err != nil {
+		return nil, err
+	}
+
+	accessToken, err := h.jwtService.GeneratePlayerToken(p, refresh.FamilyID, secondFactor)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &LoginResponse{
+		Player:           newPlayerInfo(p),
+		AccessToken:      accessToken,
+		RefreshToken:     refresh.Value,
+		TokenType:        "Bearer",
+		ExpiresIn:        config.JWT_EXPIRY_SECONDS,
+		RefreshExpiresIn: int(config.REFRESH_TOKEN_EXPIRY.Seconds()),
+		Message:          "🎪 Welcome back to the carnival!",
+	}
+
+	if h.cookies.Wants(c) {
+		if response.CSRFToken, err = h.setTokenCookies(c, accessToken, refresh.Value); err != nil {
+			return nil, err
+		}
+		response.AccessToken, response.RefreshToken = "", ""
+	}
+
+	return response, nil
+}
+
+// setTokenCookies hands tokens to a cookie mode client and returns the CSRF
+// token it has to echo
+func (h *CarnivalHandler) setTokenCookies(c *gin.Context, accessToken, refreshToken string) (string, error) {
+	return h.cookies.SetTokens(c, accessToken, config.JWT_EXPIRY_SECONDS, refreshToken, int(config.REFRESH_TOKEN_EXPIRY.Seconds()))
+}
+
+// RefreshToken godoc
+// @Summary Rotate the refresh token
+// @Description Exchange a refresh token for a new access token and a new refresh token. Each refresh token works once; reusing an already rotated token revokes every token from the same login. Without a refresh_token in the body the refresh cookie is used, and the new tokens are always set as cookies again.
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param request body RefreshRequest true "Refresh token"
+// @Success 200 {object} TokenResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /auth/refresh [post]
+func (h *CarnivalHandler) RefreshToken(c *gin.Context) {
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+		return
+	}
+
+	fromCookie := req.RefreshToken == ""
+	if fromCookie {
+		// Cookie mode: the browser sends the token, so the CSRF token must match
+		cookie, err := c.Cookie(auth.RefreshCookie)
+		if err != nil || cookie == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: refresh_token is required"})
+			return
+		}
+		if !auth.ValidCSRF(c) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Missing or invalid CSRF token"})
+			return
+		}
+		req.RefreshToken = cookie
+	}
+
+	refresh, p, err := h.authService.RotateRefreshToken(req.RefreshToken)
+	if err != nil {
+		if err.Error() == "invalid refresh token" || err.Error() == "refresh token reuse detected" {
+			h.cookies.ClearTokens(c)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired refresh token"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh token"})
+		return
+	}
+
+	accessToken, err := h.jwtService.GeneratePlayerToken(p, refresh.FamilyID, refresh.SecondFactor)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate access token"})
 		return
 	}
 
-	c.JSON(http.StatusOK, LoginResponse{
-		Player: PlayerInfo{
-			ID:    p.ID,
-			Name:  p.Name,
-			Email: p.Email,
-		},
-		AccessToken: accessToken,
-		TokenType:   "Bearer",
-		ExpiresIn:   config.JWT_EXPIRY_SECONDS,
-		Message:     "🎪 Welcome back to the carnival!",
-	})
+	response := TokenResponse{
+		AccessToken:      accessToken,
+		RefreshToken:     refresh.Value,
+		TokenType:        "Bearer",
+		ExpiresIn:        config.JWT_EXPIRY_SECONDS,
+		RefreshExpiresIn: int(config.REFRESH_TOKEN_EXPIRY.Seconds()),
+	}
+
+	// A token that came from the cookie goes back into one, whatever the
+	// request asked for; script must never see it
+	if fromCookie || h.cookies.Wants(c) {
+		if response.CSRFToken, err = h.setTokenCookies(c, accessToken, refresh.Value); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh token"})
+			return
+		}
+		response.AccessToken, response.RefreshToken = "", ""
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// Logout godoc
+// @Summary Log out of the current login
+// @Description Revoke the presented access token and every refresh token issued since this login
+// @Tags Authentication
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /auth/logout [post]
+func (h *CarnivalHandler) Logout(c *gin.Context) {
+	claims, exists := c.Get("player_claims")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Player not authenticated"})
+		return
+	}
+
+	playerClaims := claims.(*auth.PlayerClaims)
+	if err := h.authService.RevokeRefreshFamily(playerClaims.FamilyID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+		return
+	}
+
+	expiresAt := time.Now().Add(config.JWT_EXPIRY)
+	if playerClaims.ExpiresAt != nil {
+		expiresAt = playerClaims.ExpiresAt.Time
+	}
+
+	if err := h.authService.RevokeAccessToken(playerClaims.ID, playerClaims.PlayerID, expiresAt); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+		return
+	}
+
+	h.cookies.ClearTokens(c)
+	c.JSON(http.StatusOK, gin.H{"message": "🎪 Farewell, until the carnival calls again."})
 }
 
 // GetProfile godoc
 // @Summary Get authenticated player profile information
-// @Description Retrieve player profile from JWT token
+// @Description Retrieve the authenticated player's profile, including their role and whether their email is verified
 // @Tags Authentication
 // @Security BearerAuth
 // @Produce json
 // @Success 200 {object} PlayerInfo
 // @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
 // @Router /auth/profile [get]
 func (h *CarnivalHandler) GetProfile(c *gin.Context) {
-
 	playerID, exists := c.Get("player_id")
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Player not authenticated"})
 		return
 	}
 
-	playerName, _ := c.Get("player_name")
-	playerEmail, _ := c.Get("player_email")
+	p, err := h.service.GetPlayerByID(playerID.(uuid.UUID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Player not found"})
+		return
+	}
 
-	c.JSON(http.StatusOK, PlayerInfo{
-		ID:    playerID.(uuid.UUID),
-		Name:  playerName.(string),
-		Email: playerEmail.(string),
-	})
+	c.JSON(http.StatusOK, newPlayerInfo(p))
+}
+
+func newPlayerInfo(p *player.Player) PlayerInfo {
+	info := PlayerInfo{
+		ID:            p.ID,
+		Name:          p.Name,
+		Email:         p.Email,
+		Role:          string(p.Role),
+		EmailVerified: p.IsVerified(),
+		Guest:         p.IsGuest(),
+		MFAEnabled:    p.HasTOTP(),
+	}
+	if p.IsGuest() {
+		info.Email = "" // Only a placeholder
+	}
+	return info
+}
+
+// GetJWKS serves the public signing keys at /.well-known/jwks.json so
+// companion services can verify access tokens without sharing a secret. It
+// sits outside /api/v1 where JWKS clients expect it, so it is left out of the
+// Swagger docs.
+func (h *CarnivalHandler) GetJWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.jwtService.JWKS())
 }
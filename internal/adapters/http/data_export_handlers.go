@@ -0,0 +1,135 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"haoma/internal/application/services"
+	"haoma/internal/config"
+	"haoma/internal/infrastructure/export"
+	"haoma/internal/infrastructure/jobs"
+)
+
+// DataExportStatusResponse reports a personal data export that is still being built
+type DataExportStatusResponse struct {
+	Status  string `json:"status" example:"pending"`
+	Message string `json:"message" example:"Your data export is being prepared. Try again in a few seconds."`
+}
+
+// ExportMyData godoc
+// @Summary Download a copy of your data
+// @Description Everything stored about the player: profile, sessions, every answer with its question and explanation, and leaderboard entries. The archive is built in the background: the first call returns 202 with Retry-After, and calling again once it is ready downloads it. Finished exports are kept for a few minutes.
+// @Tags Players
+// @Security BearerAuth
+// @Produce json
+// @Produce application/zip
+// @Param format query string false "Archive format" Enums(json, zip) default(json)
+// @Success 200 {file} file
+// @Success 202 {object} DataExportStatusResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /players/me/export [get]
+func (h *CarnivalHandler) ExportMyData(c *gin.Context) {
+	playerID, exists := c.Get("player_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Player not authenticated"})
+		return
+	}
+	id := playerID.(uuid.UUID)
+
+	format := strings.ToLower(c.DefaultQuery("format", "json"))
+	if format != "json" && format != "zip" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Format must be json or zip"})
+		return
+	}
+
+	job := h.dataExports.Start(id.String()+"."+format, func() ([]byte, error) {
+		data, err := h.service.ExportPlayerData(id)
+		if err != nil {
+			return nil, err
+		}
+		return renderPlayerData(data, format)
+	})
+
+	switch job.Status {
+	case jobs.StatusPending:
+		c.Header("Retry-After", strconv.Itoa(config.DATA_EXPORT_POLL_SECONDS))
+		c.JSON(http.StatusAccepted, DataExportStatusResponse{
+			Status:  string(job.Status),
+			Message: "Your data export is being prepared. Try again in a few seconds.",
+		})
+	case jobs.StatusFailed:
+		log.Printf("Failed to export data of player %s: %v", id, job.Err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export your data"})
+	default:
+		contentType := "application/json"
+		if format == "zip" {
+			contentType = "application/zip"
+		}
+		filename := "haoma-my-data-" + job.FinishedAt.UTC().Format("20060102-150405") + "." + format
+		c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+		c.Data(http.StatusOK, contentType, job.Result)
+	}
+}
+
+// renderPlayerData writes the export as one JSON document, or as a ZIP with
+// a JSON file per section plus the answers as CSV
+func renderPlayerData(data *services.PlayerData, format string) ([]byte, error) {
+	if format == "json" {
+		return json.MarshalIndent(data, "", "  ")
+	}
+
+	sections := []struct {
+		name  string
+		value interface{}
+	}{
+		{"profile.json", data.Profile},
+		{"sessions.json", data.Sessions},
+		{"attempts.json", data.Attempts},
+		{"leaderboard.json", data.LeaderboardEntries},
+		{"flagged_scans.json", data.FlaggedScans},
+	}
+
+	var files []export.File
+	for _, section := range sections {
+		content, err := json.MarshalIndent(section.value, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, export.File{Name: section.name, Data: content})
+	}
+
+	rows := make([][]interface{}, len(data.Attempts))
+	for i, attempt := range data.Attempts {
+		rows[i] = []interface{}{
+			attempt.AttemptAt.UTC().Format(time.RFC3339),
+			attempt.SessionID.String(),
+			attempt.Category,
+			attempt.Question,
+			attempt.Answer,
+			attempt.IsCorrect,
+			attempt.Explanation,
+		}
+	}
+	var attemptsCSV bytes.Buffer
+	headers := []string{"Answered At", "Session ID", "Category", "Question", "Your Answer", "Correct", "Explanation"}
+	if err := export.WriteCSV(&attemptsCSV, headers, rows); err != nil {
+		return nil, err
+	}
+	files = append(files, export.File{Name: "attempts.csv", Data: attemptsCSV.Bytes()})
+
+	var archive bytes.Buffer
+	if err := export.WriteZip(&archive, files); err != nil {
+		return nil, err
+	}
+	return archive.Bytes(), nil
+}
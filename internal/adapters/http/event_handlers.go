@@ -0,0 +1,143 @@
+package http
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"haoma/internal/domain/event"
+)
+
+// CreateEventRequest represents scheduling a new carnival event
+type CreateEventRequest struct {
+	Title         string    `json:"title" binding:"required,min=2,max=100" example:"ELECOMP 1404 Carnival Night"`
+	StartsAt      time.Time `json:"starts_at" binding:"required" example:"2025-09-18T16:00:00Z"`
+	EndsAt        time.Time `json:"ends_at" binding:"required" example:"2025-09-18T20:00:00Z"`
+	FreezeMinutes int       `json:"freeze_minutes" binding:"min=0" example:"30"`
+	TieBreakers   []string  `json:"tie_breakers,omitempty" example:"time_penalty,completion_time"`
+}
+
+// UpdateTieBreakersRequest represents changing how tied scores are ordered
+type UpdateTieBreakersRequest struct {
+	TieBreakers []string `json:"tie_breakers" binding:"required" example:"nodes_completed,phdt_accuracy,achieved_at"`
+}
+
+// EventResponse represents a scheduled carnival event
+type EventResponse struct {
+	ID            uuid.UUID `json:"id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Title         string    `json:"title" example:"ELECOMP 1404 Carnival Night"`
+	StartsAt      string    `json:"starts_at" example:"2025-09-18T16:00:00Z"`
+	EndsAt        string    `json:"ends_at" example:"2025-09-18T20:00:00Z"`
+	FreezeMinutes int       `json:"freeze_minutes" example:"30"`
+	Frozen        bool      `json:"frozen" example:"false"`
+	TieBreakers   []string  `json:"tie_breakers" example:"completion_time"`
+}
+
+// CreateEvent godoc
+// @Summary Schedule a carnival event
+// @Description Create an event with its time window, leaderboard freeze period and tie-break chain. Tie-breakers are applied in order after the final score: completion_time, time_penalty, nodes_completed, phdt_accuracy, achieved_at.
+// @Tags Admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body CreateEventRequest true "Event details"
+// @Success 201 {object} EventResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /admin/events [post]
+func (h *CarnivalHandler) CreateEvent(c *gin.Context) {
+	var req CreateEventRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+		return
+	}
+
+	newEvent, err := h.service.CreateEvent(req.Title, req.StartsAt, req.EndsAt, req.FreezeMinutes, req.TieBreakers)
+	if err != nil {
+		if err.Error() == "event must end after it starts" || err.Error() == "invalid freeze window" || err.Error() == "invalid tie-breakers" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create event"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, newEventResponse(newEvent))
+}
+
+// GetCurrentEvent godoc
+// @Summary Get the current carnival event
+// @Description Retrieve the most recently started event and its freeze status
+// @Tags Events
+// @Produce json
+// @Success 200 {object} EventResponse
+// @Failure 404 {object} map[string]interface{}
+// @Router /events/current [get]
+func (h *CarnivalHandler) GetCurrentEvent(c *gin.Context) {
+	currentEvent, err := h.service.GetCurrentEvent()
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No event has started yet"})
+		return
+	}
+
+	c.JSON(http.StatusOK, newEventResponse(currentEvent))
+}
+
+// UpdateTieBreakers godoc
+// @Summary Change the current event's tie-break chain
+// @Description Replace the rules used, in order, to rank entries with equal final scores: completion_time, time_penalty, nodes_completed, phdt_accuracy, achieved_at
+// @Tags Admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body UpdateTieBreakersRequest true "Tie-break chain"
+// @Success 200 {object} EventResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /admin/events/current/tie-breakers [put]
+func (h *CarnivalHandler) UpdateTieBreakers(c *gin.Context) {
+	var req UpdateTieBreakersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+		return
+	}
+
+	currentEvent, err := h.service.UpdateTieBreakers(req.TieBreakers)
+	if err != nil {
+		switch err.Error() {
+		case "event not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": "No event has started yet"})
+		case "invalid tie-breakers":
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update tie-breakers"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, newEventResponse(currentEvent))
+}
+
+func newEventResponse(currentEvent *event.Event) EventResponse {
+	tieBreakers := make([]string, len(currentEvent.Ranking()))
+	for i, tieBreaker := range currentEvent.Ranking() {
+		tieBreakers[i] = string(tieBreaker)
+	}
+
+	return EventResponse{
+		ID:            currentEvent.ID,
+		Title:         currentEvent.Title,
+		StartsAt:      currentEvent.StartsAt.UTC().Format("2006-01-02T15:04:05Z"),
+		EndsAt:        currentEvent.EndsAt.UTC().Format("2006-01-02T15:04:05Z"),
+		FreezeMinutes: currentEvent.FreezeMinutes,
+		Frozen:        currentEvent.IsFrozen(time.Now()),
+		TieBreakers:   tieBreakers,
+	}
+}
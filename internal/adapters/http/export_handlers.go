@@ -0,0 +1,74 @@
+package http
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"haoma/internal/application/services"
+	"haoma/internal/infrastructure/export"
+)
+
+// ExportLeaderboard godoc
+// @Summary Export the full leaderboard
+// @Description Download the complete live ranking as CSV or XLSX
+// @Tags Admin
+// @Security BearerAuth
+// @Produce text/csv
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param format query string false "Export format (csv or xlsx)" Enums(csv, xlsx) default(csv)
+// @Success 200 {file} file
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /admin/export/leaderboard [get]
+func (h *CarnivalHandler) ExportLeaderboard(c *gin.Context) {
+	h.writeExport(c, h.service.ExportLeaderboard)
+}
+
+// ExportResults godoc
+// @Summary Export per-player results
+// @Description Download gradebook-ready results with per-node scores, per-category accuracy, time penalty and completion time
+// @Tags Admin
+// @Security BearerAuth
+// @Produce text/csv
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param format query string false "Export format (csv or xlsx)" Enums(csv, xlsx) default(csv)
+// @Success 200 {file} file
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /admin/export/results [get]
+func (h *CarnivalHandler) ExportResults(c *gin.Context) {
+	h.writeExport(c, h.service.ExportResults)
+}
+
+func (h *CarnivalHandler) writeExport(c *gin.Context, build func() (*services.ExportTable, error)) {
+	format := strings.ToLower(c.DefaultQuery("format", export.FormatCSV))
+	if format != export.FormatCSV && format != export.FormatXLSX {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Format must be csv or xlsx"})
+		return
+	}
+
+	table, err := build()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Render into memory first so a failure can still produce a JSON error
+	var buf bytes.Buffer
+	if err := export.Write(&buf, format, table.Name, table.Headers, table.Rows); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render export"})
+		return
+	}
+
+	filename := "haoma-" + strings.ToLower(table.Name) + "-" + time.Now().UTC().Format("20060102-150405") + "." + format
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Data(http.StatusOK, export.ContentType(format), buf.Bytes())
+}
@@ -0,0 +1,126 @@
+package http
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// GuestRequest represents a visitor joining with just a nickname
+type GuestRequest struct {
+	Nickname string `json:"nickname" binding:"required,min=2,max=30" example:"Simorgh"`
+}
+
+// ClaimGuestRequest represents turning a guest into a full account
+type ClaimGuestRequest struct {
+	Name     string `json:"name,omitempty" binding:"omitempty,min=2,max=50" example:"Rostam Dastan"`
+	Email    string `json:"email" binding:"required,email" example:"rostam@haoma.dev"`
+	Password string `json:"password" binding:"required,min=6" example:"cyber_guardian_2024"`
+}
+
+// PlayAsGuest godoc
+// @Summary Play as a guest
+// @Description Join with only a nickname, e.g. at an open-house day. Guests play full sessions and appear on the leaderboard marked as guests. They cannot log in again once their tokens are gone, so they should claim the account to keep it.
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param request body GuestRequest true "Nickname"
+// @Success 201 {object} LoginResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /auth/guest [post]
+func (h *CarnivalHandler) PlayAsGuest(c *gin.Context) {
+	var req GuestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+		return
+	}
+
+	guest, err := h.authService.PlayAsGuest(req.Nickname)
+	if err != nil {
+		switch err.Error() {
+		case "guest play disabled":
+			c.JSON(http.StatusForbidden, gin.H{"error": "Guest play is not open for this event"})
+		case "name not allowed":
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Please choose a different nickname"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create guest"})
+		}
+		return
+	}
+
+	response, err := h.issueLogin(c, guest, false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate tokens"})
+		return
+	}
+	response.Message = "🎪 Welcome to Haoma's carnival, " + guest.Name + "!"
+
+	c.JSON(http.StatusCreated, response)
+}
+
+// ClaimGuest godoc
+// @Summary Claim a guest account
+// @Description Give a guest account an email and password so it can log in later. Sessions and leaderboard entries are kept and lose their guest marker. An empty name keeps the nickname. When verification is required, a confirmation link is emailed and the player must follow it before playing on.
+// @Tags Authentication
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body ClaimGuestRequest true "Account details"
+// @Success 200 {object} PlayerInfo
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /auth/guest/claim [post]
+func (h *CarnivalHandler) ClaimGuest(c *gin.Context) {
+	playerID, exists := c.Get("player_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Player not authenticated"})
+		return
+	}
+
+	var req ClaimGuestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+		return
+	}
+
+	p, err := h.authService.ClaimGuest(playerID.(uuid.UUID), req.Name, req.Email, req.Password)
+	if err != nil {
+		switch err.Error() {
+		case "player not found":
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Player not authenticated"})
+		case "player is not a guest":
+			c.JSON(http.StatusConflict, gin.H{"error": "This account has already been claimed"})
+		case "player already exists":
+			c.JSON(http.StatusConflict, gin.H{"error": "Email already registered"})
+		case "email domain not allowed":
+			c.JSON(http.StatusForbidden, gin.H{"error": "Please use your university email address"})
+		case "name not allowed":
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Please choose a different name"})
+		case "email not on roster":
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only enrolled students can have accounts for this event"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to claim account"})
+		}
+		return
+	}
+
+	// The account is claimed either way, so a stale leaderboard marker is only logged
+	if err := h.service.RefreshLeaderboardPlayer(p); err != nil {
+		log.Printf("Failed to update leaderboard entries of claimed guest %s: %v", p.ID, err)
+	}
+
+	if !p.IsVerified() {
+		if err := h.authService.SendVerificationEmail(p.ID, getEmailVerificationURL()); err != nil {
+			log.Printf("Failed to send verification email: %v", err)
+		}
+	}
+
+	c.JSON(http.StatusOK, newPlayerInfo(p))
+}
@@ -1,38 +1,156 @@
 package http
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
 	"fmt"
+	"io"
+	"log"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 
 	"haoma/internal/application/services"
 	"haoma/internal/config"
+	"haoma/internal/domain/node"
+	"haoma/internal/domain/player"
 	"haoma/internal/infrastructure/auth"
+	"haoma/internal/infrastructure/cache"
+	"haoma/internal/infrastructure/jobs"
+	"haoma/internal/infrastructure/mail"
 	"haoma/internal/infrastructure/persistence"
+	"haoma/internal/infrastructure/qr"
+	"haoma/internal/infrastructure/sso"
+	"haoma/internal/infrastructure/throttle"
 )
 
 type CarnivalHandler struct {
-	service *services.CarnivalService
+	service          *services.CarnivalService
+	authService      *services.AuthService
+	accountService   *services.AccountService
+	loginService     *services.LoginService
+	ssoProvider      *sso.Provider // Nil when single sign-on is not configured
+	jwtService       *auth.JWTService
+	cookies          auth.CookieSettings
+	leaderboardCache *cache.LeaderboardCache
+	dataExports      *jobs.Runner
 }
 
 func RegisterRoutes(router *gin.Engine, db *persistence.Database) {
+	passwordHasher, err := auth.PasswordHasherFromEnv()
+	if err != nil {
+		log.Fatal("Invalid password hashing settings:", err)
+	}
+
 	// Initialize repositories
 	sessionRepo := persistence.NewSessionRepository(db.DB)
 	questionRepo := persistence.NewQuestionRepository(db.DB)
 	playerRepo := persistence.NewPlayerRepository(db.DB)
-	leaderboardRepo := persistence.NewLeaderboardRepository(db.DB)
+	eventRepo := cache.NewEventCache(persistence.NewEventRepository(db.DB), config.EVENT_CACHE_TTL)
+	nodeRepo := persistence.NewNodeRepository(db.DB)
+	auditRepo := persistence.NewAuditRepository(db.DB)
 
-	// Initialize service
-	service := services.NewCarnivalService(sessionRepo, questionRepo, playerRepo, leaderboardRepo)
+	// Serve the leaderboard from memory, writing through to the database
+	leaderboardCache, err := cache.NewLeaderboardCache(persistence.NewLeaderboardRepository(db.DB))
+	if err != nil {
+		log.Fatal("Failed to load leaderboard cache:", err)
+	}
+	leaderboardCache.StartConsistencyChecks(config.LEADERBOARD_CONSISTENCY_CHECK_INTERVAL)
 
-	// Initialize handler
-	handler := &CarnivalHandler{service: service}
+	tokenRepo := persistence.NewTokenRepository(db.DB)
+
+	// Keep the revocation list in memory so every request can be checked cheaply
+	revocations, err := cache.NewRevocationCache(tokenRepo)
+	if err != nil {
+		log.Fatal("Failed to load token revocation list:", err)
+	}
+	revocations.StartRefresh(config.REVOCATION_REFRESH_INTERVAL)
+
+	mailer, err := mail.NewFromEnv()
+	if err != nil {
+		log.Fatal("Failed to initialize mailer:", err)
+	}
+
+	// Initialize services
+	signupPolicy := getSignupPolicy()
+	service := services.NewCarnivalService(sessionRepo, questionRepo, playerRepo, leaderboardCache, eventRepo, nodeRepo, auditRepo, signupPolicy)
+	authService := services.NewAuthService(playerRepo, passwordHasher, tokenRepo, revocations, mailer, signupPolicy)
+
+	nodeCodeSigner, signed, err := qr.SignerFromEnv()
+	if err != nil {
+		log.Fatal("Invalid NODE_CODE_SECRET:", err)
+	}
+	if signed {
+		service.UseSignedNodeCodes(nodeCodeSigner)
+	}
+	rotating, rotateAll, err := qr.RotatingNodesFromEnv()
+	if err != nil {
+		log.Fatal("Invalid ROTATING_NODES:", err)
+	}
+	if len(rotating) > 0 || rotateAll {
+		if !signed {
+			log.Fatal("ROTATING_NODES needs NODE_CODE_SECRET")
+		}
+		service.UseRotatingNodeCodes(rotating, rotateAll, qr.RotationPeriodFromEnv())
+	}
+	if getGeofenceRejects() {
+		service.RejectScansOutsideGeofence()
+	}
+
+	secondFactorRoles := getSecondFactorRoles()
+	// Password checks outside the login count against the same per-account limit
+	accountAttempts := throttle.NewMemoryTracker(loginThrottlePolicy(config.LOGIN_FREE_ATTEMPTS, config.LOGIN_LOCKOUT_THRESHOLD))
+	accountService := services.NewAccountService(playerRepo, passwordHasher, tokenRepo, revocations, leaderboardCache, auditRepo, accountAttempts, signupPolicy, secondFactorRoles)
+
+	loginService, err := services.NewLoginService(
+		playerRepo,
+		passwordHasher,
+		tokenRepo,
+		accountAttempts,
+		throttle.NewMemoryTracker(loginThrottlePolicy(config.LOGIN_IP_FREE_ATTEMPTS, config.LOGIN_IP_LOCKOUT_THRESHOLD)),
+		auditRepo,
+	)
+	if err != nil {
+		log.Fatal("Failed to initialize login service:", err)
+	}
+
+	var ssoProvider *sso.Provider
+	if ssoConfig, enabled := sso.ConfigFromEnv(); enabled {
+		ssoProvider, err = sso.NewProvider(context.Background(), ssoConfig)
+		if err != nil {
+			log.Fatal("Failed to initialize single sign-on:", err)
+		}
+	}
+
+	cookieSettings, err := auth.CookieSettingsFromEnv()
+	if err != nil {
+		log.Fatal("Invalid cookie mode settings:", err)
+	}
 
 	// Initialize JWT service and middleware
-	jwtService := auth.NewJWTService(getJWTSecret())
-	jwtMiddleware := auth.JWTMiddleware(jwtService)
+	jwtService := auth.NewJWTService(loadSigningKeys(), revocations)
+	jwtMiddleware := auth.JWTMiddleware(jwtService, revocations)
+	requireSecondFactor := auth.RequireSecondFactor(secondFactorRoles...)
+
+	// Initialize handler
+	handler := &CarnivalHandler{
+		service:          service,
+		authService:      authService,
+		accountService:   accountService,
+		loginService:     loginService,
+		ssoProvider:      ssoProvider,
+		jwtService:       jwtService,
+		cookies:          cookieSettings,
+		leaderboardCache: leaderboardCache,
+		dataExports:      jobs.NewRunner(config.DATA_EXPORT_CONCURRENCY, config.DATA_EXPORT_RETENTION),
+	}
+
+	// Public keys for services that verify our tokens
+	router.GET("/.well-known/jwks.json", handler.GetJWKS)
 
 	// API routes
 	api := router.Group("/api/v1")
@@ -42,6 +160,15 @@ func RegisterRoutes(router *gin.Engine, db *persistence.Database) {
 		{
 			authPublic.POST("/signup", handler.Signup)
 			authPublic.POST("/login", handler.Login)
+			authPublic.POST("/refresh", handler.RefreshToken)
+			authPublic.POST("/forgot", handler.ForgotPassword)
+			authPublic.POST("/reset", handler.ResetPassword)
+			authPublic.POST("/verify", handler.VerifyEmail)
+			authPublic.POST("/activate", handler.ActivateAccount)
+			authPublic.POST("/guest", handler.PlayAsGuest)
+			authPublic.POST("/mfa/verify", handler.VerifySecondFactor)
+			authPublic.GET("/oidc/login", handler.OIDCLogin)
+			authPublic.GET("/oidc/callback", handler.OIDCCallback)
 		}
 
 		// Protected authentication routes (JWT required)
@@ -49,6 +176,17 @@ func RegisterRoutes(router *gin.Engine, db *persistence.Database) {
 		authProtected.Use(jwtMiddleware)
 		{
 			authProtected.GET("/profile", handler.GetProfile)
+			authProtected.PATCH("/profile", handler.UpdateProfile)
+			authProtected.POST("/password", handler.ChangePassword)
+			authProtected.DELETE("/account", handler.DeleteAccount)
+			authProtected.POST("/logout", handler.Logout)
+			authProtected.POST("/verify/resend", handler.ResendVerificationEmail)
+			authProtected.POST("/guest/claim", handler.ClaimGuest)
+			authProtected.GET("/mfa", handler.GetSecondFactorStatus)
+			authProtected.POST("/mfa/totp/setup", handler.SetupTOTP)
+			authProtected.POST("/mfa/totp/enable", handler.EnableTOTP)
+			authProtected.POST("/mfa/totp/disable", handler.DisableTOTP)
+			authProtected.POST("/mfa/recovery-codes", handler.RegenerateRecoveryCodes)
 		}
 
 		// Protected game session routes (JWT required)
@@ -59,6 +197,13 @@ func RegisterRoutes(router *gin.Engine, db *persistence.Database) {
 			sessions.POST("/:id/answer", handler.SubmitAnswer)
 		}
 
+		// The player's own data (JWT required)
+		players := api.Group("/players")
+		players.Use(jwtMiddleware)
+		{
+			players.GET("/me/export", handler.ExportMyData)
+		}
+
 		// Protected node access via QR codes (JWT required)
 		nodes := api.Group("/nodes")
 		nodes.Use(jwtMiddleware)
@@ -66,8 +211,45 @@ func RegisterRoutes(router *gin.Engine, db *persistence.Database) {
 			nodes.POST("/scan", handler.ScanNodeQR)
 		}
 
+		// Station screens, authenticated by their station key
+		api.GET("/stations/:node/code", handler.GetStationCode)
+		api.GET("/stations/:node/code.png", handler.GetStationQR)
+
 		// Public leaderboard (no authentication needed)
 		api.GET("/leaderboard", handler.GetLeaderboard)
+		api.GET("/leaderboard/stream", handler.StreamLeaderboard)
+		api.GET("/events/current", handler.GetCurrentEvent)
+
+		// Staff routes (staff or admin role required)
+		staff := api.Group("/admin")
+		staff.Use(jwtMiddleware, auth.RequireRole(player.RoleStaff, player.RoleAdmin), requireSecondFactor)
+		{
+			staff.GET("/leaderboard", handler.GetLiveLeaderboard)
+			staff.GET("/export/leaderboard", handler.ExportLeaderboard)
+			staff.GET("/export/results", handler.ExportResults)
+			staff.GET("/scan-flags", handler.ListScanFlags)
+			staff.POST("/scan-flags/:id/review", handler.ReviewScanFlag)
+		}
+
+		// Organizer routes (admin role required)
+		admin := api.Group("/admin")
+		admin.Use(jwtMiddleware, auth.RequireRole(player.RoleAdmin), requireSecondFactor)
+		{
+			admin.POST("/events", handler.CreateEvent)
+			admin.PUT("/events/current/tie-breakers", handler.UpdateTieBreakers)
+			admin.POST("/leaderboard/unfreeze", handler.UnfreezeLeaderboard)
+			admin.POST("/leaderboard/reveal", handler.RevealLeaderboardStep)
+			admin.POST("/leaderboard/rebuild", handler.RebuildLeaderboardCache)
+			admin.POST("/players/:id/revoke-tokens", handler.RevokePlayerTokens)
+			admin.PUT("/players/:id/role", handler.ChangePlayerRole)
+			admin.POST("/roster", handler.ImportRoster)
+			admin.POST("/roster/activation-codes", handler.IssueActivationCodes)
+			admin.GET("/nodes", handler.ListNodes)
+			admin.POST("/nodes", handler.CreateNode)
+			admin.PUT("/nodes/:id", handler.UpdateNode)
+			admin.DELETE("/nodes/:id", handler.DeleteNode)
+			admin.GET("/stations/print", handler.PrintStations)
+		}
 	}
 }
 
@@ -77,8 +259,25 @@ type StartSessionRequest struct {
 
 // StartNodeRequest represents scanning a QR code to start a specific node (no player_id needed - from JWT)
 type StartNodeRequest struct {
-	NodeCode  string     `json:"node_code" binding:"required" example:"NODE_CRYPTO_001"`
+	NodeCode  string     `json:"node_code" binding:"required" example:"NODE_001"`
 	SessionID *uuid.UUID `json:"session_id,omitempty" example:"123e4567-e89b-12d3-a456-426614174001"`
+	// Where the device says it is, for nodes with a geofence (browser Geolocation API)
+	Latitude  *float64 `json:"latitude,omitempty" binding:"omitempty,min=-90,max=90" example:"35.7036"`
+	Longitude *float64 `json:"longitude,omitempty" binding:"omitempty,min=-180,max=180" example:"51.3515"`
+	Accuracy  *float64 `json:"accuracy_meters,omitempty" binding:"omitempty,min=0" example:"12"`
+}
+
+// location returns the reported location, or nil without a full one
+func (req StartNodeRequest) location() *node.Location {
+	if req.Latitude == nil || req.Longitude == nil {
+		return nil
+	}
+
+	location := &node.Location{Latitude: *req.Latitude, Longitude: *req.Longitude}
+	if req.Accuracy != nil {
+		location.Accuracy = *req.Accuracy
+	}
+	return location
 }
 
 // StartSessionResponse represents the response when starting a session
@@ -97,6 +296,7 @@ type StartNodeResponse struct {
 // NodeResponse represents a carnival node (tent) with questions
 type NodeResponse struct {
 	Number              int                `json:"number" example:"1"`
+	Name                string             `json:"name" example:"The Cipher Tent"`
 	CategoryName        string             `json:"category_name" example:"Cryptography"`
 	CategoryDescription string             `json:"category_description" example:"Learn about encryption, decryption, and cryptographic protocols"`
 	Questions           []QuestionResponse `json:"questions"`
@@ -122,6 +322,7 @@ type QuestionResponse struct {
 // @Param request body StartSessionRequest true "Player information"
 // @Success 200 {object} StartSessionResponse
 // @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
 // @Router /sessions/start [post]
 func (h *CarnivalHandler) StartSession(c *gin.Context) {
@@ -133,6 +334,10 @@ func (h *CarnivalHandler) StartSession(c *gin.Context) {
 
 	session, err := h.service.CreateSession(playerID.(uuid.UUID))
 	if err != nil {
+		if err.Error() == "email not verified" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Verify your email before starting a session"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -218,13 +423,17 @@ func (h *CarnivalHandler) SubmitAnswer(c *gin.Context) {
 
 // LeaderboardResponse represents the taxteh-ye sharaf
 type LeaderboardResponse struct {
-	Entries []LeaderboardEntry `json:"entries"`
+	Entries  []LeaderboardEntry `json:"entries"`
+	Frozen   bool               `json:"frozen" example:"false"`
+	FrozenAt *string            `json:"frozen_at,omitempty" example:"2025-09-18T19:30:00Z"`
 }
 
 // LeaderboardEntry represents a champion's achievement
 type LeaderboardEntry struct {
-	Rank           int    `json:"rank" example:"1"`
+	Rank           int    `json:"rank" example:"3"`
+	RankLabel      string `json:"rank_label" example:"T-3"` // "T-" marks a true tie
 	PlayerName     string `json:"player_name" example:"Rostam"`
+	Guest          bool   `json:"guest" example:"false"` // Played without an account
 	FinalScore     int    `json:"final_score" example:"850"`
 	CompletionTime string `json:"completion_time" example:"38m45s"`
 	AchievedAt     string `json:"achieved_at" example:"2025-09-18T14:30:45Z"`
@@ -232,32 +441,132 @@ type LeaderboardEntry struct {
 
 // GetLeaderboard godoc
 // @Summary Get the top leaderboard
-// @Description Retrieve the taxteh-ye sharaf showing the greatest champions
+// @Description Retrieve the taxteh-ye sharaf showing the greatest champions. During the final minutes of an event the frozen snapshot is returned.
 // @Tags Leaderboard
 // @Produce json
+// @Param If-None-Match header string false "ETag from a previous response"
 // @Success 200 {object} LeaderboardResponse
+// @Success 304 "Leaderboard unchanged"
 // @Failure 500 {object} map[string]interface{}
 // @Router /leaderboard [get]
 func (h *CarnivalHandler) GetLeaderboard(c *gin.Context) {
-	entries, err := h.service.GetLeaderboard()
+	view, err := h.service.GetLeaderboard(false)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	respondWithETag(c, newLeaderboardResponse(view))
+}
+
+// StreamLeaderboard godoc
+// @Summary Stream live leaderboard updates
+// @Description Server-sent events feed that emits a "leaderboard" event with the public leaderboard whenever it changes
+// @Tags Leaderboard
+// @Produce text/event-stream
+// @Success 200 {object} LeaderboardResponse
+// @Router /leaderboard/stream [get]
+func (h *CarnivalHandler) StreamLeaderboard(c *gin.Context) {
+	ticker := time.NewTicker(config.LEADERBOARD_STREAM_INTERVAL)
+	defer ticker.Stop()
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no") // Keep reverse proxies from buffering the feed
+
+	var lastETag string
+	lastSent := time.Now()
+
+	c.Stream(func(w io.Writer) bool {
+		view, err := h.service.GetLeaderboard(false)
+		if err == nil {
+			body, err := json.Marshal(newLeaderboardResponse(view))
+			if err == nil {
+				etag := fmt.Sprintf("%x", sha256.Sum256(body))
+				if etag != lastETag {
+					c.SSEvent("leaderboard", string(body))
+					lastETag = etag
+					lastSent = time.Now()
+				}
+			}
+		}
+
+		if time.Since(lastSent) >= config.LEADERBOARD_STREAM_KEEPALIVE {
+			fmt.Fprint(w, ": keepalive\n\n")
+			lastSent = time.Now()
+		}
+
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case <-ticker.C:
+			return true
+		}
+	})
+}
+
+// respondWithETag lets pollers skip unchanged payloads with If-None-Match
+func respondWithETag(c *gin.Context, payload interface{}) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to encode response"})
+		return
+	}
+
+	etag := fmt.Sprintf(`"%x"`, sha256.Sum256(body))
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", "no-cache")
+
+	if etagMatches(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	c.Data(http.StatusOK, "application/json; charset=utf-8", body)
+}
+
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+func newLeaderboardResponse(view *services.LeaderboardView) LeaderboardResponse {
 	resp := LeaderboardResponse{
-		Entries: make([]LeaderboardEntry, len(entries)),
+		Entries: make([]LeaderboardEntry, len(view.Entries)),
+		Frozen:  view.Frozen,
 	}
 
-	for i, entry := range entries {
+	if view.FrozenAt != nil {
+		frozenAt := view.FrozenAt.UTC().Format("2006-01-02T15:04:05Z")
+		resp.FrozenAt = &frozenAt
+	}
+
+	for i, entry := range view.Entries {
 		resp.Entries[i] = LeaderboardEntry{
-			Rank:           i + config.MIN_NODE_NUMBER,
+			Rank:           view.Standings[i].Position,
+			RankLabel:      view.Standings[i].Label(),
 			PlayerName:     entry.PlayerName,
+			Guest:          entry.Guest,
 			FinalScore:     entry.FinalScore,
 			CompletionTime: entry.CompletionTime.String(),
 			AchievedAt:     entry.AchievedAt.Format("2006-01-02T15:04:05Z"),
 		}
 	}
 
-	c.JSON(http.StatusOK, resp)
+	return resp
+}
+
+func loginThrottlePolicy(freeAttempts, lockoutThreshold int) throttle.Policy {
+	return throttle.Policy{
+		FreeAttempts:     freeAttempts,
+		BackoffBase:      config.LOGIN_BACKOFF_BASE,
+		BackoffMax:       config.LOGIN_BACKOFF_MAX,
+		LockoutThreshold: lockoutThreshold,
+		LockoutDuration:  config.LOGIN_LOCKOUT_DURATION,
+		Window:           config.LOGIN_FAILURE_WINDOW,
+	}
 }
@@ -1,12 +1,28 @@
 package http
 
 import (
+	"errors"
+	"log"
 	"os"
+
+	"github.com/gin-gonic/gin"
+
+	"haoma/internal/infrastructure/auth"
 )
 
-func getJWTSecret() string {
-	if secret := os.Getenv("JWT_SECRET"); secret != "" {
-		return secret
+// loadSigningKeys reads the JWT keys from disk. Unless GIN_MODE is release, a
+// missing key falls back to a throwaway one so development works out of the
+// box. The variable is read directly: the router forces gin into release mode
+// whatever it says.
+func loadSigningKeys() *auth.KeySet {
+	keys, err := auth.LoadKeySetFromEnv()
+	if errors.Is(err, auth.ErrNoSigningKey) && os.Getenv(gin.EnvGinMode) != gin.ReleaseMode {
+		log.Println("⚠️  JWT_SIGNING_KEY_FILE is not set, signing tokens with a temporary key")
+		keys, err = auth.GenerateEphemeralKeySet()
 	}
-	return "super_secret_key"
+	if err != nil {
+		log.Fatal("Failed to load JWT signing keys:", err)
+	}
+
+	return keys
 }
@@ -0,0 +1,382 @@
+package http
+
+import (
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"haoma/internal/application/services"
+	"haoma/internal/config"
+	"haoma/internal/domain/player"
+	"haoma/internal/infrastructure/auth"
+)
+
+// MFAChallengeResponse is returned instead of tokens when the password was
+// right but the account also needs a code from an authenticator app
+type MFAChallengeResponse struct {
+	MFARequired    bool   `json:"mfa_required" example:"true"`
+	ChallengeToken string `json:"challenge_token" example:"eyJhbGciOiJFUzI1NiIsImtpZCI6IjIwMjQtMDEifQ..."`
+	ExpiresIn      int    `json:"expires_in" example:"300"` // seconds
+	Message        string `json:"message" example:"Enter the code from your authenticator app"`
+}
+
+// VerifySecondFactorRequest completes a login with a second factor
+type VerifySecondFactorRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required" example:"eyJhbGciOiJFUzI1NiIsImtpZCI6IjIwMjQtMDEifQ..."`
+	Code           string `json:"code" binding:"required" example:"492039"` // Authenticator code or recovery code
+}
+
+// SecondFactorCodeRequest confirms an action with a code from the
+// authenticator app
+type SecondFactorCodeRequest struct {
+	Code string `json:"code" binding:"required" example:"492039"`
+}
+
+// MFAStatusResponse describes the player's two-factor authentication
+type MFAStatusResponse struct {
+	Enabled           bool `json:"enabled" example:"true"`
+	Required          bool `json:"required" example:"false"`
+	RecoveryCodesLeft int  `json:"recovery_codes_left" example:"10"`
+}
+
+// TOTPSetupResponse is what the authenticator app needs
+type TOTPSetupResponse struct {
+	Secret     string `json:"secret" example:"JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"`
+	OTPAuthURI string `json:"otpauth_uri" example:"otpauth://totp/Haoma%20Carnival:rostam@haoma.dev?algorithm=SHA1&digits=6&issuer=Haoma+Carnival&period=30&secret=JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"`
+	Message    string `json:"message" example:"Scan the link with your authenticator app, then confirm with a code"`
+}
+
+// TOTPEnabledResponse carries the recovery codes, shown only this once, and
+// tokens for a login that passed the second factor
+type TOTPEnabledResponse struct {
+	LoginResponse
+	RecoveryCodes []string `json:"recovery_codes" example:"7KQ2M-XH4PD,3RT9B-WN6ZC"`
+}
+
+// RecoveryCodesResponse carries a new set of recovery codes
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes" example:"7KQ2M-XH4PD,3RT9B-WN6ZC"`
+	Message       string   `json:"message" example:"Store these somewhere safe; your old recovery codes no longer work"`
+}
+
+// getSecondFactorRoles reads MFA_REQUIRED_ROLES, which defaults to admins.
+// Setting it empty requires two-factor authentication of nobody.
+func getSecondFactorRoles() []player.Role {
+	value, set := os.LookupEnv("MFA_REQUIRED_ROLES")
+	if !set {
+		value = string(player.RoleAdmin)
+	}
+
+	var roles []player.Role
+	for _, item := range splitList(value) {
+		role, err := player.ParseRole(item)
+		if err != nil {
+			log.Fatalf("Invalid MFA_REQUIRED_ROLES entry %q", item)
+		}
+		roles = append(roles, role)
+	}
+	return roles
+}
+
+// issueChallenge asks a player who got their password right for their
+// second factor
+func (h *CarnivalHandler) issueChallenge(p *player.Player) (*MFAChallengeResponse, error) {
+	challenge, err := h.jwtService.GenerateChallengeToken(p.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MFAChallengeResponse{
+		MFARequired:    true,
+		ChallengeToken: challenge,
+		ExpiresIn:      int(config.MFA_CHALLENGE_EXPIRY.Seconds()),
+		Message:        "🔐 Enter the code from your authenticator app",
+	}, nil
+}
+
+// VerifySecondFactor godoc
+// @Summary Complete a login with a second factor
+// @Description Exchange the challenge token from /auth/login and a code from the authenticator app, or an unused recovery code, for access and refresh tokens. Wrong codes are throttled like wrong passwords.
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param request body VerifySecondFactorRequest true "Challenge token and code"
+// @Success 200 {object} LoginResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 429 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /auth/mfa/verify [post]
+func (h *CarnivalHandler) VerifySecondFactor(c *gin.Context) {
+	var req VerifySecondFactorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+		return
+	}
+
+	challenge, err := h.jwtService.ValidateChallengeToken(req.ChallengeToken)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Login expired, please enter your password again"})
+		return
+	}
+
+	p, err := h.loginService.VerifySecondFactor(challenge.PlayerID, req.Code, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		var throttled *services.LoginThrottledError
+		if errors.As(err, &throttled) {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many login attempts. Please try again later."})
+			return
+		}
+		if err.Error() == "invalid code" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid code"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify code"})
+		return
+	}
+
+	response, err := h.issueLogin(c, p, true)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate tokens"})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetSecondFactorStatus godoc
+// @Summary Show two-factor authentication status
+// @Description Whether two-factor authentication is on, whether the player's role requires it and how many recovery codes are left
+// @Tags Authentication
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} MFAStatusResponse
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /auth/mfa [get]
+func (h *CarnivalHandler) GetSecondFactorStatus(c *gin.Context) {
+	playerID, exists := c.Get("player_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Player not authenticated"})
+		return
+	}
+
+	status, err := h.accountService.SecondFactorStatus(playerID.(uuid.UUID))
+	if err != nil {
+		if err.Error() == "player not found" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Player not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load two-factor status"})
+		return
+	}
+
+	c.JSON(http.StatusOK, MFAStatusResponse{
+		Enabled:           status.Enabled,
+		Required:          status.Required,
+		RecoveryCodesLeft: status.RecoveryCodesLeft,
+	})
+}
+
+// SetupTOTP godoc
+// @Summary Start two-factor enrollment
+// @Description Generate a secret for an authenticator app. It is not enforced until confirmed with /auth/mfa/totp/enable; calling this again starts over.
+// @Tags Authentication
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} TOTPSetupResponse
+// @Failure 401 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /auth/mfa/totp/setup [post]
+func (h *CarnivalHandler) SetupTOTP(c *gin.Context) {
+	playerID, exists := c.Get("player_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Player not authenticated"})
+		return
+	}
+
+	enrollment, err := h.accountService.BeginTOTPEnrollment(playerID.(uuid.UUID))
+	if err != nil {
+		switch err.Error() {
+		case "two-factor already enabled":
+			c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is already on"})
+		case "player is a guest":
+			c.JSON(http.StatusConflict, gin.H{"error": "Claim your account before setting up two-factor authentication"})
+		case "player not found":
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Player not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start two-factor setup"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, TOTPSetupResponse{
+		Secret:     enrollment.Secret,
+		OTPAuthURI: enrollment.URI,
+		Message:    "🔐 Add this to your authenticator app, then confirm with a code",
+	})
+}
+
+// EnableTOTP godoc
+// @Summary Turn on two-factor authentication
+// @Description Confirm enrollment with a code from the authenticator app. Returns recovery codes, shown only this once, and fresh tokens; other devices are logged out.
+// @Tags Authentication
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body SecondFactorCodeRequest true "Code from the authenticator app"
+// @Success 200 {object} TOTPEnabledResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /auth/mfa/totp/enable [post]
+func (h *CarnivalHandler) EnableTOTP(c *gin.Context) {
+	playerID, exists := c.Get("player_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Player not authenticated"})
+		return
+	}
+
+	var req SecondFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+		return
+	}
+
+	p, codes, err := h.accountService.EnableTOTP(playerID.(uuid.UUID), req.Code)
+	if err != nil {
+		switch err.Error() {
+		case "invalid code":
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid code, check your authenticator app's clock"})
+		case "two-factor already enabled":
+			c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is already on"})
+		case "two-factor enrollment not started":
+			c.JSON(http.StatusConflict, gin.H{"error": "Start with /auth/mfa/totp/setup"})
+		case "player not found":
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Player not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to enable two-factor authentication"})
+		}
+		return
+	}
+
+	response, err := h.issueLogin(c, p, true)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Two-factor enabled, but failed to generate tokens"})
+		return
+	}
+	response.Message = "🔐 Two-factor authentication is on. Store your recovery codes somewhere safe."
+
+	c.JSON(http.StatusOK, TOTPEnabledResponse{LoginResponse: *response, RecoveryCodes: codes})
+}
+
+// DisableTOTP godoc
+// @Summary Turn off two-factor authentication
+// @Description Confirm with a code from the authenticator app or a recovery code. Not allowed for roles that require two-factor authentication.
+// @Tags Authentication
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body SecondFactorCodeRequest true "Authenticator code or recovery code"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /auth/mfa/totp/disable [post]
+func (h *CarnivalHandler) DisableTOTP(c *gin.Context) {
+	playerID, exists := c.Get("player_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Player not authenticated"})
+		return
+	}
+
+	var req SecondFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+		return
+	}
+
+	if err := h.accountService.DisableTOTP(playerID.(uuid.UUID), req.Code); err != nil {
+		switch err.Error() {
+		case "invalid code":
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid code"})
+		case "two-factor not enabled":
+			c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is not on"})
+		case "two-factor required for role":
+			c.JSON(http.StatusForbidden, gin.H{"error": "Your role requires two-factor authentication"})
+		case "player not found":
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Player not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to disable two-factor authentication"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication is off"})
+}
+
+// RegenerateRecoveryCodes godoc
+// @Summary Replace recovery codes
+// @Description Issue a new set of recovery codes; every earlier code stops working. Confirm with a code from the authenticator app.
+// @Tags Authentication
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body SecondFactorCodeRequest true "Code from the authenticator app"
+// @Success 200 {object} RecoveryCodesResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /auth/mfa/recovery-codes [post]
+func (h *CarnivalHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	playerID, exists := c.Get("player_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Player not authenticated"})
+		return
+	}
+
+	var req SecondFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+		return
+	}
+
+	codes, err := h.accountService.RegenerateRecoveryCodes(playerID.(uuid.UUID), req.Code)
+	if err != nil {
+		switch err.Error() {
+		case "invalid code":
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid code"})
+		case "two-factor not enabled":
+			c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is not on"})
+		case "player not found":
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Player not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to issue recovery codes"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, RecoveryCodesResponse{
+		RecoveryCodes: codes,
+		Message:       "🔐 Store these somewhere safe; your old recovery codes no longer work",
+	})
+}
+
+// secondFactorFromClaims reports whether the caller's own login passed
+// two-factor authentication, so reissued tokens can say so too
+func secondFactorFromClaims(c *gin.Context) bool {
+	claims, exists := c.Get("player_claims")
+	return exists && claims.(*auth.PlayerClaims).HasSecondFactor()
+}
@@ -0,0 +1,210 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"haoma/internal/application/services"
+	"haoma/internal/domain/node"
+)
+
+// NodeRequest represents registering or editing a carnival node. Leaving
+// out the code or name gives NODE_003 and "Node 3" style defaults.
+type NodeRequest struct {
+	Number     int        `json:"number" binding:"required" example:"3"`
+	Code       string     `json:"code,omitempty" example:"NODE_003"`
+	Name       string     `json:"name,omitempty" binding:"max=100" example:"The Cipher Tent"`
+	Location   string     `json:"location,omitempty" binding:"max=200" example:"Lab 204, second floor"`
+	Latitude   *float64   `json:"latitude,omitempty" example:"35.7036"`
+	Longitude  *float64   `json:"longitude,omitempty" example:"51.3515"`
+	Radius     *float64   `json:"radius_meters,omitempty" example:"40"` // Geofence around the coordinates
+	CategoryID *uuid.UUID `json:"category_id,omitempty" example:"550e8400-e29b-41d4-a716-446655440002"`
+	Active     *bool      `json:"active,omitempty" example:"true"` // Defaults to true
+}
+
+// NodeDetailsResponse represents a node in the registry
+type NodeDetailsResponse struct {
+	ID         uuid.UUID  `json:"id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Number     int        `json:"number" example:"3"`
+	Code       string     `json:"code" example:"NODE_003"`
+	Name       string     `json:"name" example:"The Cipher Tent"`
+	Location   string     `json:"location,omitempty" example:"Lab 204, second floor"`
+	Latitude   *float64   `json:"latitude,omitempty" example:"35.7036"`
+	Longitude  *float64   `json:"longitude,omitempty" example:"51.3515"`
+	Radius     *float64   `json:"radius_meters,omitempty" example:"40"`
+	CategoryID *uuid.UUID `json:"category_id,omitempty" example:"550e8400-e29b-41d4-a716-446655440002"`
+	Active     bool       `json:"active" example:"true"`
+}
+
+// ListNodes godoc
+// @Summary List the node registry
+// @Description Every registered node, inactive ones included, by number
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} NodeDetailsResponse
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /admin/nodes [get]
+func (h *CarnivalHandler) ListNodes(c *gin.Context) {
+	nodes, err := h.service.ListNodes()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load nodes"})
+		return
+	}
+
+	response := make([]NodeDetailsResponse, len(nodes))
+	for i := range nodes {
+		response[i] = newNodeDetailsResponse(&nodes[i])
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// CreateNode godoc
+// @Summary Register a carnival node
+// @Description Add a node players can scan. Sessions started afterwards include it; a pinned category is always dealt to this node, otherwise each session draws one at random. Give coordinates and radius_meters to check where players scan from.
+// @Tags Admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body NodeRequest true "Node details"
+// @Success 201 {object} NodeDetailsResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /admin/nodes [post]
+func (h *CarnivalHandler) CreateNode(c *gin.Context) {
+	var req NodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+		return
+	}
+
+	registered, err := h.service.CreateNode(req.details())
+	if err != nil {
+		respondNodeError(c, err, "Failed to create node")
+		return
+	}
+
+	c.JSON(http.StatusCreated, newNodeDetailsResponse(registered))
+}
+
+// UpdateNode godoc
+// @Summary Edit a carnival node
+// @Description Replace a node's details. The number must stay the same (409 otherwise), since sessions deal categories by node number; register a new node and switch this one off instead. Sessions under way keep the categories they were dealt; switching a node off makes its codes stop working.
+// @Tags Admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Node ID"
+// @Param request body NodeRequest true "Node details"
+// @Success 200 {object} NodeDetailsResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /admin/nodes/{id} [put]
+func (h *CarnivalHandler) UpdateNode(c *gin.Context) {
+	nodeID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid node ID"})
+		return
+	}
+
+	var req NodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+		return
+	}
+
+	registered, err := h.service.UpdateNode(nodeID, req.details())
+	if err != nil {
+		respondNodeError(c, err, "Failed to update node")
+		return
+	}
+
+	c.JSON(http.StatusOK, newNodeDetailsResponse(registered))
+}
+
+// DeleteNode godoc
+// @Summary Remove a carnival node
+// @Description Delete a node from the registry. Deactivating it instead keeps its number and code reserved.
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Node ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /admin/nodes/{id} [delete]
+func (h *CarnivalHandler) DeleteNode(c *gin.Context) {
+	nodeID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid node ID"})
+		return
+	}
+
+	if err := h.service.DeleteNode(nodeID); err != nil {
+		respondNodeError(c, err, "Failed to delete node")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Node removed"})
+}
+
+func (req NodeRequest) details() services.NodeDetails {
+	active := true
+	if req.Active != nil {
+		active = *req.Active
+	}
+
+	return services.NodeDetails{
+		Number:     req.Number,
+		Code:       req.Code,
+		Name:       req.Name,
+		Location:   req.Location,
+		Latitude:   req.Latitude,
+		Longitude:  req.Longitude,
+		Radius:     req.Radius,
+		CategoryID: req.CategoryID,
+		Active:     active,
+	}
+}
+
+func respondNodeError(c *gin.Context, err error, fallback string) {
+	switch err.Error() {
+	case "node not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": "Node not found"})
+	case "invalid node number", "invalid node code", "node name required", "invalid coordinates", "invalid geofence radius", "category not found":
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case "node number taken", "node code taken", "category already pinned", "node number cannot change":
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
+
+func newNodeDetailsResponse(registered *node.Node) NodeDetailsResponse {
+	return NodeDetailsResponse{
+		ID:         registered.ID,
+		Number:     registered.Number,
+		Code:       registered.Code,
+		Name:       registered.Name,
+		Location:   registered.Location,
+		Latitude:   registered.Latitude,
+		Longitude:  registered.Longitude,
+		Radius:     registered.Radius,
+		CategoryID: registered.CategoryID,
+		Active:     registered.Active,
+	}
+}
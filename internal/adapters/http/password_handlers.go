@@ -0,0 +1,79 @@
+package http
+
+import (
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ForgotPasswordRequest represents asking for a password reset email
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email" example:"rostam@haoma.dev"`
+}
+
+// ResetPasswordRequest represents choosing a new password with an emailed token
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required" example:"q3Zb1u2y8Xk0vJ4lWcT7sN9aRfE5dHgP6mYoK1iLzQw"`
+	Password string `json:"password" binding:"required,min=6" example:"new_cyber_guardian_2024"`
+}
+
+// ForgotPassword godoc
+// @Summary Request a password reset email
+// @Description Email a single-use reset link that expires after 30 minutes. The response is the same whether or not the email belongs to an account.
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param request body ForgotPasswordRequest true "Account email"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Router /auth/forgot [post]
+func (h *CarnivalHandler) ForgotPassword(c *gin.Context) {
+	var req ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+		return
+	}
+
+	// Failures are only logged so the response never reveals whether the account exists
+	if err := h.authService.RequestPasswordReset(req.Email, getPasswordResetURL()); err != nil {
+		log.Printf("Failed to send password reset email: %v", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "If that email is registered, a reset link is on its way"})
+}
+
+// ResetPassword godoc
+// @Summary Reset a forgotten password
+// @Description Set a new password with the token from the reset email. The token works once, and every existing login for the account is signed out.
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param request body ResetPasswordRequest true "Reset token and new password"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /auth/reset [post]
+func (h *CarnivalHandler) ResetPassword(c *gin.Context) {
+	var req ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+		return
+	}
+
+	if err := h.authService.ResetPassword(req.Token, req.Password); err != nil {
+		if err.Error() == "invalid reset token" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired reset token"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password updated. Please log in again."})
+}
+
+func getPasswordResetURL() string {
+	return os.Getenv("PASSWORD_RESET_URL")
+}
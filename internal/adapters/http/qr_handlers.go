@@ -12,7 +12,7 @@ import (
 
 // ScanNodeQR godoc
 // @Summary Scan QR code to access a carnival node
-// @Description Scan a QR code at a physical location to unlock and start a carnival node
+// @Description Scan a QR code at a physical location to unlock and start a carnival node. With NODE_CODE_SECRET set, only signed payloads (HAOMA1....) are accepted; they may be tied to an event and a validity window. Nodes listed in ROTATING_NODES only accept the code currently on their station screen. Nodes with a geofence check the reported location: doubtful scans are flagged for review, and with GEOFENCE_MODE=reject scans clearly too far away or without a usable location are refused.
 // @Tags Nodes
 // @Security BearerAuth
 // @Accept json
@@ -20,6 +20,7 @@ import (
 // @Param request body StartNodeRequest true "QR code scan information"
 // @Success 200 {object} StartNodeResponse
 // @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
 // @Failure 404 {object} map[string]interface{}
 // @Failure 409 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
@@ -37,12 +38,36 @@ func (h *CarnivalHandler) ScanNodeQR(c *gin.Context) {
 		return
 	}
 
-	resultSessionID, node, category, err := h.service.ScanNodeQR(playerID.(uuid.UUID), req.NodeCode, req.SessionID)
+	resultSessionID, node, category, err := h.service.ScanNodeQR(playerID.(uuid.UUID), req.NodeCode, req.SessionID, req.location())
 	if err != nil {
 		if err.Error() == "node not found" {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Invalid QR code - node not found"})
 			return
 		}
+		if err.Error() == "email not verified" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Verify your email before starting a session"})
+			return
+		}
+		if err.Error() == "node code expired" || err.Error() == "node code not yet valid" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "This QR code is not valid right now"})
+			return
+		}
+		if err.Error() == "outside node geofence" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You seem to be too far from this node - scan it in person"})
+			return
+		}
+		if err.Error() == "location required" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "This node needs your location - allow location access and scan again"})
+			return
+		}
+		if err.Error() == "node requires station code" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Scan the code on this station's screen"})
+			return
+		}
+		if err.Error() == "node code for another event" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "This QR code belongs to a different event"})
+			return
+		}
 		if err.Error() == "node already completed" {
 			c.JSON(http.StatusConflict, gin.H{"error": "You have already completed this node"})
 			return
@@ -53,6 +78,7 @@ func (h *CarnivalHandler) ScanNodeQR(c *gin.Context) {
 
 	nodeResp := NodeResponse{
 		Number:              node.Number,
+		Name:                node.Name,
 		CategoryName:        category.Name,
 		CategoryDescription: category.Description,
 		Questions:           make([]QuestionResponse, len(node.Questions)),
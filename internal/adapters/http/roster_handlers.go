@@ -0,0 +1,180 @@
+package http
+
+import (
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"haoma/internal/application/services"
+	"haoma/internal/infrastructure/export"
+)
+
+// RosterImportResponse summarizes a roster import
+type RosterImportResponse struct {
+	Invited   int      `json:"invited" example:"42"`
+	Enrolled  int      `json:"enrolled" example:"3"`
+	Unchanged int      `json:"unchanged" example:"0"`
+	Errors    []string `json:"errors" example:"row 7: missing student ID"`
+}
+
+// ActivateAccountRequest represents claiming a pre-registered account
+type ActivateAccountRequest struct {
+	Code     string `json:"code" binding:"required" example:"7KQ2M-XH4PD"`
+	Password string `json:"password" binding:"required,min=6" example:"cyber_guardian_2024"`
+}
+
+// ImportRoster godoc
+// @Summary Import the class roster
+// @Description Upload a CSV or XLSX roster with a header row and student ID, name and email columns. Every student gets an invited account; students who already signed up are enrolled instead.
+// @Tags Admin
+// @Security BearerAuth
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file true "Roster (.csv or .xlsx)"
+// @Success 200 {object} RosterImportResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /admin/roster [post]
+func (h *CarnivalHandler) ImportRoster(c *gin.Context) {
+	upload, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Upload the roster as the \"file\" form field"})
+		return
+	}
+
+	format := strings.TrimPrefix(strings.ToLower(filepath.Ext(upload.Filename)), ".")
+	if format != export.FormatCSV && format != export.FormatXLSX {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Roster must be a .csv or .xlsx file"})
+		return
+	}
+
+	file, err := upload.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read upload"})
+		return
+	}
+	defer file.Close()
+
+	cells, err := export.Read(file, format)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to parse roster: " + err.Error()})
+		return
+	}
+	if len(cells) < 2 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Roster needs a header row and at least one student"})
+		return
+	}
+
+	result, err := h.authService.ImportRoster(parseRosterRows(cells))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import roster"})
+		return
+	}
+
+	c.JSON(http.StatusOK, RosterImportResponse{
+		Invited:   result.Invited,
+		Enrolled:  result.Enrolled,
+		Unchanged: result.Unchanged,
+		Errors:    append([]string{}, result.Errors...),
+	})
+}
+
+// IssueActivationCodes godoc
+// @Summary Print activation codes
+// @Description Issue one-time activation codes for invited students and download them for printing. Codes are shown only once. By default only students without a code get one; regenerate=true replaces every outstanding code.
+// @Tags Admin
+// @Security BearerAuth
+// @Produce octet-stream
+// @Param format query string false "csv or xlsx" default(csv)
+// @Param regenerate query bool false "Replace codes that were already issued"
+// @Success 200 {file} file
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /admin/roster/activation-codes [post]
+func (h *CarnivalHandler) IssueActivationCodes(c *gin.Context) {
+	regenerate := c.Query("regenerate") == "true"
+
+	h.writeExport(c, func() (*services.ExportTable, error) {
+		codes, err := h.authService.IssueActivationCodes(regenerate)
+		if err != nil {
+			return nil, err
+		}
+		return services.ActivationCodeTable(codes), nil
+	})
+}
+
+// ActivateAccount godoc
+// @Summary Activate a pre-registered account
+// @Description Claim the account the organizers created from the roster with the printed activation code and choose a password. The player is logged in straight away.
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param request body ActivateAccountRequest true "Activation code and new password"
+// @Success 200 {object} LoginResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /auth/activate [post]
+func (h *CarnivalHandler) ActivateAccount(c *gin.Context) {
+	var req ActivateAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+		return
+	}
+
+	p, err := h.authService.ActivateAccount(req.Code, req.Password)
+	if err != nil {
+		if err.Error() == "invalid activation code" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or already used activation code"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to activate account"})
+		return
+	}
+
+	response, err := h.issueLogin(c, p, false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate tokens"})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// parseRosterRows maps spreadsheet rows to students by their header names,
+// falling back to the student ID, name, email column order
+func parseRosterRows(cells [][]string) []services.RosterRow {
+	studentCol, nameCol, emailCol := 0, 1, 2
+	for i, header := range cells[0] {
+		switch strings.NewReplacer(" ", "", "_", "", "-", "").Replace(strings.ToLower(header)) {
+		case "studentid", "studentnumber", "id":
+			studentCol = i
+		case "name", "fullname":
+			nameCol = i
+		case "email", "emailaddress":
+			emailCol = i
+		}
+	}
+
+	rows := make([]services.RosterRow, 0, len(cells)-1)
+	for _, row := range cells[1:] {
+		rows = append(rows, services.RosterRow{
+			StudentID: safeCell(row, studentCol),
+			Name:      safeCell(row, nameCol),
+			Email:     safeCell(row, emailCol),
+		})
+	}
+	return rows
+}
+
+func safeCell(row []string, index int) string {
+	if index < len(row) {
+		return row[index]
+	}
+	return ""
+}
@@ -0,0 +1,140 @@
+package http
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"haoma/internal/domain/audit"
+)
+
+// ScanFlagResponse represents a node scan flagged for the anti-cheat review
+type ScanFlagResponse struct {
+	ID         uuid.UUID  `json:"id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	PlayerID   uuid.UUID  `json:"player_id" example:"550e8400-e29b-41d4-a716-446655440001"`
+	PlayerName string     `json:"player_name" example:"Roya"`
+	SessionID  *uuid.UUID `json:"session_id,omitempty" example:"550e8400-e29b-41d4-a716-446655440002"`
+	NodeNumber int        `json:"node_number" example:"3"`
+	Reason     string     `json:"reason" example:"outside_geofence"`
+	Latitude   *float64   `json:"latitude,omitempty" example:"35.7081"`
+	Longitude  *float64   `json:"longitude,omitempty" example:"51.3515"`
+	Accuracy   *float64   `json:"accuracy_meters,omitempty" example:"15"`
+	Distance   *float64   `json:"distance_meters,omitempty" example:"500.4"`
+	Rejected   bool       `json:"rejected" example:"false"`
+	ScannedAt  time.Time  `json:"scanned_at" example:"2025-05-01T19:30:00Z"`
+	ReviewedAt *time.Time `json:"reviewed_at,omitempty" example:"2025-05-01T21:00:00Z"`
+}
+
+// getGeofenceRejects reads GEOFENCE_MODE: "flag" (the default) lets scans
+// from too far away through and flags them, "reject" refuses them along with
+// scans that come without a usable location
+func getGeofenceRejects() bool {
+	switch mode := os.Getenv("GEOFENCE_MODE"); mode {
+	case "", "flag":
+		return false
+	case "reject":
+		return true
+	default:
+		log.Fatalf("GEOFENCE_MODE must be flag or reject, got %q", mode)
+		return false
+	}
+}
+
+// ListScanFlags godoc
+// @Summary Flagged node scans
+// @Description Scans at geofenced nodes whose reported location did not clearly place the player there: no location, too vague, just outside, or clearly elsewhere. With GEOFENCE_MODE=reject all but near_geofence_edge scans were refused (rejected is true). A player gets at most one flag per session, node and outcome. Newest first.
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Param status query string false "pending for unreviewed flags only, all for every flag" Enums(pending, all) default(pending)
+// @Success 200 {array} ScanFlagResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /admin/scan-flags [get]
+func (h *CarnivalHandler) ListScanFlags(c *gin.Context) {
+	var pendingOnly bool
+	switch c.DefaultQuery("status", "pending") {
+	case "pending":
+		pendingOnly = true
+	case "all":
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Status must be pending or all"})
+		return
+	}
+
+	scans, err := h.service.ListScanFlags(pendingOnly)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load flagged scans"})
+		return
+	}
+
+	response := make([]ScanFlagResponse, len(scans))
+	for i, scan := range scans {
+		response[i] = newScanFlagResponse(&scan.ScanFlag, scan.PlayerName)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// ReviewScanFlag godoc
+// @Summary Mark a flagged scan as reviewed
+// @Description Take a flagged scan off the pending list once it has been looked into
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Scan flag ID"
+// @Success 200 {object} ScanFlagResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /admin/scan-flags/{id}/review [post]
+func (h *CarnivalHandler) ReviewScanFlag(c *gin.Context) {
+	flagID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid scan flag ID"})
+		return
+	}
+
+	reviewerID, exists := c.Get("player_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Player not authenticated"})
+		return
+	}
+
+	flag, err := h.service.ReviewScanFlag(flagID, reviewerID.(uuid.UUID))
+	if err != nil {
+		if err.Error() == "scan flag not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Scan flag not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to review scan flag"})
+		return
+	}
+
+	c.JSON(http.StatusOK, newScanFlagResponse(&flag.ScanFlag, flag.PlayerName))
+}
+
+func newScanFlagResponse(flag *audit.ScanFlag, playerName string) ScanFlagResponse {
+	return ScanFlagResponse{
+		ID:         flag.ID,
+		PlayerID:   flag.PlayerID,
+		PlayerName: playerName,
+		SessionID:  flag.SessionID,
+		NodeNumber: flag.NodeNumber,
+		Reason:     flag.Reason,
+		Latitude:   flag.Latitude,
+		Longitude:  flag.Longitude,
+		Accuracy:   flag.Accuracy,
+		Distance:   flag.Distance,
+		Rejected:   flag.Rejected,
+		ScannedAt:  flag.ScannedAt,
+		ReviewedAt: flag.ReviewedAt,
+	}
+}
@@ -0,0 +1,172 @@
+package http
+
+import (
+	"crypto/subtle"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"haoma/internal/application/services"
+	"haoma/internal/domain/player"
+	"haoma/internal/infrastructure/auth"
+)
+
+// oidcStateCookie ties the provider callback to the browser that started the
+// login, so nobody can log a victim into the attacker's account
+const oidcStateCookie = "haoma_oidc_state"
+
+// OIDCLogin godoc
+// @Summary Log in with the university account
+// @Description Redirect the browser to the university identity provider (authorization code flow with PKCE). Only available when OIDC is configured.
+// @Tags Authentication
+// @Success 302
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /auth/oidc/login [get]
+func (h *CarnivalHandler) OIDCLogin(c *gin.Context) {
+	if h.ssoProvider == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Single sign-on is not configured"})
+		return
+	}
+
+	authURL, state, err := h.ssoProvider.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start single sign-on"})
+		return
+	}
+
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, state, 600, "/api/v1/auth/oidc", "", c.Request.TLS != nil, true)
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// OIDCCallback godoc
+// @Summary Finish logging in with the university account
+// @Description The identity provider redirects here. The player is linked by verified email, or created, and receives the usual tokens: as JSON, or in the URL fragment of OIDC_SUCCESS_URL when that is set.
+// @Tags Authentication
+// @Produce json
+// @Param code query string true "Authorization code"
+// @Param state query string true "Login state"
+// @Success 200 {object} LoginResponse
+// @Success 302
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /auth/oidc/callback [get]
+func (h *CarnivalHandler) OIDCCallback(c *gin.Context) {
+	if h.ssoProvider == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Single sign-on is not configured"})
+		return
+	}
+
+	if providerError := c.Query("error"); providerError != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Login was not completed: " + providerError})
+		return
+	}
+
+	state := c.Query("state")
+	cookieState, err := c.Cookie(oidcStateCookie)
+	if err != nil || subtle.ConstantTimeCompare([]byte(cookieState), []byte(state)) != 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Login state mismatch, please start again"})
+		return
+	}
+	c.SetCookie(oidcStateCookie, "", -1, "/api/v1/auth/oidc", "", c.Request.TLS != nil, true)
+
+	identity, err := h.ssoProvider.Complete(c.Request.Context(), state, c.Query("code"))
+	if err != nil {
+		log.Printf("Single sign-on failed: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Single sign-on failed, please start again"})
+		return
+	}
+
+	p, err := h.authService.LoginWithIdentity(services.ExternalIdentity{
+		Issuer:        identity.Issuer,
+		Subject:       identity.Subject,
+		Email:         identity.Email,
+		EmailVerified: identity.EmailVerified,
+		Name:          identity.Name,
+	})
+	if err != nil {
+		switch err.Error() {
+		case "identity email not verified":
+			c.JSON(http.StatusForbidden, gin.H{"error": "Your university account has no verified email"})
+		case "email domain not allowed":
+			c.JSON(http.StatusForbidden, gin.H{"error": "Please sign in with your university account"})
+		case "email not on roster":
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only enrolled students can sign in to this event"})
+		case "account linked to another identity":
+			c.JSON(http.StatusConflict, gin.H{"error": "This email is already linked to a different university account"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign in"})
+		}
+		return
+	}
+
+	if p.HasTOTP() {
+		h.respondWithChallenge(c, p)
+		return
+	}
+
+	successURL := getOIDCSuccessURL()
+	if successURL != "" && h.cookies.Enabled {
+		// The browser lands on the web client, so keep the tokens in cookies
+		c.Request.Header.Set(auth.AuthModeHeader, "cookie")
+	}
+
+	response, err := h.issueLogin(c, p, false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate tokens"})
+		return
+	}
+
+	if successURL != "" {
+		// A fragment never reaches server logs or Referer headers
+		fragment := url.Values{
+			"token_type":         {response.TokenType},
+			"expires_in":         {strconv.Itoa(response.ExpiresIn)},
+			"refresh_expires_in": {strconv.Itoa(response.RefreshExpiresIn)},
+		}
+		if response.CSRFToken != "" {
+			fragment.Set("csrf_token", response.CSRFToken)
+		} else {
+			fragment.Set("access_token", response.AccessToken)
+			fragment.Set("refresh_token", response.RefreshToken)
+		}
+		c.Redirect(http.StatusFound, successURL+"#"+fragment.Encode())
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// respondWithChallenge asks a single sign-on player with two-factor
+// authentication for their code, through the same page tokens would go to
+func (h *CarnivalHandler) respondWithChallenge(c *gin.Context, p *player.Player) {
+	challenge, err := h.issueChallenge(p)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate tokens"})
+		return
+	}
+
+	if successURL := getOIDCSuccessURL(); successURL != "" {
+		fragment := url.Values{
+			"mfa_required":    {"true"},
+			"challenge_token": {challenge.ChallengeToken},
+			"expires_in":      {strconv.Itoa(challenge.ExpiresIn)},
+		}
+		c.Redirect(http.StatusFound, successURL+"#"+fragment.Encode())
+		return
+	}
+
+	c.JSON(http.StatusAccepted, challenge)
+}
+
+func getOIDCSuccessURL() string {
+	return os.Getenv("OIDC_SUCCESS_URL")
+}
@@ -0,0 +1,187 @@
+package http
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"haoma/internal/domain/nodecode"
+	"haoma/internal/infrastructure/qr"
+)
+
+const stationQRSize = 768 // pixels, read from across a room
+
+// stationKeyHeader carries the station key. It is kept out of the URL, where
+// access logs and proxies would record it.
+const stationKeyHeader = "X-Station-Key"
+
+// StationCodeResponse is the code a station screen shows right now
+type StationCodeResponse struct {
+	Node          int       `json:"node" example:"3"`
+	Code          string    `json:"code" example:"HAOMAR.3.56666666.pqRpZy6zPWLNgz3TkXUnNQ"`
+	RotatesAt     time.Time `json:"rotates_at" example:"2025-05-01T19:30:30Z"`
+	PeriodSeconds int       `json:"period_seconds" example:"30"`
+}
+
+// GetStationCode godoc
+// @Summary Current code for a station screen
+// @Description The rotating code a node's station screen should show now, and when it changes. The screen authenticates with its station key from cmd/node-codes.
+// @Tags Nodes
+// @Produce json
+// @Param node path int true "Node number"
+// @Param X-Station-Key header string true "Station key"
+// @Success 200 {object} StationCodeResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /stations/{node}/code [get]
+func (h *CarnivalHandler) GetStationCode(c *gin.Context) {
+	node, code, rotatesAt, ok := h.stationCode(c)
+	if !ok {
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, StationCodeResponse{
+		Node:          node,
+		Code:          code,
+		RotatesAt:     rotatesAt,
+		PeriodSeconds: int(qr.RotationPeriodFromEnv().Seconds()),
+	})
+}
+
+// GetStationQR godoc
+// @Summary Current QR code image for a station screen
+// @Description The rotating code a node's station screen should show now, as a PNG. Open /station?node=N#key=... on the screen instead of polling this directly.
+// @Tags Nodes
+// @Produce png
+// @Param node path int true "Node number"
+// @Param X-Station-Key header string true "Station key"
+// @Success 200 {file} binary
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /stations/{node}/code.png [get]
+func (h *CarnivalHandler) GetStationQR(c *gin.Context) {
+	_, code, _, ok := h.stationCode(c)
+	if !ok {
+		return
+	}
+
+	png, err := qr.PNG(code, stationQRSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate QR code"})
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, "image/png", png)
+}
+
+// stationCode looks up the current code for the station in the request,
+// answering the request itself when that fails
+func (h *CarnivalHandler) stationCode(c *gin.Context) (int, string, time.Time, bool) {
+	node, err := strconv.Atoi(c.Param("node"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid node number"})
+		return 0, "", time.Time{}, false
+	}
+
+	code, rotatesAt, err := h.service.StationCode(node, c.GetHeader(stationKeyHeader))
+	if err != nil {
+		switch err.Error() {
+		case "invalid station key":
+			c.JSON(http.StatusForbidden, gin.H{"error": "Invalid station key"})
+		case "node not rotating":
+			c.JSON(http.StatusNotFound, gin.H{"error": "This node uses a printed code"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load the station code"})
+		}
+		return 0, "", time.Time{}, false
+	}
+
+	return node, code, rotatesAt, true
+}
+
+// PrintStations godoc
+// @Summary Printable station sheets and QR codes
+// @Description Renders the codes the server accepts right now as a PDF with one sign per station (node number, theme, QR code, instructions), or as bare PNG or SVG QR codes, zipped unless a single node is asked for. Nodes on station screens get a sign pointing at the screen and no image.
+// @Tags Admin
+// @Security BearerAuth
+// @Produce application/pdf
+// @Produce image/png
+// @Produce image/svg+xml
+// @Produce application/zip
+// @Param format query string false "Output format" Enums(pdf, png, svg) default(pdf)
+// @Param node query int false "Only this node"
+// @Param event query string false "current ties signed codes to the current event and its hours" Enums(current)
+// @Success 200 {file} file
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /admin/stations/print [get]
+func (h *CarnivalHandler) PrintStations(c *gin.Context) {
+	format := strings.ToLower(c.DefaultQuery("format", qr.FormatPDF))
+	if format != qr.FormatPDF && format != qr.FormatPNG && format != qr.FormatSVG {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Format must be pdf, png or svg"})
+		return
+	}
+
+	var forCurrentEvent bool
+	switch c.Query("event") {
+	case "":
+	case "current":
+		forCurrentEvent = true
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Event must be current or left out"})
+		return
+	}
+
+	stations, err := h.service.Stations(forCurrentEvent)
+	if err != nil {
+		if err.Error() == "no current event" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "No event is running"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load the stations"})
+		return
+	}
+
+	filename := "haoma-stations"
+	if value := c.Query("node"); value != "" {
+		node, err := strconv.Atoi(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid node number"})
+			return
+		}
+		station, ok := nodecode.FindStation(stations, node)
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Node not found"})
+			return
+		}
+		if format != qr.FormatPDF && station.UsesScreen() {
+			c.JSON(http.StatusNotFound, gin.H{"error": "This node shows its code on a station screen"})
+			return
+		}
+		stations = []nodecode.Station{station}
+		filename = fmt.Sprintf("haoma-node-%d", node)
+	}
+
+	// Render into memory first so a failure can still produce a JSON error
+	var buf bytes.Buffer
+	if err := qr.WriteStations(&buf, format, stations); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render the stations"})
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Header("Content-Disposition", `attachment; filename="`+filename+"."+qr.Extension(format, len(stations))+`"`)
+	c.Data(http.StatusOK, qr.ContentType(format, len(stations)), buf.Bytes())
+}
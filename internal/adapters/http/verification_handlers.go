@@ -0,0 +1,112 @@
+package http
+
+import (
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"haoma/internal/application/services"
+	"haoma/internal/config"
+)
+
+// VerifyEmailRequest represents confirming an email address with an emailed token
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required" example:"q3Zb1u2y8Xk0vJ4lWcT7sN9aRfE5dHgP6mYoK1iLzQw"`
+}
+
+// VerifyEmail godoc
+// @Summary Verify an email address
+// @Description Confirm the player's email with the token from the verification email. Each token works once.
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param request body VerifyEmailRequest true "Verification token"
+// @Success 200 {object} PlayerInfo
+// @Failure 400 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /auth/verify [post]
+func (h *CarnivalHandler) VerifyEmail(c *gin.Context) {
+	var req VerifyEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+		return
+	}
+
+	p, err := h.authService.VerifyEmail(req.Token)
+	if err != nil {
+		if err.Error() == "invalid verification token" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired verification token"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify email"})
+		return
+	}
+
+	c.JSON(http.StatusOK, newPlayerInfo(p))
+}
+
+// ResendVerificationEmail godoc
+// @Summary Resend the verification email
+// @Description Email the authenticated player a new verification link
+// @Tags Authentication
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /auth/verify/resend [post]
+func (h *CarnivalHandler) ResendVerificationEmail(c *gin.Context) {
+	playerID, exists := c.Get("player_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Player not authenticated"})
+		return
+	}
+
+	if err := h.authService.SendVerificationEmail(playerID.(uuid.UUID), getEmailVerificationURL()); err != nil {
+		switch err.Error() {
+		case "email already verified":
+			c.JSON(http.StatusConflict, gin.H{"error": "Your email is already verified"})
+		case "player is a guest":
+			c.JSON(http.StatusConflict, gin.H{"error": "Guests have no email to verify; claim your account first"})
+		case "player not found":
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Player not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send verification email"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "A new verification link is on its way"})
+}
+
+func getEmailVerificationURL() string {
+	return os.Getenv("EMAIL_VERIFICATION_URL")
+}
+
+// getSignupPolicy reads the comma-separated ALLOWED_EMAIL_DOMAINS and
+// BLOCKED_NAME_WORDS, REQUIRE_EMAIL_VERIFICATION=true, SIGNUP_MODE=roster and
+// GUEST_PLAY=true from the environment
+func getSignupPolicy() services.SignupPolicy {
+	return services.SignupPolicy{
+		AllowedDomains:      splitList(os.Getenv("ALLOWED_EMAIL_DOMAINS")),
+		RequireVerification: os.Getenv("REQUIRE_EMAIL_VERIFICATION") == "true",
+		RosterOnly:          os.Getenv("SIGNUP_MODE") == "roster",
+		AllowGuests:         os.Getenv("GUEST_PLAY") == "true",
+		BlockedNameWords:    append(append([]string{}, config.BlockedNameWords...), splitList(os.Getenv("BLOCKED_NAME_WORDS"))...),
+	}
+}
+
+// splitList parses a comma-separated setting, skipping blank items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,154 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"haoma/internal/domain/player"
+)
+
+// AccountService handles what players do with their own account: renaming,
+// changing their password, two-factor authentication and deleting it
+type AccountService struct {
+	playerRepo        PlayerRepository
+	passwords         player.PasswordHasher
+	tokenRepo         TokenRepository
+	revocations       RevocationRepository
+	leaderboardRepo   LeaderboardRepository
+	auditRepo         AuditRepository
+	accounts          LoginAttemptTracker // Shared with logins, so password guesses count the same everywhere
+	policy            SignupPolicy
+	secondFactorRoles []player.Role // Roles that must use two-factor authentication
+}
+
+func NewAccountService(playerRepo PlayerRepository, passwords player.PasswordHasher, tokenRepo TokenRepository, revocations RevocationRepository,
+	leaderboardRepo LeaderboardRepository, auditRepo AuditRepository, accounts LoginAttemptTracker, policy SignupPolicy, secondFactorRoles []player.Role) *AccountService {
+	return &AccountService{
+		playerRepo:        playerRepo,
+		passwords:         passwords,
+		tokenRepo:         tokenRepo,
+		revocations:       revocations,
+		leaderboardRepo:   leaderboardRepo,
+		auditRepo:         auditRepo,
+		accounts:          accounts,
+		policy:            policy,
+		secondFactorRoles: secondFactorRoles,
+	}
+}
+
+// UpdateProfile renames the player, on their leaderboard entries too
+func (s *AccountService) UpdateProfile(playerID uuid.UUID, name string) (*player.Player, error) {
+	p, err := s.playerRepo.FindByID(playerID)
+	if err != nil {
+		return nil, errors.New("player not found")
+	}
+
+	name = NormalizeName(name)
+	if len(name) < 2 || !s.policy.AllowsName(name) {
+		return nil, errors.New("name not allowed")
+	}
+
+	p.Rename(name)
+	if err := s.playerRepo.Update(p); err != nil {
+		return nil, err
+	}
+
+	if err := s.leaderboardRepo.UpdatePlayer(p.ID, p.Name, p.IsGuest()); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// ChangePassword replaces the password after checking the current one. Every
+// token the player holds is revoked, so other devices have to log in again.
+// Wrong guesses are throttled like logins; it fails with "invalid password"
+// or a *LoginThrottledError.
+func (s *AccountService) ChangePassword(playerID uuid.UUID, currentPassword, newPassword string) (*player.Player, error) {
+	p, err := s.playerRepo.FindByID(playerID)
+	if err != nil {
+		return nil, errors.New("player not found")
+	}
+	if p.IsGuest() {
+		return nil, errors.New("player is a guest")
+	}
+	if err := s.checkPassword(p, currentPassword); err != nil {
+		return nil, err
+	}
+
+	if err := p.UpdatePassword(s.passwords, newPassword); err != nil {
+		return nil, err
+	}
+	if err := s.playerRepo.Update(p); err != nil {
+		return nil, err
+	}
+
+	if err := s.revokeAll(p.ID); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// DeleteAccount removes the player's personal data. Their sessions, answers
+// and scores stay for the event statistics, with the leaderboard showing
+// them as a deleted player. Anyone who chose a password must confirm with
+// it, single sign-on or not; guests and single sign-on accounts that never
+// set one have none to give. Wrong passwords are throttled like logins.
+func (s *AccountService) DeleteAccount(playerID uuid.UUID, password string) error {
+	p, err := s.playerRepo.FindByID(playerID)
+	if err != nil {
+		return errors.New("player not found")
+	}
+	if !p.IsGuest() && !p.RandomPassword {
+		if err := s.checkPassword(p, password); err != nil {
+			return err
+		}
+	}
+
+	email := p.Email
+	if err := s.revokeAll(p.ID); err != nil {
+		return err
+	}
+	if err := s.tokenRepo.DeletePlayerTokens(p.ID); err != nil {
+		return err
+	}
+	if err := s.auditRepo.DeleteLoginFailures(p.ID, email); err != nil {
+		return err
+	}
+	if err := s.auditRepo.DeleteScanFlags(p.ID); err != nil {
+		return err
+	}
+	if err := s.leaderboardRepo.UpdatePlayer(p.ID, player.DeletedName, false); err != nil {
+		return err
+	}
+
+	p.Anonymize()
+	return s.playerRepo.Delete(p)
+}
+
+// checkPassword confirms the player's current password. A stolen access
+// token must not allow unlimited guessing, so each try counts against the
+// account's login limit until the right password clears it.
+func (s *AccountService) checkPassword(p *player.Player, password string) error {
+	accountKey := strings.ToLower(p.Email)
+	if wait := s.accounts.Reserve(accountKey); wait > 0 {
+		return &LoginThrottledError{RetryAfter: wait}
+	}
+	if !p.ValidatePassword(s.passwords, password) {
+		return errors.New("invalid password")
+	}
+
+	s.accounts.Reset(accountKey)
+	return nil
+}
+
+func (s *AccountService) revokeAll(playerID uuid.UUID) error {
+	if err := s.tokenRepo.RevokePlayerRefreshTokens(playerID); err != nil {
+		return err
+	}
+	return s.revocations.RevokeTokensIssuedBefore(playerID, time.Now())
+}
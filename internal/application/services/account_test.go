@@ -0,0 +1,83 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"haoma/internal/domain/player"
+)
+
+// countingTracker locks a key out once it has reserved limit attempts
+type countingTracker struct {
+	limit    int
+	attempts map[string]int
+}
+
+func (t *countingTracker) Reserve(key string) time.Duration {
+	if t.attempts[key] >= t.limit {
+		return time.Minute
+	}
+	t.attempts[key]++
+	return 0
+}
+
+func (t *countingTracker) Release(key string) {
+	t.attempts[key]--
+}
+
+func (t *countingTracker) Reset(key string) {
+	delete(t.attempts, key)
+}
+
+func TestAccountService_ChangePasswordThrottled(t *testing.T) {
+	players := &memoryPlayers{players: make(map[uuid.UUID]player.Player)}
+	tokens := &memoryTokens{refreshRevoked: make(map[uuid.UUID]bool)}
+	revocations := &memoryRevocations{validAfter: make(map[uuid.UUID]time.Time)}
+	tracker := &countingTracker{limit: 3, attempts: make(map[string]int)}
+	accounts := NewAccountService(players, testPasswords, tokens, revocations, nil, nil, tracker, SignupPolicy{}, nil)
+
+	p, _ := player.NewPlayer(testPasswords, "Faranak", "Faranak@uni.edu", "fereydun_mother")
+	players.Save(p)
+
+	for i := 0; i < 3; i++ {
+		if _, err := accounts.ChangePassword(p.ID, "guess", "new_password"); err == nil || err.Error() != "invalid password" {
+			t.Fatalf("Expected guess %d to be refused as invalid, got %v", i+1, err)
+		}
+	}
+
+	// Now even the right password has to wait
+	_, err := accounts.ChangePassword(p.ID, "fereydun_mother", "new_password")
+	var throttled *LoginThrottledError
+	if !errors.As(err, &throttled) {
+		t.Fatalf("Expected the account to be throttled, got %v", err)
+	}
+	if tracker.attempts["faranak@uni.edu"] != 3 {
+		t.Errorf("Expected the guesses to count against the login key, got %v", tracker.attempts)
+	}
+
+	if err := accounts.DeleteAccount(p.ID, "guess"); !errors.As(err, &throttled) {
+		t.Errorf("Expected account deletion to be throttled too, got %v", err)
+	}
+}
+
+func TestAccountService_ChangePasswordResetsAttempts(t *testing.T) {
+	players := &memoryPlayers{players: make(map[uuid.UUID]player.Player)}
+	tokens := &memoryTokens{refreshRevoked: make(map[uuid.UUID]bool)}
+	revocations := &memoryRevocations{validAfter: make(map[uuid.UUID]time.Time)}
+	tracker := &countingTracker{limit: 3, attempts: make(map[string]int)}
+	accounts := NewAccountService(players, testPasswords, tokens, revocations, nil, nil, tracker, SignupPolicy{}, nil)
+
+	p, _ := player.NewPlayer(testPasswords, "Faranak", "faranak@uni.edu", "fereydun_mother")
+	players.Save(p)
+
+	accounts.ChangePassword(p.ID, "guess", "new_password")
+	if _, err := accounts.ChangePassword(p.ID, "fereydun_mother", "new_password"); err != nil {
+		t.Fatalf("ChangePassword failed: %v", err)
+	}
+	if attempts := tracker.attempts["faranak@uni.edu"]; attempts != 0 {
+		t.Errorf("Expected the right password to clear the attempts, got %d", attempts)
+	}
+}
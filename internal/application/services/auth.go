@@ -0,0 +1,284 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"haoma/internal/config"
+	"haoma/internal/domain/player"
+	"haoma/internal/domain/token"
+)
+
+// AuthService manages the credentials players hold beyond their password
+type AuthService struct {
+	playerRepo  PlayerRepository
+	passwords   player.PasswordHasher
+	tokenRepo   TokenRepository
+	revocations RevocationRepository
+	mailer      Mailer
+	policy      SignupPolicy
+}
+
+type TokenRepository interface {
+	SaveRefreshToken(refresh *token.RefreshToken) error
+	FindRefreshTokenByHash(tokenHash string) (*token.RefreshToken, error)
+	RotateRefreshToken(refresh *token.RefreshToken) error
+	RevokeRefreshFamily(familyID uuid.UUID) error
+	RevokePlayerRefreshTokens(playerID uuid.UUID) error
+	DeletePlayerTokens(playerID uuid.UUID) error
+	SavePasswordResetToken(reset *token.PasswordResetToken) error
+	FindPasswordResetTokenByHash(tokenHash string) (*token.PasswordResetToken, error)
+	UsePasswordResetToken(reset *token.PasswordResetToken) error
+	UsePlayerPasswordResetTokens(playerID uuid.UUID) error
+	SaveEmailVerificationToken(verification *token.EmailVerificationToken) error
+	FindEmailVerificationTokenByHash(tokenHash string) (*token.EmailVerificationToken, error)
+	UseEmailVerificationToken(verification *token.EmailVerificationToken) error
+	ReplaceRecoveryCodes(playerID uuid.UUID, codes []*token.RecoveryCode) error
+	UseRecoveryCode(playerID uuid.UUID, codeHash string) error
+	CountUnusedRecoveryCodes(playerID uuid.UUID) (int, error)
+}
+
+type RevocationRepository interface {
+	RevokeToken(revoked *token.RevokedToken) error
+	RevokeTokensIssuedBefore(playerID uuid.UUID, validAfter time.Time) error
+}
+
+// Mailer sends plain-text email to players
+type Mailer interface {
+	Send(to, subject, body string) error
+}
+
+// IssuedRefreshToken is a freshly minted refresh token; Value is only ever
+// shown to the client once
+type IssuedRefreshToken struct {
+	Value        string
+	FamilyID     uuid.UUID
+	SecondFactor bool // The login passed two-factor authentication
+}
+
+func NewAuthService(playerRepo PlayerRepository, passwords player.PasswordHasher, tokenRepo TokenRepository, revocations RevocationRepository, mailer Mailer, policy SignupPolicy) *AuthService {
+	return &AuthService{
+		playerRepo:  playerRepo,
+		passwords:   passwords,
+		tokenRepo:   tokenRepo,
+		revocations: revocations,
+		mailer:      mailer,
+		policy:      policy,
+	}
+}
+
+// IssueRefreshToken starts a new token family for a fresh login.
+// secondFactor records whether the login passed two-factor authentication,
+// so every rotation keeps saying so.
+func (a *AuthService) IssueRefreshToken(playerID uuid.UUID, secondFactor bool) (*IssuedRefreshToken, error) {
+	return a.issueRefreshToken(playerID, uuid.New(), secondFactor)
+}
+
+// RotateRefreshToken exchanges a refresh token for a new one in the same
+// family. Presenting a token that was already rotated means it leaked, so the
+// whole family is revoked.
+func (a *AuthService) RotateRefreshToken(value string) (*IssuedRefreshToken, *player.Player, error) {
+	refresh, err := a.tokenRepo.FindRefreshTokenByHash(token.Hash(value))
+	if err != nil {
+		return nil, nil, errors.New("invalid refresh token")
+	}
+
+	if refresh.WasRotated() {
+		if err := a.tokenRepo.RevokeRefreshFamily(refresh.FamilyID); err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, errors.New("refresh token reuse detected")
+	}
+
+	if refresh.IsRevoked() || refresh.IsExpired() {
+		return nil, nil, errors.New("invalid refresh token")
+	}
+
+	p, err := a.playerRepo.FindByID(refresh.PlayerID)
+	if err != nil {
+		return nil, nil, errors.New("invalid refresh token")
+	}
+
+	// Another request may have exchanged the same token since it was read
+	if err := a.tokenRepo.RotateRefreshToken(refresh); err != nil {
+		if err.Error() != "refresh token already rotated" {
+			return nil, nil, err
+		}
+		if err := a.tokenRepo.RevokeRefreshFamily(refresh.FamilyID); err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, errors.New("refresh token reuse detected")
+	}
+
+	issued, err := a.issueRefreshToken(p.ID, refresh.FamilyID, refresh.SecondFactor)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return issued, p, nil
+}
+
+// RevokeRefreshFamily ends every refresh token descended from one login
+func (a *AuthService) RevokeRefreshFamily(familyID uuid.UUID) error {
+	return a.tokenRepo.RevokeRefreshFamily(familyID)
+}
+
+// RevokeAccessToken blocks a single access token until it expires
+func (a *AuthService) RevokeAccessToken(tokenID string, playerID uuid.UUID, expiresAt time.Time) error {
+	if tokenID == "" {
+		return nil
+	}
+	return a.revocations.RevokeToken(token.NewRevokedToken(tokenID, playerID, expiresAt))
+}
+
+// RevokeAllPlayerTokens invalidates every access and refresh token the player
+// currently holds, e.g. when they are caught cheating mid-event
+func (a *AuthService) RevokeAllPlayerTokens(playerID uuid.UUID) error {
+	if _, err := a.playerRepo.FindByID(playerID); err != nil {
+		return errors.New("player not found")
+	}
+
+	if err := a.tokenRepo.RevokePlayerRefreshTokens(playerID); err != nil {
+		return err
+	}
+
+	return a.revocations.RevokeTokensIssuedBefore(playerID, time.Now())
+}
+
+// RequestPasswordReset emails a single-use reset link. Unknown emails are
+// ignored so the endpoint cannot be used to discover accounts. The link
+// points at resetURL with the token appended; without one the bare token is
+// sent.
+func (a *AuthService) RequestPasswordReset(email, resetURL string) error {
+	p, err := a.playerRepo.FindByEmail(email)
+	if err != nil || p.IsGuest() {
+		return nil
+	}
+
+	value, hash, err := token.Generate()
+	if err != nil {
+		return err
+	}
+
+	if err := a.tokenRepo.SavePasswordResetToken(token.NewPasswordResetToken(p.ID, hash, config.PASSWORD_RESET_EXPIRY)); err != nil {
+		return err
+	}
+
+	minutes := int(config.PASSWORD_RESET_EXPIRY.Minutes())
+	body := fmt.Sprintf("Hi %s,\n\nUse this within %d minutes to choose a new password:\n\n%s\n\n"+
+		"If you did not ask for a reset, you can ignore this email.\n", p.Name, minutes, tokenLink(resetURL, value))
+
+	return a.mailer.Send(p.Email, "Reset your Haoma password", body)
+}
+
+// ResetPassword sets a new password using an emailed reset token. Every
+// other outstanding reset token is spent and all of the player's sessions
+// are signed out.
+func (a *AuthService) ResetPassword(value, newPassword string) error {
+	reset, err := a.tokenRepo.FindPasswordResetTokenByHash(token.Hash(value))
+	if err != nil || !reset.CanBeUsed() {
+		return errors.New("invalid reset token")
+	}
+
+	if err := a.tokenRepo.UsePasswordResetToken(reset); err != nil {
+		return errors.New("invalid reset token")
+	}
+
+	p, err := a.playerRepo.FindByID(reset.PlayerID)
+	if err != nil {
+		return errors.New("invalid reset token")
+	}
+
+	if err := p.UpdatePassword(a.passwords, newPassword); err != nil {
+		return err
+	}
+	if err := a.playerRepo.Update(p); err != nil {
+		return err
+	}
+
+	if err := a.tokenRepo.UsePlayerPasswordResetTokens(p.ID); err != nil {
+		return err
+	}
+
+	return a.RevokeAllPlayerTokens(p.ID)
+}
+
+// ChangeRole grants a player a new role. Existing tokens still carry the old
+// role, so they are revoked and the player has to log in again.
+func (a *AuthService) ChangeRole(playerID uuid.UUID, role player.Role) (*player.Player, error) {
+	p, err := a.playerRepo.FindByID(playerID)
+	if err != nil {
+		return nil, errors.New("player not found")
+	}
+
+	p.ChangeRole(role)
+	if err := a.playerRepo.Update(p); err != nil {
+		return nil, err
+	}
+
+	if err := a.RevokeAllPlayerTokens(p.ID); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// BootstrapAdmin creates the first admin account, or promotes an existing
+// account with the same email. A new account needs a password as long as
+// signup demands, since it opens the admin routes.
+func (a *AuthService) BootstrapAdmin(name, email, password string) (*player.Player, error) {
+	email = NormalizeEmail(email)
+	if existing, err := a.playerRepo.FindByEmail(email); err == nil {
+		return a.ChangeRole(existing.ID, player.RoleAdmin)
+	}
+
+	if len(password) < config.MIN_PASSWORD_LENGTH {
+		return nil, errors.New("password too short")
+	}
+
+	admin, err := player.NewPlayer(a.passwords, name, email, password)
+	if err != nil {
+		return nil, err
+	}
+	admin.ChangeRole(player.RoleAdmin)
+	admin.MarkVerified()
+
+	if err := a.playerRepo.Save(admin); err != nil {
+		return nil, err
+	}
+
+	return admin, nil
+}
+
+func (a *AuthService) issueRefreshToken(playerID, familyID uuid.UUID, secondFactor bool) (*IssuedRefreshToken, error) {
+	value, tokenHash, err := token.Generate()
+	if err != nil {
+		return nil, err
+	}
+
+	refresh := token.NewRefreshToken(playerID, familyID, tokenHash, config.REFRESH_TOKEN_EXPIRY, secondFactor)
+	if err := a.tokenRepo.SaveRefreshToken(refresh); err != nil {
+		return nil, err
+	}
+
+	return &IssuedRefreshToken{Value: value, FamilyID: familyID, SecondFactor: secondFactor}, nil
+}
+
+// tokenLink appends an emailed token to the page that accepts it. Without a
+// page the bare token is returned for the player to paste into the app.
+func tokenLink(baseURL, value string) string {
+	if baseURL == "" {
+		return value
+	}
+
+	separator := "?"
+	if strings.Contains(baseURL, "?") {
+		separator = "&"
+	}
+	return baseURL + separator + "token=" + url.QueryEscape(value)
+}
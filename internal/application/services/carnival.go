@@ -3,12 +3,17 @@ package services
 import (
 	"errors"
 	"math/rand"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 
 	"haoma/internal/config"
+	"haoma/internal/domain/audit"
+	"haoma/internal/domain/event"
 	"haoma/internal/domain/leaderboard"
+	"haoma/internal/domain/node"
+	"haoma/internal/domain/nodecode"
 	"haoma/internal/domain/player"
 	"haoma/internal/domain/question"
 	"haoma/internal/domain/session"
@@ -19,12 +24,22 @@ type CarnivalService struct {
 	questionRepo    QuestionRepository
 	playerRepo      PlayerRepository
 	leaderboardRepo LeaderboardRepository
+	eventRepo       EventRepository
+	nodeRepo        NodeRepository
+	scanFlagRepo    ScanFlagRepository
+	signupPolicy    SignupPolicy
+	nodeCodes       *nodecode.Signer // Nil while static node codes are accepted
+	rotatingNodes   map[int]bool     // Nodes that only accept their station screen's code
+	rotateAll       bool             // Every node does, including ones registered later
+	rotationPeriod  time.Duration
+	rejectOutside   bool // Refuse scans clearly outside a node's geofence instead of only flagging them
 }
 
 type SessionRepository interface {
 	Save(session *session.Session) error
 	FindByID(id uuid.UUID) (*session.Session, error)
 	Update(session *session.Session) error
+	FindByPlayer(playerID uuid.UUID) ([]session.Session, error)
 }
 
 type QuestionRepository interface {
@@ -32,13 +47,23 @@ type QuestionRepository interface {
 	GetQuestionsByCategory(categoryID uuid.UUID, limit int) ([]question.Question, error)
 	GetUnusedFunQuestionsForSession(sessionID uuid.UUID, limit int) ([]question.Question, error)
 	FindByID(id uuid.UUID) (*question.Question, error)
+	FindByIDs(ids []uuid.UUID) ([]question.Question, error)
 }
 
 type PlayerRepository interface {
 	Save(player *player.Player) error
+	Update(player *player.Player) error
+	ReplacePasswordHash(playerID uuid.UUID, oldHash, newHash string) error
+	ClaimInvitation(player *player.Player, activationCodeHash *string) error
+	Delete(player *player.Player) error
 	FindByID(id uuid.UUID) (*player.Player, error)
 	FindByEmail(email string) (*player.Player, error)
+	FindByOIDCIdentity(issuer, subject string) (*player.Player, error)
+	FindByStudentID(studentID string) (*player.Player, error)
+	FindByActivationCodeHash(codeHash string) (*player.Player, error)
+	GetInvited() ([]player.Player, error)
 	SaveAttempt(attempt *player.Attempt) error
+	GetAttemptsBySession(sessionID uuid.UUID) ([]player.Attempt, error)
 	GetAttemptsBySessionAndCategory(sessionID, categoryID uuid.UUID) ([]player.Attempt, error)
 	HasAnsweredQuestion(sessionID, questionID uuid.UUID) (bool, error)
 }
@@ -46,7 +71,36 @@ type PlayerRepository interface {
 type LeaderboardRepository interface {
 	AddEntry(entry *leaderboard.Entry) error
 	UpsertEntry(entry *leaderboard.Entry) error
-	GetTop10() ([]leaderboard.Entry, error)
+	UpdatePlayer(playerID uuid.UUID, playerName string, guest bool) error
+	GetTop10(tieBreakers []leaderboard.TieBreaker) ([]leaderboard.Entry, error)
+	GetAll(tieBreakers []leaderboard.TieBreaker) ([]leaderboard.Entry, error)
+	SaveSnapshot(snapshot *leaderboard.Snapshot) error
+	CreateSnapshot(snapshot *leaderboard.Snapshot) (*leaderboard.Snapshot, error)
+	FindSnapshotByEvent(eventID uuid.UUID) (*leaderboard.Snapshot, error)
+}
+
+type EventRepository interface {
+	Save(event *event.Event) error
+	Update(event *event.Event) error
+	FindCurrent() (*event.Event, error)
+}
+
+type NodeRepository interface {
+	Save(n *node.Node) error
+	Update(n *node.Node) error
+	Delete(n *node.Node) error
+	FindByID(id uuid.UUID) (*node.Node, error)
+	FindByNumber(number int) (*node.Node, error)
+	FindByCode(code string) (*node.Node, error)
+	GetAll() ([]node.Node, error)
+}
+
+type ScanFlagRepository interface {
+	SaveScanFlagOnce(flag *audit.ScanFlag) error
+	UpdateScanFlag(flag *audit.ScanFlag) error
+	FindScanFlag(id uuid.UUID) (*audit.ScanFlag, error)
+	GetScanFlags(pendingOnly bool) ([]audit.ScanFlag, error)
+	GetPlayerScanFlags(playerID uuid.UUID) ([]audit.ScanFlag, error)
 }
 
 func NewCarnivalService(
@@ -54,24 +108,23 @@ func NewCarnivalService(
 	questionRepo QuestionRepository,
 	playerRepo PlayerRepository,
 	leaderboardRepo LeaderboardRepository,
+	eventRepo EventRepository,
+	nodeRepo NodeRepository,
+	scanFlagRepo ScanFlagRepository,
+	signupPolicy SignupPolicy,
 ) *CarnivalService {
 	return &CarnivalService{
 		sessionRepo:     sessionRepo,
 		questionRepo:    questionRepo,
 		playerRepo:      playerRepo,
 		leaderboardRepo: leaderboardRepo,
+		eventRepo:       eventRepo,
+		nodeRepo:        nodeRepo,
+		scanFlagRepo:    scanFlagRepo,
+		signupPolicy:    signupPolicy,
 	}
 }
 
-func (c *CarnivalService) CreatePlayer(player *player.Player) error {
-	_, err := c.playerRepo.FindByEmail(player.Email)
-	if err == nil {
-		return errors.New("player already exists")
-	}
-
-	return c.playerRepo.Save(player)
-}
-
 func (c *CarnivalService) GetPlayerByID(id uuid.UUID) (*player.Player, error) {
 	return c.playerRepo.FindByID(id)
 }
@@ -80,13 +133,22 @@ func (c *CarnivalService) GetPlayerByEmail(email string) (*player.Player, error)
 	return c.playerRepo.FindByEmail(email)
 }
 
+// RefreshLeaderboardPlayer copies the player's current name and guest status
+// onto their leaderboard entries, e.g. after a guest claimed their account
+func (c *CarnivalService) RefreshLeaderboardPlayer(p *player.Player) error {
+	return c.leaderboardRepo.UpdatePlayer(p.ID, p.Name, p.IsGuest())
+}
+
 func (c *CarnivalService) CreateSession(playerID uuid.UUID) (*session.Session, error) {
-	_, err := c.playerRepo.FindByID(playerID)
+	p, err := c.playerRepo.FindByID(playerID)
 	if err != nil {
 		return nil, errors.New("player not found")
 	}
+	if !c.signupPolicy.CanPlay(p) {
+		return nil, errors.New("email not verified")
+	}
 
-	randomCategories, err := c.generateRandomCategoryAssignment()
+	randomCategories, err := c.assignCategories()
 	if err != nil {
 		return nil, err
 	}
@@ -108,16 +170,36 @@ func (c *CarnivalService) CreateSession(playerID uuid.UUID) (*session.Session, e
 	return newSession, nil
 }
 
-func (c *CarnivalService) ScanNodeQR(playerID uuid.UUID, nodeCode string, sessionID *uuid.UUID) (*uuid.UUID, *question.Node, *question.Category, error) {
+// ScanNodeQR starts the node a scanned code belongs to, in the given session
+// or a new one. Nodes with a geofence compare it with the location the
+// player's device reported, which may be nil; doubtful scans are flagged
+// for review.
+func (c *CarnivalService) ScanNodeQR(playerID uuid.UUID, nodeCode string, sessionID *uuid.UUID, location *node.Location) (*uuid.UUID, *question.Node, *question.Category, error) {
 	_, err := c.playerRepo.FindByID(playerID)
 	if err != nil {
 		return nil, nil, nil, errors.New("player not found")
 	}
 
-	nodeNumber, err := c.parseNodeCode(nodeCode)
+	registered, err := c.parseNodeCode(nodeCode)
 	if err != nil {
+		switch err.Error() {
+		case "node code expired", "node code not yet valid", "node code for another event", "node requires station code":
+			return nil, nil, nil, err
+		}
 		return nil, nil, nil, errors.New("node not found")
 	}
+	nodeNumber := registered.Number
+
+	geofence := registered.CheckLocation(location)
+	if geofence.NotAtNode() && c.rejectOutside {
+		if err := c.flagScan(playerID, sessionID, nodeNumber, location, geofence, true); err != nil {
+			return nil, nil, nil, err
+		}
+		if geofence.Problem != node.GeofenceOutside {
+			return nil, nil, nil, errors.New("location required")
+		}
+		return nil, nil, nil, errors.New("outside node geofence")
+	}
 
 	var currentSession *session.Session
 
@@ -136,7 +218,8 @@ func (c *CarnivalService) ScanNodeQR(playerID uuid.UUID, nodeCode string, sessio
 		}
 	}
 
-	if nodeNumber > len([]string(currentSession.Categories)) {
+	// Nodes registered or switched on after the session began have no category
+	if nodeNumber > len([]string(currentSession.Categories)) || currentSession.Categories[nodeNumber-1] == "" {
 		return nil, nil, nil, errors.New("invalid node number for session")
 	}
 
@@ -159,7 +242,7 @@ func (c *CarnivalService) ScanNodeQR(playerID uuid.UUID, nodeCode string, sessio
 		return nil, nil, nil, errors.New("assigned category not found")
 	}
 
-	node, err := c.generateNodeFromCategory(nodeNumber, nodeCategory.ID, currentSession.ID)
+	started, err := c.generateNodeFromCategory(registered, nodeCategory.ID, currentSession.ID)
 	if err != nil {
 		return nil, nil, nil, err
 	}
@@ -176,7 +259,13 @@ func (c *CarnivalService) ScanNodeQR(playerID uuid.UUID, nodeCode string, sessio
 		return nil, nil, nil, err
 	}
 
-	return &currentSession.ID, node, nodeCategory, nil
+	if geofence.Problem != "" {
+		if err := c.flagScan(playerID, &currentSession.ID, nodeNumber, location, geofence, false); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	return &currentSession.ID, started, nodeCategory, nil
 }
 
 type AnswerResult struct {
@@ -242,6 +331,8 @@ func (c *CarnivalService) SubmitAnswer(sessionID, questionID uuid.UUID, answer s
 	}
 
 	if nodeCompleted {
+		currentSession.NodesCompleted++
+
 		if err := c.addNodeTimePenalty(currentSession, currentSession.CurrentNode); err != nil {
 			return nil, err
 		}
@@ -261,23 +352,110 @@ func (c *CarnivalService) SubmitAnswer(sessionID, questionID uuid.UUID, answer s
 	return result, nil
 }
 
-func (c *CarnivalService) GetLeaderboard() ([]leaderboard.Entry, error) {
-	return c.leaderboardRepo.GetTop10()
+// UseSignedNodeCodes makes node scans require QR payloads signed with the
+// signer's key. The nodes' static codes stop working.
+func (c *CarnivalService) UseSignedNodeCodes(signer *nodecode.Signer) {
+	c.nodeCodes = signer
+}
+
+// UseRotatingNodeCodes makes the given nodes accept only the code currently
+// shown on their station screen, so a photo of it soon stops working; with
+// all set, every registered node does. It needs signed node codes.
+func (c *CarnivalService) UseRotatingNodeCodes(nodes []int, all bool, period time.Duration) {
+	c.rotatingNodes = make(map[int]bool, len(nodes))
+	for _, number := range nodes {
+		c.rotatingNodes[number] = true
+	}
+	c.rotateAll = all
+	c.rotationPeriod = period
+}
+
+// rotates reports whether a node only accepts its station screen's code
+func (c *CarnivalService) rotates(number int) bool {
+	return c.rotateAll || c.rotatingNodes[number]
+}
+
+// StationCode returns the code a station screen should show now and when it
+// changes. The screen proves it belongs to the station with its key.
+func (c *CarnivalService) StationCode(node int, stationKey string) (string, time.Time, error) {
+	if c.nodeCodes == nil || !c.nodeCodes.ValidStationKey(node, stationKey) {
+		return "", time.Time{}, errors.New("invalid station key")
+	}
+	if !c.rotates(node) {
+		return "", time.Time{}, errors.New("node not rotating")
+	}
+
+	now := time.Now()
+	return c.nodeCodes.Rotating(node, now, c.rotationPeriod), nodecode.RotatesAt(now, c.rotationPeriod), nil
+}
+
+// parseNodeCode finds the active registered node a scanned code belongs to
+func (c *CarnivalService) parseNodeCode(nodeCode string) (*node.Node, error) {
+	if c.nodeCodes != nil && nodecode.IsRotating(nodeCode) {
+		number, err := c.nodeCodes.VerifyRotating(nodeCode, time.Now(), c.rotationPeriod, config.NODE_CODE_ROTATION_SKEW)
+		if err != nil {
+			return nil, err
+		}
+		if !c.rotates(number) {
+			return nil, errors.New("invalid node code")
+		}
+		return c.activeNode(c.nodeRepo.FindByNumber(number))
+	}
+
+	registered, err := c.parsePrintedNodeCode(nodeCode)
+	if err != nil {
+		return nil, err
+	}
+	if c.rotates(registered.Number) {
+		return nil, errors.New("node requires station code")
+	}
+	return registered, nil
+}
+
+// parsePrintedNodeCode reads a code that does not change: signed when
+// signing is on, otherwise a node's static code from the registry
+func (c *CarnivalService) parsePrintedNodeCode(nodeCode string) (*node.Node, error) {
+	if c.nodeCodes != nil {
+		number, err := c.parseSignedNodeCode(nodeCode)
+		if err != nil {
+			return nil, err
+		}
+		return c.activeNode(c.nodeRepo.FindByNumber(number))
+	}
+
+	// Static codes such as "NODE_001", as printed from the registry
+	return c.activeNode(c.nodeRepo.FindByCode(strings.TrimSpace(nodeCode)))
 }
 
-func (c *CarnivalService) parseNodeCode(nodeCode string) (int, error) {
-	// QR codes format: "NODE_XXX" where XXX is a unique identifier
-	// Examples: "NODE_001", "NODE_002", "NODE_003", etc.
+// parseSignedNodeCode checks the signature and validity window of a QR
+// payload, and that a code made for one event is not used at another
+func (c *CarnivalService) parseSignedNodeCode(nodeCode string) (int, error) {
+	payload, err := c.nodeCodes.Verify(nodeCode, time.Now())
+	if err != nil {
+		return config.DEFAULT_RANK, err
+	}
 
-	if nodeNumber, exists := config.NodeCodes[nodeCode]; exists {
-		return nodeNumber, nil
+	if payload.EventID != nil {
+		current, err := c.eventRepo.FindCurrent()
+		if err != nil || current.ID != *payload.EventID {
+			return config.DEFAULT_RANK, errors.New("node code for another event")
+		}
 	}
 
-	return config.DEFAULT_RANK, errors.New("invalid node code")
+	return payload.Node, nil
+}
+
+// activeNode passes on a registry lookup, treating a switched-off node as
+// an unknown code
+func (c *CarnivalService) activeNode(registered *node.Node, err error) (*node.Node, error) {
+	if err != nil || !registered.Active {
+		return nil, errors.New("invalid node code")
+	}
+	return registered, nil
 }
 
-func (c *CarnivalService) generateNodeFromCategory(nodeNumber int, categoryID uuid.UUID, sessionID uuid.UUID) (*question.Node, error) {
-	if nodeNumber < config.MIN_NODE_NUMBER || nodeNumber > config.MAX_NODE_NUMBER {
+func (c *CarnivalService) generateNodeFromCategory(registered *node.Node, categoryID uuid.UUID, sessionID uuid.UUID) (*question.Node, error) {
+	if !registered.Active {
 		return nil, errors.New("invalid node number")
 	}
 
@@ -298,7 +476,8 @@ func (c *CarnivalService) generateNodeFromCategory(nodeNumber int, categoryID uu
 	})
 
 	return &question.Node{
-		Number:     nodeNumber,
+		Number:     registered.Number,
+		Name:       registered.Name,
 		CategoryID: categoryID,
 		Questions:  allQuestions,
 	}, nil
@@ -338,36 +517,62 @@ func (c *CarnivalService) countQuestionsAnsweredInCategory(sessionID, categoryID
 	return len(attempts), nil
 }
 
-func (c *CarnivalService) generateRandomCategoryAssignment() ([]string, error) {
+// assignCategories gives every active node a category for a new session:
+// its pinned one, or a general category drawn at random that no other node
+// uses. The result is indexed by node number minus one, with "" for numbers
+// that have no active node.
+func (c *CarnivalService) assignCategories() ([]string, error) {
+	nodes, err := c.activeNodes()
+	if err != nil {
+		return nil, err
+	}
+	if len(nodes) == 0 {
+		return nil, errors.New("no active nodes")
+	}
+
 	allCategories, err := c.questionRepo.GetCategories()
 	if err != nil {
 		return nil, err
 	}
 
+	pinned := make(map[uuid.UUID]bool)
+	for _, n := range nodes {
+		if n.CategoryID != nil {
+			pinned[*n.CategoryID] = true
+		}
+	}
+
+	categoryNames := make(map[uuid.UUID]string, len(allCategories))
 	var generalCategories []string
 	for _, cat := range allCategories {
-		if cat.Name != config.FUN_CATEGORY_NAME {
+		categoryNames[cat.ID] = cat.Name
+		if cat.Name != config.FUN_CATEGORY_NAME && !pinned[cat.ID] {
 			generalCategories = append(generalCategories, cat.Name)
 		}
 	}
 
-	if len(generalCategories) < config.MIN_REQUIRED_GENERAL_CATEGORIES {
-		return nil, errors.New("insufficient general categories available")
-	}
-
-	if len(generalCategories) == config.MIN_REQUIRED_GENERAL_CATEGORIES {
-		rand.Shuffle(len(generalCategories), func(i, j int) {
-			generalCategories[i], generalCategories[j] = generalCategories[j], generalCategories[i]
-		})
-		return generalCategories, nil
-	}
-
-	// If more than required, randomly select the required number
 	rand.Shuffle(len(generalCategories), func(i, j int) {
 		generalCategories[i], generalCategories[j] = generalCategories[j], generalCategories[i]
 	})
 
-	return generalCategories[:config.MAX_CARNIVAL_NODES], nil
+	assigned := make([]string, nodes[len(nodes)-1].Number)
+	for _, n := range nodes {
+		if n.CategoryID != nil {
+			name, exists := categoryNames[*n.CategoryID]
+			if !exists {
+				return nil, errors.New("assigned category not found")
+			}
+			assigned[n.Number-1] = name
+			continue
+		}
+
+		if len(generalCategories) == 0 {
+			return nil, errors.New("insufficient general categories available")
+		}
+		assigned[n.Number-1], generalCategories = generalCategories[0], generalCategories[1:]
+	}
+
+	return assigned, nil
 }
 
 func (c *CarnivalService) getUniqueQuestionsFromCategory(categoryID uuid.UUID, limit int) ([]question.Question, error) {
@@ -417,11 +622,55 @@ func (c *CarnivalService) updateLeaderboardAfterNode(currentSession *session.Ses
 		return err
 	}
 
+	// Capture the frozen board before this score lands on it
+	if _, _, err := c.activeFreezeSnapshot(); err != nil {
+		return err
+	}
+
+	phdtAccuracy, err := c.phdtAccuracy(currentSession.ID)
+	if err != nil {
+		return err
+	}
+
 	currentTime := time.Since(currentSession.StartedAt)
 	entry := leaderboard.NewEntry(player.ID, player.Name, currentSession.ID, currentSession.Score.Final, currentTime)
+	entry.Guest = player.IsGuest()
+	entry.TimePenalty = currentSession.Score.TimePenalty
+	entry.NodesCompleted = currentSession.NodesCompleted
+	entry.PhDTAccuracy = phdtAccuracy
 	if err := c.leaderboardRepo.UpsertEntry(entry); err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// phdtAccuracy returns the fraction of the session's phishing detection
+// answers that were correct, used as a tie-breaker
+func (c *CarnivalService) phdtAccuracy(sessionID uuid.UUID) (float64, error) {
+	categories, err := c.questionRepo.GetCategories()
+	if err != nil {
+		return 0, err
+	}
+
+	for _, cat := range categories {
+		if !cat.IsPhDT {
+			continue
+		}
+
+		attempts, err := c.playerRepo.GetAttemptsBySessionAndCategory(sessionID, cat.ID)
+		if err != nil || len(attempts) == 0 {
+			return 0, err
+		}
+
+		correct := 0
+		for _, attempt := range attempts {
+			if attempt.IsCorrect {
+				correct++
+			}
+		}
+		return float64(correct) / float64(len(attempts)), nil
+	}
+
+	return 0, nil
+}
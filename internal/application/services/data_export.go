@@ -0,0 +1,161 @@
+package services
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"haoma/internal/domain/audit"
+	"haoma/internal/domain/leaderboard"
+	"haoma/internal/domain/session"
+)
+
+// PlayerData is everything the carnival stores about one player, as handed
+// to them when they ask for a copy
+type PlayerData struct {
+	ExportedAt         time.Time           `json:"exported_at"`
+	Profile            PlayerDataProfile   `json:"profile"`
+	Sessions           []session.Session   `json:"sessions"`
+	Attempts           []PlayerDataAttempt `json:"attempts"`
+	LeaderboardEntries []leaderboard.Entry `json:"leaderboard_entries"`
+	FlaggedScans       []audit.ScanFlag    `json:"flagged_scans"`
+}
+
+// PlayerDataProfile is the account as stored, minus credentials
+type PlayerDataProfile struct {
+	ID              uuid.UUID  `json:"id"`
+	Name            string     `json:"name"`
+	Email           string     `json:"email,omitempty"`
+	Role            string     `json:"role"`
+	Guest           bool       `json:"guest"`
+	StudentID       *string    `json:"student_id,omitempty"`
+	SingleSignOn    *string    `json:"single_sign_on_issuer,omitempty"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"`
+	TOTPEnabledAt   *time.Time `json:"totp_enabled_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// PlayerDataAttempt is one answer together with the question it answered.
+// The correct option is left out; the explanation covers it.
+type PlayerDataAttempt struct {
+	SessionID   uuid.UUID `json:"session_id"`
+	QuestionID  uuid.UUID `json:"question_id"`
+	Category    string    `json:"category"`
+	Question    string    `json:"question"`
+	Options     []string  `json:"options"`
+	Answer      string    `json:"answer"`
+	IsCorrect   bool      `json:"is_correct"`
+	Explanation string    `json:"explanation"`
+	AttemptAt   time.Time `json:"attempt_at"`
+}
+
+// ExportPlayerData collects the player's profile, sessions, answers,
+// leaderboard entries and the locations of any flagged scans
+func (c *CarnivalService) ExportPlayerData(playerID uuid.UUID) (*PlayerData, error) {
+	p, err := c.playerRepo.FindByID(playerID)
+	if err != nil {
+		return nil, err
+	}
+
+	data := &PlayerData{
+		ExportedAt: time.Now().UTC(),
+		Profile: PlayerDataProfile{
+			ID:              p.ID,
+			Name:            p.Name,
+			Role:            string(p.Role),
+			Guest:           p.IsGuest(),
+			StudentID:       p.StudentID,
+			SingleSignOn:    p.OIDCIssuer,
+			EmailVerifiedAt: p.EmailVerifiedAt,
+			TOTPEnabledAt:   p.TOTPEnabledAt,
+			CreatedAt:       p.CreatedAt,
+			UpdatedAt:       p.UpdatedAt,
+		},
+		Attempts:           []PlayerDataAttempt{},
+		LeaderboardEntries: []leaderboard.Entry{},
+	}
+	if !p.IsGuest() {
+		data.Profile.Email = p.Email
+	}
+
+	data.Sessions, err = c.sessionRepo.FindByPlayer(p.ID)
+	if err != nil {
+		return nil, err
+	}
+	if data.Sessions == nil {
+		data.Sessions = []session.Session{}
+	}
+
+	categories, err := c.questionRepo.GetCategories()
+	if err != nil {
+		return nil, err
+	}
+	categoryNames := make(map[uuid.UUID]string, len(categories))
+	for _, cat := range categories {
+		categoryNames[cat.ID] = cat.Name
+	}
+
+	for _, s := range data.Sessions {
+		attempts, err := c.playerRepo.GetAttemptsBySession(s.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		questionIDs := make([]uuid.UUID, len(attempts))
+		for i, attempt := range attempts {
+			questionIDs[i] = attempt.QuestionID
+		}
+		questions, err := c.questionRepo.FindByIDs(questionIDs)
+		if err != nil {
+			return nil, err
+		}
+		byID := make(map[uuid.UUID]int, len(questions))
+		for i, q := range questions {
+			byID[q.ID] = i
+		}
+
+		for _, attempt := range attempts {
+			exported := PlayerDataAttempt{
+				SessionID:  attempt.SessionID,
+				QuestionID: attempt.QuestionID,
+				Answer:     attempt.Answer,
+				IsCorrect:  attempt.IsCorrect,
+				AttemptAt:  attempt.AttemptAt,
+			}
+			if i, found := byID[attempt.QuestionID]; found {
+				q := questions[i]
+				exported.Category = categoryNames[q.CategoryID]
+				exported.Question = q.Text
+				exported.Options = []string{q.OptionA, q.OptionB}
+				for _, option := range []*string{q.OptionC, q.OptionD} {
+					if option != nil {
+						exported.Options = append(exported.Options, *option)
+					}
+				}
+				exported.Explanation = q.Explanation
+			}
+			data.Attempts = append(data.Attempts, exported)
+		}
+	}
+
+	entries, err := c.leaderboardRepo.GetAll(c.currentRanking())
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.PlayerID == p.ID {
+			data.LeaderboardEntries = append(data.LeaderboardEntries, entry)
+		}
+	}
+
+	data.FlaggedScans, err = c.scanFlagRepo.GetPlayerScanFlags(p.ID)
+	if err != nil {
+		return nil, err
+	}
+	if data.FlaggedScans == nil {
+		data.FlaggedScans = []audit.ScanFlag{}
+	}
+
+	return data, nil
+}
@@ -0,0 +1,170 @@
+package services
+
+import (
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/google/uuid"
+
+	"haoma/internal/config"
+	"haoma/internal/domain/leaderboard"
+)
+
+// ExportTable is a spreadsheet-shaped view of carnival results
+type ExportTable struct {
+	Name    string
+	Headers []string
+	Rows    [][]interface{}
+}
+
+// ExportLeaderboard returns the full live ranking, not just the top entries
+func (c *CarnivalService) ExportLeaderboard() (*ExportTable, error) {
+	tieBreakers := c.currentRanking()
+	entries, err := c.leaderboardRepo.GetAll(tieBreakers)
+	if err != nil {
+		return nil, err
+	}
+	standings := leaderboard.Standings(entries, tieBreakers)
+
+	table := &ExportTable{
+		Name:    "Leaderboard",
+		Headers: []string{"Rank", "Player", "Guest", "Final Score", "Completion Time (s)", "Achieved At"},
+	}
+
+	for i, entry := range entries {
+		table.Rows = append(table.Rows, []interface{}{
+			standings[i].Label(),
+			entry.PlayerName,
+			entry.Guest,
+			entry.FinalScore,
+			int(entry.CompletionTime.Seconds()),
+			entry.AchievedAt.UTC().Format("2006-01-02T15:04:05Z"),
+		})
+	}
+
+	return table, nil
+}
+
+// ExportResults returns one gradebook row per ranked session with per-node
+// scores and per-category accuracy
+func (c *CarnivalService) ExportResults() (*ExportTable, error) {
+	tieBreakers := c.currentRanking()
+	entries, err := c.leaderboardRepo.GetAll(tieBreakers)
+	if err != nil {
+		return nil, err
+	}
+	standings := leaderboard.Standings(entries, tieBreakers)
+
+	categories, err := c.questionRepo.GetCategories()
+	if err != nil {
+		return nil, err
+	}
+
+	categoryNames := make(map[uuid.UUID]string, len(categories))
+	var sortedNames []string
+	for _, cat := range categories {
+		categoryNames[cat.ID] = cat.Name
+		sortedNames = append(sortedNames, cat.Name)
+	}
+	sort.Strings(sortedNames)
+
+	table := &ExportTable{
+		Name:    "Results",
+		Headers: []string{"Rank", "Player", "Email", "Session ID", "Started At", "Nodes Visited"},
+	}
+	nodes, err := c.nodeRepo.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	for _, n := range nodes {
+		table.Headers = append(table.Headers, "Node "+strconv.Itoa(n.Number)+" Score")
+	}
+	for _, name := range sortedNames {
+		table.Headers = append(table.Headers, name+" Accuracy (%)")
+	}
+	table.Headers = append(table.Headers, "Correct", "Answered", "Time Penalty", "Final Score", "Completion Time (s)")
+
+	for i, entry := range entries {
+		currentSession, err := c.sessionRepo.FindByID(entry.SessionID)
+		if err != nil {
+			return nil, err
+		}
+
+		email := ""
+		if p, err := c.playerRepo.FindByID(entry.PlayerID); err == nil && !p.IsGuest() {
+			email = p.Email
+		}
+
+		attempts, err := c.playerRepo.GetAttemptsBySession(entry.SessionID)
+		if err != nil {
+			return nil, err
+		}
+
+		questionIDs := make([]uuid.UUID, len(attempts))
+		for j, attempt := range attempts {
+			questionIDs[j] = attempt.QuestionID
+		}
+
+		questions, err := c.questionRepo.FindByIDs(questionIDs)
+		if err != nil {
+			return nil, err
+		}
+
+		questionCategory := make(map[uuid.UUID]string, len(questions))
+		for _, q := range questions {
+			questionCategory[q.ID] = categoryNames[q.CategoryID]
+		}
+
+		answered := make(map[string]int)
+		correct := make(map[string]int)
+		for _, attempt := range attempts {
+			name := questionCategory[attempt.QuestionID]
+			answered[name]++
+			if attempt.IsCorrect {
+				correct[name]++
+			}
+		}
+
+		row := []interface{}{
+			standings[i].Label(),
+			entry.PlayerName,
+			email,
+			entry.SessionID.String(),
+			currentSession.StartedAt.UTC().Format("2006-01-02T15:04:05Z"),
+			len(currentSession.NodeStartTimes),
+		}
+
+		assigned := []string(currentSession.Categories)
+		for _, n := range nodes {
+			nodeScore := 0
+			if n.Number <= len(assigned) && assigned[n.Number-1] != "" {
+				nodeScore = correct[assigned[n.Number-1]] * config.CORRECT_ANSWER_MULTIPLIER
+			}
+			row = append(row, nodeScore)
+		}
+
+		for _, name := range sortedNames {
+			row = append(row, accuracyPercent(correct[name], answered[name]))
+		}
+
+		row = append(row,
+			currentSession.Score.Correct,
+			currentSession.Score.Total,
+			currentSession.Score.TimePenalty,
+			entry.FinalScore,
+			int(entry.CompletionTime.Seconds()),
+		)
+
+		table.Rows = append(table.Rows, row)
+	}
+
+	return table, nil
+}
+
+func accuracyPercent(correct, answered int) float64 {
+	if answered == 0 {
+		return 0
+	}
+	return math.Round(float64(correct)/float64(answered)*1000) / 10
+}
@@ -0,0 +1,82 @@
+package services
+
+import (
+	"errors"
+
+	"github.com/google/uuid"
+
+	"haoma/internal/domain/audit"
+	"haoma/internal/domain/node"
+)
+
+// RejectScansOutsideGeofence makes scans at geofenced nodes fail unless the
+// reported location puts the player at the node or near its edge; missing
+// and vague locations are refused too, or leaving them out would get around
+// the check. Without it such scans go through and are only flagged for
+// review.
+func (c *CarnivalService) RejectScansOutsideGeofence() {
+	c.rejectOutside = true
+}
+
+// FlaggedScan is a flagged scan together with who made it
+type FlaggedScan struct {
+	audit.ScanFlag
+	PlayerName string
+}
+
+// ListScanFlags returns flagged scans for the anti-cheat review, newest
+// first, optionally only those nobody has reviewed yet
+func (c *CarnivalService) ListScanFlags(pendingOnly bool) ([]FlaggedScan, error) {
+	flags, err := c.scanFlagRepo.GetScanFlags(pendingOnly)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[uuid.UUID]string)
+	scans := make([]FlaggedScan, len(flags))
+	for i, flag := range flags {
+		name, known := names[flag.PlayerID]
+		if !known {
+			name = c.playerName(flag.PlayerID)
+			names[flag.PlayerID] = name
+		}
+		scans[i] = FlaggedScan{ScanFlag: flag, PlayerName: name}
+	}
+	return scans, nil
+}
+
+// ReviewScanFlag marks a flagged scan as dealt with
+func (c *CarnivalService) ReviewScanFlag(flagID, reviewerID uuid.UUID) (*FlaggedScan, error) {
+	flag, err := c.scanFlagRepo.FindScanFlag(flagID)
+	if err != nil {
+		return nil, errors.New("scan flag not found")
+	}
+
+	flag.Review(reviewerID)
+	if err := c.scanFlagRepo.UpdateScanFlag(flag); err != nil {
+		return nil, err
+	}
+	return &FlaggedScan{ScanFlag: *flag, PlayerName: c.playerName(flag.PlayerID)}, nil
+}
+
+// playerName is empty when the player cannot be found
+func (c *CarnivalService) playerName(playerID uuid.UUID) string {
+	p, err := c.playerRepo.FindByID(playerID)
+	if err != nil {
+		return ""
+	}
+	return p.Name
+}
+
+func (c *CarnivalService) flagScan(playerID uuid.UUID, sessionID *uuid.UUID, nodeNumber int, location *node.Location, check node.GeofenceCheck, rejected bool) error {
+	flag := audit.NewScanFlag(playerID, sessionID, nodeNumber, check.Problem, rejected)
+	if location != nil {
+		flag.Latitude, flag.Longitude = &location.Latitude, &location.Longitude
+		if location.Accuracy > 0 {
+			flag.Accuracy = &location.Accuracy
+		}
+	}
+	flag.Distance = check.Distance
+
+	return c.scanFlagRepo.SaveScanFlagOnce(flag)
+}
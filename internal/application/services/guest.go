@@ -0,0 +1,73 @@
+package services
+
+import (
+	"errors"
+
+	"github.com/google/uuid"
+
+	"haoma/internal/domain/player"
+)
+
+// PlayAsGuest creates a guest player for a visitor who only gives a nickname
+func (a *AuthService) PlayAsGuest(nickname string) (*player.Player, error) {
+	if !a.policy.AllowGuests {
+		return nil, errors.New("guest play disabled")
+	}
+	nickname = NormalizeName(nickname)
+	if !a.policy.AllowsName(nickname) {
+		return nil, errors.New("name not allowed")
+	}
+
+	guest, err := player.NewGuestPlayer(a.passwords, nickname)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := a.playerRepo.Save(guest); err != nil {
+		return nil, err
+	}
+
+	return guest, nil
+}
+
+// ClaimGuest turns a guest into a full account under the same signup rules
+// as Register. An empty name keeps the nickname. When verification is not
+// required the account is verified straight away.
+func (a *AuthService) ClaimGuest(playerID uuid.UUID, name, email, password string) (*player.Player, error) {
+	guest, err := a.playerRepo.FindByID(playerID)
+	if err != nil {
+		return nil, errors.New("player not found")
+	}
+	if !guest.IsGuest() {
+		return nil, errors.New("player is not a guest")
+	}
+
+	email = NormalizeEmail(email)
+	if !a.policy.AllowsEmail(email) {
+		return nil, errors.New("email domain not allowed")
+	}
+	name = NormalizeName(name)
+	if !a.policy.AllowsName(name) {
+		return nil, errors.New("name not allowed")
+	}
+	if _, err := a.playerRepo.FindByEmail(email); err == nil {
+		// Rostered students already have an account waiting for them
+		return nil, errors.New("player already exists")
+	}
+	if a.policy.RosterOnly {
+		return nil, errors.New("email not on roster")
+	}
+
+	if err := guest.Claim(a.passwords, name, email, password); err != nil {
+		return nil, err
+	}
+	if !a.policy.RequireVerification {
+		guest.MarkVerified()
+	}
+
+	if err := a.playerRepo.Update(guest); err != nil {
+		return nil, err
+	}
+
+	return guest, nil
+}
@@ -0,0 +1,205 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"haoma/internal/config"
+	"haoma/internal/domain/event"
+	"haoma/internal/domain/leaderboard"
+)
+
+// LeaderboardView is the ranking as a particular viewer is allowed to see it
+type LeaderboardView struct {
+	Entries   []leaderboard.Entry
+	Standings []leaderboard.Standing
+	Frozen    bool
+	FrozenAt  *time.Time
+}
+
+// GetLeaderboard returns the public top entries, or the live ranking for
+// privileged viewers while the board is frozen
+func (c *CarnivalService) GetLeaderboard(privileged bool) (*LeaderboardView, error) {
+	snapshot, currentEvent, err := c.activeFreezeSnapshot()
+	if err != nil {
+		return nil, err
+	}
+
+	tieBreakers := rankingOf(currentEvent)
+
+	view := &LeaderboardView{}
+	if snapshot != nil {
+		frozenAt := currentEvent.FreezesAt()
+		view.Frozen = true
+		view.FrozenAt = &frozenAt
+
+		if !privileged {
+			view.Entries = topEntries(snapshot.Entries)
+			view.Standings = leaderboard.Standings(view.Entries, tieBreakers)
+			return view, nil
+		}
+	}
+
+	entries, err := c.leaderboardRepo.GetTop10(tieBreakers)
+	if err != nil {
+		return nil, err
+	}
+	view.Entries = entries
+	view.Standings = leaderboard.Standings(entries, tieBreakers)
+
+	return view, nil
+}
+
+func (c *CarnivalService) CreateEvent(title string, startsAt, endsAt time.Time, freezeMinutes int, tieBreakerNames []string) (*event.Event, error) {
+	if !endsAt.After(startsAt) {
+		return nil, errors.New("event must end after it starts")
+	}
+	if freezeMinutes < 0 || time.Duration(freezeMinutes)*time.Minute > endsAt.Sub(startsAt) {
+		return nil, errors.New("invalid freeze window")
+	}
+
+	tieBreakers, err := leaderboard.ParseTieBreakers(tieBreakerNames)
+	if err != nil {
+		return nil, errors.New("invalid tie-breakers")
+	}
+
+	newEvent := event.NewEvent(title, startsAt, endsAt, freezeMinutes, tieBreakers)
+	if err := c.eventRepo.Save(newEvent); err != nil {
+		return nil, err
+	}
+
+	return newEvent, nil
+}
+
+func (c *CarnivalService) GetCurrentEvent() (*event.Event, error) {
+	return c.eventRepo.FindCurrent()
+}
+
+// UpdateTieBreakers replaces the current event's tie-break chain
+func (c *CarnivalService) UpdateTieBreakers(tieBreakerNames []string) (*event.Event, error) {
+	currentEvent, err := c.eventRepo.FindCurrent()
+	if err != nil {
+		return nil, errors.New("event not found")
+	}
+
+	tieBreakers, err := leaderboard.ParseTieBreakers(tieBreakerNames)
+	if err != nil {
+		return nil, errors.New("invalid tie-breakers")
+	}
+
+	currentEvent.TieBreakers = tieBreakers
+	currentEvent.UpdatedAt = time.Now()
+	if err := c.eventRepo.Update(currentEvent); err != nil {
+		return nil, err
+	}
+
+	return currentEvent, nil
+}
+
+// UnfreezeLeaderboard reveals every hidden change at once
+func (c *CarnivalService) UnfreezeLeaderboard() error {
+	currentEvent, err := c.eventRepo.FindCurrent()
+	if err != nil {
+		return errors.New("event not found")
+	}
+
+	if !currentEvent.IsFrozen(time.Now()) {
+		return errors.New("leaderboard not frozen")
+	}
+
+	currentEvent.Unfreeze()
+	return c.eventRepo.Update(currentEvent)
+}
+
+// RevealNextLeaderboardChange uncovers one hidden rank change at a time and
+// unfreezes the board once nothing is left to reveal
+func (c *CarnivalService) RevealNextLeaderboardChange() (*leaderboard.RevealStep, error) {
+	snapshot, currentEvent, err := c.activeFreezeSnapshot()
+	if err != nil {
+		return nil, err
+	}
+	if snapshot == nil {
+		return nil, errors.New("leaderboard not frozen")
+	}
+
+	tieBreakers := currentEvent.Ranking()
+	live, err := c.leaderboardRepo.GetAll(tieBreakers)
+	if err != nil {
+		return nil, err
+	}
+
+	step := snapshot.RevealNext(live, tieBreakers)
+	if step == nil {
+		currentEvent.Unfreeze()
+		return nil, c.eventRepo.Update(currentEvent)
+	}
+
+	if err := c.leaderboardRepo.SaveSnapshot(snapshot); err != nil {
+		return nil, err
+	}
+
+	return step, nil
+}
+
+// activeFreezeSnapshot returns the frozen board of the current event, taking
+// the snapshot on first use after the freeze begins. It returns nil when the
+// board is not frozen, and an error when it cannot tell.
+func (c *CarnivalService) activeFreezeSnapshot() (*leaderboard.Snapshot, *event.Event, error) {
+	currentEvent, err := c.eventRepo.FindCurrent()
+	if err != nil {
+		if err.Error() == "event not found" {
+			return nil, nil, nil // No event scheduled means nothing to freeze
+		}
+		// Serving the live board could leak a frozen one
+		return nil, nil, err
+	}
+
+	if !currentEvent.IsFrozen(time.Now()) {
+		return nil, currentEvent, nil
+	}
+
+	snapshot, err := c.leaderboardRepo.FindSnapshotByEvent(currentEvent.ID)
+	if err == nil {
+		return snapshot, currentEvent, nil
+	}
+	if err.Error() != "snapshot not found" {
+		return nil, nil, err
+	}
+
+	live, err := c.leaderboardRepo.GetAll(currentEvent.Ranking())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Requests arriving together as the freeze begins all get here; the
+	// first snapshot stored wins
+	snapshot, err = c.leaderboardRepo.CreateSnapshot(leaderboard.NewSnapshot(currentEvent.ID, live, currentEvent.Ranking()))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return snapshot, currentEvent, nil
+}
+
+// currentRanking returns the tie-break chain of the current event
+func (c *CarnivalService) currentRanking() []leaderboard.TieBreaker {
+	currentEvent, err := c.eventRepo.FindCurrent()
+	if err != nil {
+		return leaderboard.DefaultTieBreakers
+	}
+	return currentEvent.Ranking()
+}
+
+func rankingOf(currentEvent *event.Event) []leaderboard.TieBreaker {
+	if currentEvent == nil {
+		return leaderboard.DefaultTieBreakers
+	}
+	return currentEvent.Ranking()
+}
+
+func topEntries(entries []leaderboard.Entry) []leaderboard.Entry {
+	if len(entries) <= config.LEADERBOARD_TOP_ENTRIES {
+		return entries
+	}
+	return entries[:config.LEADERBOARD_TOP_ENTRIES]
+}
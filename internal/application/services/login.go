@@ -0,0 +1,170 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"haoma/internal/domain/audit"
+	"haoma/internal/domain/player"
+)
+
+// LoginAttemptTracker counts failed logins per key and says how long a key
+// has to back off. Reserve checks the backoff and counts the attempt as a
+// failure in one step; Release takes it back once the attempt succeeds.
+type LoginAttemptTracker interface {
+	Reserve(key string) time.Duration
+	Release(key string)
+	Reset(key string)
+}
+
+type AuditRepository interface {
+	SaveLoginFailure(failure *audit.LoginFailure) error
+	DeleteLoginFailures(playerID uuid.UUID, email string) error
+	DeleteScanFlags(playerID uuid.UUID) error
+}
+
+// LoginThrottledError means the account or client IP is backing off
+type LoginThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginThrottledError) Error() string {
+	return fmt.Sprintf("too many login attempts, retry in %s", e.RetryAfter.Round(time.Second))
+}
+
+// LoginService checks passwords while slowing down guessing, both per
+// account and per client IP. Unknown emails are treated exactly like wrong
+// passwords so responses never reveal whether an account exists.
+type LoginService struct {
+	playerRepo PlayerRepository
+	passwords  player.PasswordHasher
+	tokenRepo  TokenRepository
+	accounts   LoginAttemptTracker
+	addresses  LoginAttemptTracker
+	auditRepo  AuditRepository
+	decoy      *player.Player // Keeps unknown-email logins as slow as real ones
+}
+
+func NewLoginService(playerRepo PlayerRepository, passwords player.PasswordHasher, tokenRepo TokenRepository, accounts, addresses LoginAttemptTracker, auditRepo AuditRepository) (*LoginService, error) {
+	decoy, err := player.NewPlayer(passwords, "decoy", "decoy@haoma.invalid", uuid.New().String())
+	if err != nil {
+		return nil, err
+	}
+
+	return &LoginService{
+		playerRepo: playerRepo,
+		passwords:  passwords,
+		tokenRepo:  tokenRepo,
+		accounts:   accounts,
+		addresses:  addresses,
+		auditRepo:  auditRepo,
+		decoy:      decoy,
+	}, nil
+}
+
+// Login returns the player if the password is right. It fails with
+// "invalid credentials" or a *LoginThrottledError.
+func (l *LoginService) Login(email, password, ipAddress, userAgent string) (*player.Player, error) {
+	accountKey := strings.ToLower(strings.TrimSpace(email))
+
+	if wait := l.reserve(accountKey, ipAddress); wait > 0 {
+		l.recordFailure(email, nil, ipAddress, userAgent, audit.ReasonThrottled)
+		return nil, &LoginThrottledError{RetryAfter: wait}
+	}
+
+	p, err := l.playerRepo.FindByEmail(email)
+	if err != nil {
+		l.decoy.ValidatePassword(l.passwords, password)
+		l.recordFailure(email, nil, ipAddress, userAgent, audit.ReasonUnknownAccount)
+		return nil, errors.New("invalid credentials")
+	}
+
+	if !p.ValidatePassword(l.passwords, password) {
+		l.recordFailure(email, &p.ID, ipAddress, userAgent, audit.ReasonWrongPassword)
+		return nil, errors.New("invalid credentials")
+	}
+
+	l.succeed(accountKey, ipAddress)
+	l.upgradePasswordHash(p, password)
+	return p, nil
+}
+
+// VerifySecondFactor completes a login for a player with two-factor
+// authentication, who already got their password right. The code may come
+// from the authenticator app or be an unused recovery code. Guessing is
+// throttled like passwords; it fails with "invalid code" or a
+// *LoginThrottledError.
+func (l *LoginService) VerifySecondFactor(playerID uuid.UUID, code, ipAddress, userAgent string) (*player.Player, error) {
+	p, err := l.playerRepo.FindByID(playerID)
+	if err != nil {
+		return nil, errors.New("invalid code")
+	}
+	accountKey := strings.ToLower(p.Email)
+
+	if wait := l.reserve(accountKey, ipAddress); wait > 0 {
+		l.recordFailure(p.Email, &p.ID, ipAddress, userAgent, audit.ReasonThrottled)
+		return nil, &LoginThrottledError{RetryAfter: wait}
+	}
+
+	ok, err := checkSecondFactor(p, code, l.playerRepo, l.tokenRepo)
+	if err != nil {
+		// Not a guess, so it should not count as one
+		l.accounts.Release(accountKey)
+		l.addresses.Release(ipAddress)
+		return nil, err
+	}
+	if !ok {
+		l.recordFailure(p.Email, &p.ID, ipAddress, userAgent, audit.ReasonWrongSecondFactor)
+		return nil, errors.New("invalid code")
+	}
+
+	l.succeed(accountKey, ipAddress)
+	return p, nil
+}
+
+// upgradePasswordHash rehashes a correct password stored with an outdated
+// algorithm or parameters. The login succeeds either way, so a failure only
+// leaves the old hash in place until the next login. Only the hash column is
+// written, and only if nobody changed the password since it was read.
+func (l *LoginService) upgradePasswordHash(p *player.Player, password string) {
+	if !p.PasswordNeedsRehash(l.passwords) {
+		return
+	}
+	hashedPassword, err := l.passwords.Hash(password)
+	if err != nil {
+		return
+	}
+	if err := l.playerRepo.ReplacePasswordHash(p.ID, p.PasswordHash, hashedPassword); err == nil {
+		p.PasswordHash = hashedPassword
+	}
+}
+
+// reserve counts an attempt against both the account and the IP before the
+// password is checked, or returns how long one of them must back off
+func (l *LoginService) reserve(accountKey, ipAddress string) time.Duration {
+	if wait := l.accounts.Reserve(accountKey); wait > 0 {
+		return wait
+	}
+	if wait := l.addresses.Reserve(ipAddress); wait > 0 {
+		l.accounts.Release(accountKey)
+		return wait
+	}
+	return 0
+}
+
+// succeed clears the account's failures. The IP only gets its reserved
+// attempt back, or one good account could clear a guessing IP.
+func (l *LoginService) succeed(accountKey, ipAddress string) {
+	l.accounts.Reset(accountKey)
+	l.addresses.Release(ipAddress)
+}
+
+// recordFailure writes the audit trail. A failed write must not change the
+// login outcome, so the error is dropped.
+func (l *LoginService) recordFailure(email string, playerID *uuid.UUID, ipAddress, userAgent, reason string) {
+	_ = l.auditRepo.SaveLoginFailure(audit.NewLoginFailure(email, playerID, ipAddress, userAgent, reason))
+}
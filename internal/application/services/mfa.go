@@ -0,0 +1,199 @@
+package services
+
+import (
+	"errors"
+
+	"github.com/google/uuid"
+
+	"haoma/internal/config"
+	"haoma/internal/domain/otp"
+	"haoma/internal/domain/player"
+	"haoma/internal/domain/token"
+)
+
+// TOTPEnrollment is what the player's authenticator app needs to start
+// producing codes
+type TOTPEnrollment struct {
+	Secret string
+	URI    string // otpauth:// link, usually shown as a QR code
+}
+
+// SecondFactorStatus describes a player's two-factor authentication
+type SecondFactorStatus struct {
+	Enabled           bool
+	Required          bool // The player's role may not do without it
+	RecoveryCodesLeft int
+}
+
+// RequiresSecondFactor reports whether the player's role must use two-factor
+// authentication
+func (s *AccountService) RequiresSecondFactor(p *player.Player) bool {
+	return p.HasRole(s.secondFactorRoles...)
+}
+
+func (s *AccountService) SecondFactorStatus(playerID uuid.UUID) (*SecondFactorStatus, error) {
+	p, err := s.playerRepo.FindByID(playerID)
+	if err != nil {
+		return nil, errors.New("player not found")
+	}
+
+	status := &SecondFactorStatus{Enabled: p.HasTOTP(), Required: s.RequiresSecondFactor(p)}
+	if p.HasTOTP() {
+		if status.RecoveryCodesLeft, err = s.tokenRepo.CountUnusedRecoveryCodes(p.ID); err != nil {
+			return nil, err
+		}
+	}
+	return status, nil
+}
+
+// BeginTOTPEnrollment generates a new authenticator secret. Starting over
+// replaces a secret that was never confirmed.
+func (s *AccountService) BeginTOTPEnrollment(playerID uuid.UUID) (*TOTPEnrollment, error) {
+	p, err := s.playerRepo.FindByID(playerID)
+	if err != nil {
+		return nil, errors.New("player not found")
+	}
+	if p.IsGuest() {
+		return nil, errors.New("player is a guest")
+	}
+	if p.HasTOTP() {
+		return nil, errors.New("two-factor already enabled")
+	}
+
+	secret, err := otp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	p.BeginTOTPEnrollment(secret)
+	if err := s.playerRepo.Update(p); err != nil {
+		return nil, err
+	}
+
+	return &TOTPEnrollment{
+		Secret: secret,
+		URI:    otp.URI(config.TOTP_ISSUER, p.Email, secret),
+	}, nil
+}
+
+// EnableTOTP turns on two-factor authentication once the player enters a
+// code from their app, and hands out the first set of recovery codes. Other
+// devices are logged out, as their logins never passed the second factor.
+func (s *AccountService) EnableTOTP(playerID uuid.UUID, code string) (*player.Player, []string, error) {
+	p, err := s.playerRepo.FindByID(playerID)
+	if err != nil {
+		return nil, nil, errors.New("player not found")
+	}
+	if p.HasTOTP() {
+		return nil, nil, errors.New("two-factor already enabled")
+	}
+	if p.TOTPSecret == nil {
+		return nil, nil, errors.New("two-factor enrollment not started")
+	}
+	if !p.CheckTOTP(code) {
+		return nil, nil, errors.New("invalid code")
+	}
+
+	p.EnableTOTP()
+	if err := s.playerRepo.Update(p); err != nil {
+		return nil, nil, err
+	}
+
+	codes, err := s.issueRecoveryCodes(p.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := s.revokeAll(p.ID); err != nil {
+		return nil, nil, err
+	}
+
+	return p, codes, nil
+}
+
+// DisableTOTP turns two-factor authentication off after one last code. Roles
+// that require it cannot switch it off.
+func (s *AccountService) DisableTOTP(playerID uuid.UUID, code string) error {
+	p, err := s.playerRepo.FindByID(playerID)
+	if err != nil {
+		return errors.New("player not found")
+	}
+	if !p.HasTOTP() {
+		return errors.New("two-factor not enabled")
+	}
+	if s.RequiresSecondFactor(p) {
+		return errors.New("two-factor required for role")
+	}
+
+	ok, err := checkSecondFactor(p, code, s.playerRepo, s.tokenRepo)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("invalid code")
+	}
+
+	p.DisableTOTP()
+	if err := s.playerRepo.Update(p); err != nil {
+		return err
+	}
+	return s.tokenRepo.ReplaceRecoveryCodes(p.ID, nil)
+}
+
+// RegenerateRecoveryCodes replaces every recovery code, used or not, with a
+// fresh set. A code from the authenticator app is required.
+func (s *AccountService) RegenerateRecoveryCodes(playerID uuid.UUID, code string) ([]string, error) {
+	p, err := s.playerRepo.FindByID(playerID)
+	if err != nil {
+		return nil, errors.New("player not found")
+	}
+	if !p.HasTOTP() {
+		return nil, errors.New("two-factor not enabled")
+	}
+	if !p.CheckTOTP(code) {
+		return nil, errors.New("invalid code")
+	}
+	if err := s.playerRepo.Update(p); err != nil {
+		return nil, err
+	}
+
+	return s.issueRecoveryCodes(p.ID)
+}
+
+func (s *AccountService) issueRecoveryCodes(playerID uuid.UUID) ([]string, error) {
+	values := make([]string, 0, config.RECOVERY_CODE_COUNT)
+	codes := make([]*token.RecoveryCode, 0, config.RECOVERY_CODE_COUNT)
+	for i := 0; i < config.RECOVERY_CODE_COUNT; i++ {
+		value, hash, err := token.GenerateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+		codes = append(codes, token.NewRecoveryCode(playerID, hash))
+	}
+
+	if err := s.tokenRepo.ReplaceRecoveryCodes(playerID, codes); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// checkSecondFactor accepts a code from the authenticator app or an unused
+// recovery code, spending it either way
+func checkSecondFactor(p *player.Player, code string, playerRepo PlayerRepository, tokenRepo TokenRepository) (bool, error) {
+	if !p.HasTOTP() {
+		return false, nil
+	}
+
+	if p.CheckTOTP(code) {
+		return true, playerRepo.Update(p)
+	}
+
+	if err := tokenRepo.UseRecoveryCode(p.ID, token.HashRecoveryCode(code)); err != nil {
+		if err.Error() == "recovery code not found" {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,153 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+
+	"haoma/internal/config"
+	"haoma/internal/domain/node"
+)
+
+// NodeDetails are the registry fields an organizer sets for a node. An empty
+// code or name falls back to the defaults, e.g. NODE_003 and "Node 3".
+type NodeDetails struct {
+	Number     int
+	Code       string
+	Name       string
+	Location   string
+	Latitude   *float64
+	Longitude  *float64
+	Radius     *float64   // Geofence in meters; nil checks nothing
+	CategoryID *uuid.UUID // Pinned category; nil draws one at random per session
+	Active     bool
+}
+
+// ListNodes returns the whole registry, inactive nodes included
+func (c *CarnivalService) ListNodes() ([]node.Node, error) {
+	return c.nodeRepo.GetAll()
+}
+
+func (c *CarnivalService) CreateNode(details NodeDetails) (*node.Node, error) {
+	registered := node.NewNode(details.Number, details.Code, details.Name)
+	if err := c.applyNodeDetails(registered, details); err != nil {
+		return nil, err
+	}
+
+	if err := c.nodeRepo.Save(registered); err != nil {
+		return nil, err
+	}
+	return registered, nil
+}
+
+// UpdateNode replaces every editable field of a node. The number cannot
+// change: sessions deal categories by node number, so renumbering would hand
+// the station a different category mid-event. Sessions already under way
+// keep the categories they were dealt.
+func (c *CarnivalService) UpdateNode(id uuid.UUID, details NodeDetails) (*node.Node, error) {
+	registered, err := c.nodeRepo.FindByID(id)
+	if err != nil {
+		return nil, errors.New("node not found")
+	}
+	if details.Number != registered.Number {
+		return nil, errors.New("node number cannot change")
+	}
+
+	registered.Code = details.Code
+	registered.Name = details.Name
+	if registered.Code == "" {
+		registered.Code = node.DefaultCode(details.Number)
+	}
+	if registered.Name == "" {
+		registered.Name = node.DefaultName(details.Number)
+	}
+	if err := c.applyNodeDetails(registered, details); err != nil {
+		return nil, err
+	}
+
+	registered.UpdatedAt = time.Now()
+	if err := c.nodeRepo.Update(registered); err != nil {
+		return nil, err
+	}
+	return registered, nil
+}
+
+// DeleteNode removes a node from the registry. Deactivating it instead keeps
+// its number and code reserved.
+func (c *CarnivalService) DeleteNode(id uuid.UUID) error {
+	registered, err := c.nodeRepo.FindByID(id)
+	if err != nil {
+		return errors.New("node not found")
+	}
+	return c.nodeRepo.Delete(registered)
+}
+
+// applyNodeDetails copies the remaining fields and checks the node against
+// the rest of the registry
+func (c *CarnivalService) applyNodeDetails(registered *node.Node, details NodeDetails) error {
+	registered.Location = details.Location
+	registered.Latitude = details.Latitude
+	registered.Longitude = details.Longitude
+	registered.Radius = details.Radius
+	registered.CategoryID = details.CategoryID
+	registered.Active = details.Active
+
+	if err := registered.Validate(); err != nil {
+		return err
+	}
+
+	if other, err := c.nodeRepo.FindByNumber(registered.Number); err == nil && other.ID != registered.ID {
+		return errors.New("node number taken")
+	}
+	if other, err := c.nodeRepo.FindByCode(registered.Code); err == nil && other.ID != registered.ID {
+		return errors.New("node code taken")
+	}
+
+	if registered.CategoryID == nil {
+		return nil
+	}
+
+	categories, err := c.questionRepo.GetCategories()
+	if err != nil {
+		return err
+	}
+	found := false
+	for _, cat := range categories {
+		if cat.ID == *registered.CategoryID {
+			found = cat.Name != config.FUN_CATEGORY_NAME // Fun questions come with every node
+			break
+		}
+	}
+	if !found {
+		return errors.New("category not found")
+	}
+
+	// A category answered at two nodes would count toward both
+	nodes, err := c.nodeRepo.GetAll()
+	if err != nil {
+		return err
+	}
+	for _, other := range nodes {
+		if other.ID != registered.ID && other.CategoryID != nil && *other.CategoryID == *registered.CategoryID {
+			return errors.New("category already pinned")
+		}
+	}
+	return nil
+}
+
+// activeNodes returns the nodes players can scan, by number
+func (c *CarnivalService) activeNodes() ([]node.Node, error) {
+	nodes, err := c.nodeRepo.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	active := nodes[:0]
+	for _, n := range nodes {
+		if n.Active {
+			active = append(active, n)
+		}
+	}
+	return active, nil
+}
@@ -0,0 +1,170 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"haoma/internal/domain/player"
+	"haoma/internal/domain/token"
+)
+
+// RosterRow is one enrolled student from an imported class list
+type RosterRow struct {
+	StudentID string
+	Name      string
+	Email     string
+}
+
+// RosterImportResult summarizes what an import changed
+type RosterImportResult struct {
+	Invited   int      // New accounts waiting for their student
+	Enrolled  int      // Existing accounts that were given a student ID
+	Unchanged int      // Students already on the roster
+	Errors    []string // Rows that were skipped, with the reason
+}
+
+// ActivationCode is a freshly issued code; it is only ever shown once
+type ActivationCode struct {
+	StudentID string
+	Name      string
+	Email     string
+	Code      string
+}
+
+// ImportRoster pre-registers every student on the roster. Students who
+// already have an account are enrolled instead. Rows are numbered from 2 in
+// errors, matching the spreadsheet with its header row.
+func (a *AuthService) ImportRoster(rows []RosterRow) (*RosterImportResult, error) {
+	result := &RosterImportResult{}
+	seen := make(map[string]bool)
+
+	for i, row := range rows {
+		line := i + 2
+		studentID := strings.TrimSpace(row.StudentID)
+		name := strings.TrimSpace(row.Name)
+		email := NormalizeEmail(row.Email)
+
+		switch {
+		case studentID == "":
+			result.Errors = append(result.Errors, fmt.Sprintf("row %d: missing student ID", line))
+			continue
+		case !strings.Contains(email, "@"):
+			result.Errors = append(result.Errors, fmt.Sprintf("row %d: invalid email %q", line, email))
+			continue
+		case seen[studentID]:
+			result.Errors = append(result.Errors, fmt.Sprintf("row %d: duplicate student ID %s", line, studentID))
+			continue
+		}
+		seen[studentID] = true
+		if name == "" {
+			name = studentID
+		}
+
+		if enrolled, err := a.playerRepo.FindByStudentID(studentID); err == nil {
+			if enrolled.Email != email {
+				result.Errors = append(result.Errors, fmt.Sprintf("row %d: student %s is already enrolled as %s", line, studentID, enrolled.Email))
+				continue
+			}
+			result.Unchanged++
+			continue
+		}
+
+		if existing, err := a.playerRepo.FindByEmail(email); err == nil {
+			if existing.IsRostered() {
+				result.Errors = append(result.Errors, fmt.Sprintf("row %d: %s is already enrolled under another student ID", line, email))
+				continue
+			}
+			existing.Enroll(studentID)
+			if err := a.playerRepo.Update(existing); err != nil {
+				return nil, err
+			}
+			result.Enrolled++
+			continue
+		}
+
+		invited, err := player.NewInvitedPlayer(a.passwords, studentID, name, email)
+		if err != nil {
+			return nil, err
+		}
+		if err := a.playerRepo.Save(invited); err != nil {
+			return nil, err
+		}
+		result.Invited++
+	}
+
+	return result, nil
+}
+
+// IssueActivationCodes creates printable one-time codes for invited players.
+// By default only players without a code get one; regenerate replaces every
+// outstanding code, invalidating earlier printouts.
+func (a *AuthService) IssueActivationCodes(regenerate bool) ([]ActivationCode, error) {
+	invited, err := a.playerRepo.GetInvited()
+	if err != nil {
+		return nil, err
+	}
+
+	var codes []ActivationCode
+	for i := range invited {
+		p := &invited[i]
+		if p.ActivationCodeHash != nil && !regenerate {
+			continue
+		}
+
+		code, hash, err := token.GenerateActivationCode()
+		if err != nil {
+			return nil, err
+		}
+		p.SetActivationCode(hash)
+		if err := a.playerRepo.Update(p); err != nil {
+			return nil, err
+		}
+
+		codes = append(codes, ActivationCode{
+			StudentID: *p.StudentID,
+			Name:      p.Name,
+			Email:     p.Email,
+			Code:      code,
+		})
+	}
+
+	return codes, nil
+}
+
+// ActivateAccount lets a student claim their pre-registered account with the
+// code they were handed. The code proves who they are, so the email counts
+// as verified.
+func (a *AuthService) ActivateAccount(code, password string) (*player.Player, error) {
+	codeHash := token.HashActivationCode(code)
+	invited, err := a.playerRepo.FindByActivationCodeHash(codeHash)
+	if err != nil || !invited.IsInvited() {
+		return nil, errors.New("invalid activation code")
+	}
+
+	if err := invited.Activate(a.passwords, password); err != nil {
+		return nil, err
+	}
+	invited.ProveEmail()
+
+	if err := a.playerRepo.ClaimInvitation(invited, &codeHash); err != nil {
+		if err.Error() == "invitation not found" {
+			return nil, errors.New("invalid activation code")
+		}
+		return nil, err
+	}
+
+	return invited, nil
+}
+
+// ActivationCodeTable lays out issued codes for printing
+func ActivationCodeTable(codes []ActivationCode) *ExportTable {
+	table := &ExportTable{
+		Name:    "Activation-Codes",
+		Headers: []string{"Student ID", "Name", "Email", "Activation Code"},
+	}
+	for _, code := range codes {
+		table.Rows = append(table.Rows, []interface{}{code.StudentID, code.Name, code.Email, code.Code})
+	}
+	return table
+}
@@ -0,0 +1,41 @@
+package services
+
+import (
+	"testing"
+
+	"haoma/internal/domain/player"
+)
+
+func TestImportRoster_NormalizesEmails(t *testing.T) {
+	authService, players, _, _ := newTestAuthService(SignupPolicy{RosterOnly: true})
+
+	existing, _ := player.NewPlayer(testPasswords, "Gordafarid", "gordafarid@uni.edu", "white_fortress")
+	players.Save(existing)
+
+	result, err := authService.ImportRoster([]RosterRow{
+		{StudentID: "40012", Name: "Gordafarid", Email: " Gordafarid@Uni.edu "},
+		{StudentID: "40013", Name: "Jane Doe", Email: "Jane@Uni.edu"},
+	})
+	if err != nil {
+		t.Fatalf("ImportRoster failed: %v", err)
+	}
+	if result.Enrolled != 1 || result.Invited != 1 || len(result.Errors) != 0 {
+		t.Fatalf("Expected one enrolled and one invited, got %+v", result)
+	}
+
+	enrolled, _ := players.FindByID(existing.ID)
+	if !enrolled.IsRostered() {
+		t.Errorf("Expected the existing account to be enrolled instead of duplicated")
+	}
+
+	invited, err := players.FindByStudentID("40013")
+	if err != nil || invited.Email != "jane@uni.edu" {
+		t.Fatalf("Expected the invited account under jane@uni.edu, got %v (%v)", invited, err)
+	}
+
+	// Importing the same roster again changes nothing
+	again, err := authService.ImportRoster([]RosterRow{{StudentID: "40013", Name: "Jane Doe", Email: "JANE@uni.edu"}})
+	if err != nil || again.Unchanged != 1 {
+		t.Errorf("Expected the student to be unchanged, got %+v (%v)", again, err)
+	}
+}
@@ -0,0 +1,246 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/google/uuid"
+
+	"haoma/internal/config"
+	"haoma/internal/domain/player"
+	"haoma/internal/domain/token"
+)
+
+// SignupPolicy decides who may create an account and whether they must
+// confirm their email before playing
+type SignupPolicy struct {
+	AllowedDomains      []string // Empty allows every domain; subdomains of a listed domain are allowed too
+	RequireVerification bool
+	RosterOnly          bool     // Only students imported from the roster may sign up
+	AllowGuests         bool     // Visitors may play under a nickname without an account
+	BlockedNameWords    []string // Names containing any of these cannot be shown on the leaderboard
+}
+
+// AllowsEmail reports whether the email's domain is on the allow-list
+func (p SignupPolicy) AllowsEmail(email string) bool {
+	if len(p.AllowedDomains) == 0 {
+		return true
+	}
+
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+	// A trailing dot names the same domain
+	domain := strings.TrimSuffix(strings.ToLower(email[at+1:]), ".")
+
+	for _, allowed := range p.AllowedDomains {
+		allowed = strings.TrimSuffix(strings.ToLower(allowed), ".")
+		if allowed == "" {
+			continue
+		}
+		if domain == allowed || strings.HasSuffix(domain, "."+allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// leetspeak maps look-alike characters to the letters they stand in for
+var leetspeak = strings.NewReplacer("0", "o", "1", "i", "3", "e", "4", "a", "5", "s", "7", "t", "@", "a", "$", "s", "!", "i")
+
+// AllowsName reports whether a display name may appear on the leaderboard.
+// Invisible characters are refused outright. Each word is checked on its own
+// after folding case and look-alike digits, so "Ahmad Minaei" passes while
+// "4dm1n" and "superadmin" do not; a word that merely hides a blocked word
+// in its middle, like "badminton", passes too.
+func (p SignupPolicy) AllowsName(name string) bool {
+	for _, r := range name {
+		if !unicode.IsPrint(r) {
+			return false
+		}
+	}
+
+	words := strings.FieldsFunc(leetspeak.Replace(strings.ToLower(name)), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+
+	for _, blocked := range p.BlockedNameWords {
+		blocked = strings.ToLower(blocked)
+		if blocked == "" {
+			continue
+		}
+		for _, word := range words {
+			if strings.HasPrefix(word, blocked) || strings.HasSuffix(word, blocked) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// NormalizeEmail trims and lower-cases an email address. Every address is
+// stored this way, so one mailbox can only ever hold one account.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// NormalizeName collapses the whitespace in a display name
+func NormalizeName(name string) string {
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// CanPlay reports whether the player may start sessions. Guests have no
+// email to verify.
+func (p SignupPolicy) CanPlay(pl *player.Player) bool {
+	return !p.RequireVerification || pl.IsVerified() || pl.IsGuest()
+}
+
+// Register creates a player account, or starts claiming the invited account
+// a roster import made for this email. When verification is not required the
+// account is verified straight away.
+func (a *AuthService) Register(name, email, password, verifyURL string) (*player.Player, error) {
+	email = NormalizeEmail(email)
+	if !a.policy.AllowsEmail(email) {
+		return nil, errors.New("email domain not allowed")
+	}
+	name = NormalizeName(name)
+	if !a.policy.AllowsName(name) {
+		return nil, errors.New("name not allowed")
+	}
+
+	existing, err := a.playerRepo.FindByEmail(email)
+	if err == nil && !existing.IsInvited() {
+		return nil, errors.New("player already exists")
+	}
+	if err != nil && a.policy.RosterOnly {
+		return nil, errors.New("email not on roster")
+	}
+
+	if existing != nil {
+		return a.claimInvitation(existing, password, verifyURL)
+	}
+
+	newPlayer, err := player.NewPlayer(a.passwords, name, email, password)
+	if err != nil {
+		return nil, err
+	}
+	if !a.policy.RequireVerification {
+		newPlayer.MarkVerified()
+	}
+
+	if err := a.playerRepo.Save(newPlayer); err != nil {
+		return nil, err
+	}
+
+	return newPlayer, nil
+}
+
+// claimInvitation lets a student take over their pre-registered account by
+// signing up. Anyone knowing the address could ask, so the account is left
+// as it is, activation code included; the new password waits on the
+// verification token and only takes effect once the emailed link is used.
+// Without email verification only the printed activation code (or SSO) will
+// do. The roster's name is kept.
+func (a *AuthService) claimInvitation(invited *player.Player, password, verifyURL string) (*player.Player, error) {
+	if !a.policy.RequireVerification {
+		return nil, errors.New("activation code required")
+	}
+
+	passwordHash, err := a.passwords.Hash(password)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := a.sendVerificationEmail(invited, verifyURL, &passwordHash); err != nil {
+		return nil, err
+	}
+
+	return invited, nil
+}
+
+// SendVerificationEmail emails the player a single-use verification link. The
+// link points at verifyURL with the token appended; without one the bare
+// token is sent.
+func (a *AuthService) SendVerificationEmail(playerID uuid.UUID, verifyURL string) error {
+	p, err := a.playerRepo.FindByID(playerID)
+	if err != nil {
+		return errors.New("player not found")
+	}
+	if p.IsGuest() {
+		return errors.New("player is a guest")
+	}
+	if p.IsVerified() {
+		return errors.New("email already verified")
+	}
+
+	return a.sendVerificationEmail(p, verifyURL, nil)
+}
+
+// sendVerificationEmail issues and emails a verification token, carrying the
+// password of a pending invitation claim if there is one
+func (a *AuthService) sendVerificationEmail(p *player.Player, verifyURL string, pendingPasswordHash *string) error {
+	value, hash, err := token.Generate()
+	if err != nil {
+		return err
+	}
+
+	verification := token.NewEmailVerificationToken(p.ID, hash, config.EMAIL_VERIFICATION_EXPIRY)
+	verification.PendingPasswordHash = pendingPasswordHash
+	if err := a.tokenRepo.SaveEmailVerificationToken(verification); err != nil {
+		return err
+	}
+
+	hours := int(config.EMAIL_VERIFICATION_EXPIRY.Hours())
+	body := fmt.Sprintf("Hi %s,\n\nUse this within %d hours to confirm your email and start playing:\n\n%s\n\n"+
+		"If you did not sign up for the carnival, you can ignore this email.\n", p.Name, hours, tokenLink(verifyURL, value))
+
+	return a.mailer.Send(p.Email, "Confirm your Haoma email", body)
+}
+
+// VerifyEmail confirms a player's address using an emailed token. A token
+// from signing up with an invited address also completes that claim.
+func (a *AuthService) VerifyEmail(value string) (*player.Player, error) {
+	verification, err := a.tokenRepo.FindEmailVerificationTokenByHash(token.Hash(value))
+	if err != nil || !verification.CanBeUsed() {
+		return nil, errors.New("invalid verification token")
+	}
+
+	if err := a.tokenRepo.UseEmailVerificationToken(verification); err != nil {
+		return nil, errors.New("invalid verification token")
+	}
+
+	p, err := a.playerRepo.FindByID(verification.PlayerID)
+	if err != nil {
+		return nil, errors.New("invalid verification token")
+	}
+
+	if verification.PendingPasswordHash != nil {
+		if !p.IsInvited() {
+			return nil, errors.New("invalid verification token") // Claimed another way meanwhile
+		}
+		p.ActivateWithHash(*verification.PendingPasswordHash)
+		p.ProveEmail()
+		if err := a.playerRepo.ClaimInvitation(p, nil); err != nil {
+			if err.Error() == "invitation not found" {
+				return nil, errors.New("invalid verification token")
+			}
+			return nil, err
+		}
+		return p, nil
+	}
+
+	p.ProveEmail()
+	if err := a.playerRepo.Update(p); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// RequiresVerification reports whether new accounts must confirm their email
+func (a *AuthService) RequiresVerification() bool {
+	return a.policy.RequireVerification
+}
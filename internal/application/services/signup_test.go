@@ -0,0 +1,87 @@
+package services
+
+import "testing"
+
+func TestSignupPolicy_AllowsEmail(t *testing.T) {
+	policy := SignupPolicy{AllowedDomains: []string{"ut.ac.ir", "Uni.EDU."}}
+
+	tests := []struct {
+		name     string
+		email    string
+		expected bool
+	}{
+		{"listed domain", "rostam@ut.ac.ir", true},
+		{"subdomain", "rostam@ece.ut.ac.ir", true},
+		{"nested subdomain", "rostam@mail.ece.ut.ac.ir", true},
+		{"case is ignored", "Rostam@UT.AC.IR", true},
+		{"listed with a trailing dot", "sohrab@uni.edu", true},
+		{"email with a trailing dot", "sohrab@ut.ac.ir.", true},
+		{"other domain", "rostam@gmail.com", false},
+		{"same ending without a dot", "rostam@evilut.ac.ir", false},
+		{"listed domain as a prefix", "rostam@ut.ac.ir.evil.com", false},
+		{"parent of a listed domain", "rostam@ac.ir", false},
+		{"no at sign", "rostam.ut.ac.ir", false},
+		{"listed domain in the local part", "ut.ac.ir@gmail.com", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := policy.AllowsEmail(tt.email); got != tt.expected {
+				t.Errorf("Expected AllowsEmail(%q) to be %v, got %v", tt.email, tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestSignupPolicy_AllowsEmailWithoutList(t *testing.T) {
+	if !(SignupPolicy{}).AllowsEmail("rostam@gmail.com") {
+		t.Errorf("Expected every domain to be allowed without a list")
+	}
+	if (SignupPolicy{AllowedDomains: []string{""}}).AllowsEmail("rostam@gmail.com") {
+		t.Errorf("Expected an empty entry not to allow every domain")
+	}
+}
+
+func TestSignupPolicy_AllowsName(t *testing.T) {
+	policy := SignupPolicy{BlockedNameWords: []string{"admin", "Staff"}}
+
+	tests := []struct {
+		name     string
+		input    string
+		expected bool
+	}{
+		{"plain name", "Rostam Dastan", true},
+		{"blocked word as a name", "admin", false},
+		{"case is folded", "ADMIN", false},
+		{"blocked word list is case-insensitive", "staff", false},
+		{"leetspeak digits", "4dm1n", false},
+		{"leetspeak symbols", "St@ff", false},
+		{"blocked word as a prefix", "Admins", false},
+		{"blocked word as a suffix", "superadmin", false},
+		{"blocked word inside another word", "badminton", true},
+		{"blocked word across words", "Ahmad Minaei", true},
+		{"blocked word split by a space", "Ad Min", true},
+		{"punctuation separates words", "x_admin_x", false},
+		{"digits separate words after folding", "rostam2admin", false},
+		{"non-Latin letters", "رستم", true},
+		{"invisible character", "Rost\u200bam", false},
+		{"control character", "Rostam\x07", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := policy.AllowsName(tt.input); got != tt.expected {
+				t.Errorf("Expected AllowsName(%q) to be %v, got %v", tt.input, tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestSignupPolicy_AllowsNameWithoutBlockedWords(t *testing.T) {
+	if !(SignupPolicy{}).AllowsName("admin") {
+		t.Errorf("Expected every printable name to pass without blocked words")
+	}
+	if !(SignupPolicy{BlockedNameWords: []string{""}}).AllowsName("Rostam") {
+		t.Errorf("Expected an empty blocked word to be ignored")
+	}
+}
@@ -0,0 +1,106 @@
+package services
+
+import (
+	"errors"
+	"strings"
+
+	"haoma/internal/domain/player"
+)
+
+// ExternalIdentity is a user vouched for by the university identity provider
+type ExternalIdentity struct {
+	Issuer        string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// LoginWithIdentity finds the player behind a single sign-on identity. An
+// account with the same verified email is linked on first use; otherwise a
+// new account is created. Either way the email counts as proven. Linking an
+// account whose owner never proved they read the address logs out every
+// device and resets its password and two-factor setup. Being verified is not
+// enough: accounts are verified on signup whenever verification is not
+// required, so someone could have signed up with another person's address.
+func (a *AuthService) LoginWithIdentity(identity ExternalIdentity) (*player.Player, error) {
+	identity.Email = NormalizeEmail(identity.Email)
+	if linked, err := a.playerRepo.FindByOIDCIdentity(identity.Issuer, identity.Subject); err == nil {
+		return linked, nil
+	}
+
+	// Linking by email is only safe when the provider checked the address
+	if identity.Email == "" || !identity.EmailVerified {
+		return nil, errors.New("identity email not verified")
+	}
+
+	if existing, err := a.playerRepo.FindByEmail(identity.Email); err == nil {
+		if existing.IsLinkedTo(identity.Issuer) {
+			return nil, errors.New("account linked to another identity")
+		}
+
+		// Until now nobody proved they own the address, so whatever
+		// credentials were set up meanwhile may be someone else's
+		unproven := !existing.HasProvenEmail()
+		if unproven {
+			if err := a.resetUnprovenCredentials(existing); err != nil {
+				return nil, err
+			}
+		}
+
+		existing.LinkIdentity(identity.Issuer, identity.Subject)
+		existing.ProveEmail()
+		existing.AcceptInvitation()
+		if err := a.playerRepo.Update(existing); err != nil {
+			return nil, err
+		}
+
+		if unproven {
+			if err := a.RevokeAllPlayerTokens(existing.ID); err != nil {
+				return nil, err
+			}
+		}
+		return existing, nil
+	}
+
+	if a.policy.RosterOnly {
+		return nil, errors.New("email not on roster")
+	}
+	if !a.policy.AllowsEmail(identity.Email) {
+		return nil, errors.New("email domain not allowed")
+	}
+
+	// SSO players never see a password; they can set one with a reset
+	newPlayer, err := player.NewPlayerWithoutPassword(a.passwords, identityName(identity), identity.Email)
+	if err != nil {
+		return nil, err
+	}
+	newPlayer.LinkIdentity(identity.Issuer, identity.Subject)
+	newPlayer.ProveEmail()
+
+	if err := a.playerRepo.Save(newPlayer); err != nil {
+		return nil, err
+	}
+
+	return newPlayer, nil
+}
+
+// resetUnprovenCredentials replaces the password with a random one and drops
+// two-factor authentication, for an account whose email was never proven
+// before single sign-on vouched for it. The owner can set a password with a
+// reset.
+func (a *AuthService) resetUnprovenCredentials(p *player.Player) error {
+	if err := p.ScramblePassword(a.passwords); err != nil {
+		return err
+	}
+
+	p.DisableTOTP()
+	return a.tokenRepo.ReplaceRecoveryCodes(p.ID, nil)
+}
+
+func identityName(identity ExternalIdentity) string {
+	if name := strings.TrimSpace(identity.Name); name != "" {
+		return name
+	}
+	return strings.Split(identity.Email, "@")[0]
+}
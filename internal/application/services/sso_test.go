@@ -0,0 +1,174 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"haoma/internal/domain/player"
+	"haoma/internal/domain/token"
+)
+
+// Small parameters keep the tests fast
+var testPasswords = player.NewArgon2idHasher(player.Argon2idParams{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32})
+
+// memoryPlayers keeps copies, so a test only sees what a service saved
+type memoryPlayers struct {
+	PlayerRepository
+	players map[uuid.UUID]player.Player
+}
+
+func (r *memoryPlayers) Save(p *player.Player) error {
+	r.players[p.ID] = *p
+	return nil
+}
+
+func (r *memoryPlayers) Update(p *player.Player) error {
+	r.players[p.ID] = *p
+	return nil
+}
+
+func (r *memoryPlayers) FindByID(id uuid.UUID) (*player.Player, error) {
+	if p, exists := r.players[id]; exists {
+		return &p, nil
+	}
+	return nil, errors.New("player not found")
+}
+
+func (r *memoryPlayers) FindByEmail(email string) (*player.Player, error) {
+	for _, p := range r.players {
+		if strings.EqualFold(p.Email, email) {
+			return &p, nil
+		}
+	}
+	return nil, errors.New("player not found")
+}
+
+func (r *memoryPlayers) FindByOIDCIdentity(issuer, subject string) (*player.Player, error) {
+	for _, p := range r.players {
+		if p.OIDCIssuer != nil && *p.OIDCIssuer == issuer && p.OIDCSubject != nil && *p.OIDCSubject == subject {
+			return &p, nil
+		}
+	}
+	return nil, errors.New("player not found")
+}
+
+func (r *memoryPlayers) FindByStudentID(studentID string) (*player.Player, error) {
+	for _, p := range r.players {
+		if p.StudentID != nil && *p.StudentID == studentID {
+			return &p, nil
+		}
+	}
+	return nil, errors.New("player not found")
+}
+
+type memoryTokens struct {
+	TokenRepository
+	refreshRevoked map[uuid.UUID]bool
+}
+
+func (r *memoryTokens) RevokePlayerRefreshTokens(playerID uuid.UUID) error {
+	r.refreshRevoked[playerID] = true
+	return nil
+}
+
+func (r *memoryTokens) ReplaceRecoveryCodes(playerID uuid.UUID, codes []*token.RecoveryCode) error {
+	return nil
+}
+
+type memoryRevocations struct {
+	validAfter map[uuid.UUID]time.Time
+}
+
+func (r *memoryRevocations) RevokeToken(revoked *token.RevokedToken) error {
+	return nil
+}
+
+func (r *memoryRevocations) RevokeTokensIssuedBefore(playerID uuid.UUID, validAfter time.Time) error {
+	r.validAfter[playerID] = validAfter
+	return nil
+}
+
+func newTestAuthService(policy SignupPolicy) (*AuthService, *memoryPlayers, *memoryTokens, *memoryRevocations) {
+	players := &memoryPlayers{players: make(map[uuid.UUID]player.Player)}
+	tokens := &memoryTokens{refreshRevoked: make(map[uuid.UUID]bool)}
+	revocations := &memoryRevocations{validAfter: make(map[uuid.UUID]time.Time)}
+	return NewAuthService(players, testPasswords, tokens, revocations, nil, policy), players, tokens, revocations
+}
+
+func TestLoginWithIdentity_PreRegisteredAccount(t *testing.T) {
+	authService, players, tokens, revocations := newTestAuthService(SignupPolicy{})
+
+	// Someone signs up with the student's address before the student does.
+	// Verification is not required, so the account counts as verified.
+	squatter, err := authService.Register("Zahhak", "rudabeh@uni.edu", "serpent_shoulders", "")
+	if err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	if !squatter.IsVerified() || squatter.HasProvenEmail() {
+		t.Fatalf("Expected a verified but unproven account")
+	}
+	squatter.BeginTOTPEnrollment("JBSWY3DPEHPK3PXP")
+	squatter.EnableTOTP()
+	players.Update(squatter)
+
+	linked, err := authService.LoginWithIdentity(ExternalIdentity{
+		Issuer:        "https://sso.uni.edu",
+		Subject:       "rudabeh",
+		Email:         "rudabeh@uni.edu",
+		EmailVerified: true,
+		Name:          "Rudabeh",
+	})
+	if err != nil {
+		t.Fatalf("LoginWithIdentity failed: %v", err)
+	}
+
+	stored, _ := players.FindByID(linked.ID)
+	if stored.ValidatePassword(testPasswords, "serpent_shoulders") {
+		t.Errorf("Expected the squatter's password to stop working")
+	}
+	if stored.HasTOTP() {
+		t.Errorf("Expected the squatter's two-factor setup to be removed")
+	}
+	if !stored.HasProvenEmail() || !stored.IsLinkedTo("https://sso.uni.edu") {
+		t.Errorf("Expected the account to be linked with a proven email")
+	}
+	if !tokens.refreshRevoked[linked.ID] {
+		t.Errorf("Expected the squatter's refresh tokens to be revoked")
+	}
+	if _, revoked := revocations.validAfter[linked.ID]; !revoked {
+		t.Errorf("Expected the squatter's access tokens to be revoked")
+	}
+}
+
+func TestLoginWithIdentity_ProvenAccount(t *testing.T) {
+	authService, players, tokens, _ := newTestAuthService(SignupPolicy{})
+
+	owner, err := player.NewPlayer(testPasswords, "Rudabeh", "rudabeh@uni.edu", "kabul_princess")
+	if err != nil {
+		t.Fatalf("NewPlayer failed: %v", err)
+	}
+	owner.ProveEmail()
+	players.Save(owner)
+
+	linked, err := authService.LoginWithIdentity(ExternalIdentity{
+		Issuer:        "https://sso.uni.edu",
+		Subject:       "rudabeh",
+		Email:         "rudabeh@uni.edu",
+		EmailVerified: true,
+	})
+	if err != nil {
+		t.Fatalf("LoginWithIdentity failed: %v", err)
+	}
+
+	stored, _ := players.FindByID(linked.ID)
+	if !stored.ValidatePassword(testPasswords, "kabul_princess") {
+		t.Errorf("Expected the owner's password to keep working")
+	}
+	if tokens.refreshRevoked[linked.ID] {
+		t.Errorf("Expected the owner's sessions to survive")
+	}
+}
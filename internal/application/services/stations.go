@@ -0,0 +1,58 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"haoma/internal/config"
+	"haoma/internal/domain/nodecode"
+)
+
+// Stations builds the printed sign for every active node from the registry
+// and the same codes the server accepts, so printed material cannot drift
+// from the server. With forCurrentEvent, signed codes are tied to the
+// current event and only work during it.
+func (c *CarnivalService) Stations(forCurrentEvent bool) ([]nodecode.Station, error) {
+	theme := config.CARNIVAL_NAME
+	var payload nodecode.Payload
+	if forCurrentEvent {
+		current, err := c.eventRepo.FindCurrent()
+		if err != nil {
+			return nil, errors.New("no current event")
+		}
+		theme = current.Title
+		if c.nodeCodes != nil {
+			payload.EventID = &current.ID
+			payload.NotBefore, payload.NotAfter = current.StartsAt, current.EndsAt
+		}
+	}
+
+	nodes, err := c.activeNodes()
+	if err != nil {
+		return nil, err
+	}
+
+	stations := make([]nodecode.Station, 0, len(nodes))
+	for _, n := range nodes {
+		station := nodecode.Station{
+			Node:  n.Number,
+			Title: fmt.Sprintf("Node %d", n.Number),
+			Name:  n.Name,
+			Theme: theme,
+		}
+
+		switch {
+		case c.rotates(n.Number):
+			// Printed codes are refused; the sign points at the screen
+		case c.nodeCodes != nil:
+			payload.Node = n.Number
+			station.Code = c.nodeCodes.Sign(payload)
+		default:
+			station.Code = n.Code
+		}
+
+		stations = append(stations, station)
+	}
+
+	return stations, nil
+}
@@ -8,24 +8,26 @@ import "time"
 
 const (
 	// Core game flow
-	MAX_CARNIVAL_NODES              = 7 // Total number of carnival nodes/categories
-	QUESTIONS_PER_NODE              = 5 // Total questions per carnival node
-	CATEGORY_QUESTIONS_PER_NODE     = 4 // Category-specific questions per node
-	FUN_QUESTIONS_PER_NODE          = 1 // Fun questions per node
-	MIN_REQUIRED_GENERAL_CATEGORIES = 7 // Minimum general categories needed
+	QUESTIONS_PER_NODE          = 5 // Total questions per carnival node
+	CATEGORY_QUESTIONS_PER_NODE = 4 // Category-specific questions per node
+	FUN_QUESTIONS_PER_NODE      = 1 // Fun questions per node
 
 	// Scoring system
 	CORRECT_ANSWER_MULTIPLIER     = 100 // Points per correct answer
 	PENALTY_MULTIPLIER            = 10  // Points per penalty point
 	TIME_PENALTY_INTERVAL_SECONDS = 20  // Seconds per penalty point
 
-	// Node validation
-	MIN_NODE_NUMBER = 1 // Minimum valid node number
-	MAX_NODE_NUMBER = 7 // Maximum valid node number
+	// Node registry; which nodes exist is up to the organizers
+	MIN_NODE_NUMBER    = 1  // Lowest node number the registry accepts
+	MAX_NODE_NUMBER    = 99 // Highest node number the registry accepts
+	DEFAULT_NODE_COUNT = 7  // Nodes registered when the registry is first created
+
+	// Geofences around nodes
+	GEOFENCE_MAX_RADIUS_METERS   = 5000.0 // Largest geofence an organizer can set
+	GEOFENCE_MAX_ACCURACY_METERS = 150.0  // Reported locations vaguer than this prove nothing either way
 
 	// Question requirements
 	QUESTIONS_TO_COMPLETE_NODE = 5 // Questions needed to complete a node
-	NODES_TO_COMPLETE_SESSION  = 7 // Nodes needed to complete session
 
 	// Database limits
 	LEADERBOARD_TOP_ENTRIES   = 10 // Number of top entries in leaderboard
@@ -43,23 +45,65 @@ const (
 	SESSION_EXPIRY_SECONDS     = 7200                                   // Session expiry in seconds (2 hours)
 
 	// Authentication
-	JWT_EXPIRY_SECONDS = 86400 // JWT token expiry (24 hours)
+	JWT_EXPIRY_SECONDS        = 900                              // Access token expiry (15 minutes)
+	JWT_EXPIRY                = JWT_EXPIRY_SECONDS * time.Second // Access token expiry
+	REFRESH_TOKEN_EXPIRY      = 7 * 24 * time.Hour               // Refresh token expiry (7 days)
+	PASSWORD_RESET_EXPIRY     = 30 * time.Minute                 // How long an emailed reset link works
+	EMAIL_VERIFICATION_EXPIRY = 24 * time.Hour                   // How long an emailed verification link works
+	MIN_PASSWORD_LENGTH       = 6                                // Shortest password accepted, as request bindings enforce
+
+	// Two-factor authentication
+	MFA_CHALLENGE_EXPIRY = 5 * time.Minute  // How long after the password the second factor may be entered
+	RECOVERY_CODE_COUNT  = 10               // Recovery codes handed out per set
+	TOTP_ISSUER          = "Haoma Carnival" // Account label shown in authenticator apps
+
+	// Password hashing (argon2id); PASSWORD_ARGON2_* in the environment override these
+	PASSWORD_ARGON2_MEMORY_KIB  = 19 * 1024 // Memory per hash in KiB
+	PASSWORD_ARGON2_ITERATIONS  = 2         // Passes over the memory
+	PASSWORD_ARGON2_PARALLELISM = 1         // Threads per hash
+
+	// Login throttling, tracked separately per account and per client IP
+	LOGIN_FREE_ATTEMPTS        = 3                // Failed logins per account before backoff starts
+	LOGIN_LOCKOUT_THRESHOLD    = 10               // Failed logins per account before a lockout
+	LOGIN_IP_FREE_ATTEMPTS     = 20               // Failed logins per IP before backoff starts (labs share IPs)
+	LOGIN_IP_LOCKOUT_THRESHOLD = 100              // Failed logins per IP before a lockout
+	LOGIN_BACKOFF_BASE         = time.Second      // First backoff delay, doubled on every further failure
+	LOGIN_BACKOFF_MAX          = 5 * time.Minute  // Longest backoff delay
+	LOGIN_LOCKOUT_DURATION     = 15 * time.Minute // How long a lockout lasts
+	LOGIN_FAILURE_WINDOW       = time.Hour        // Failures older than this are forgotten
+
+	// Caching
+	LEADERBOARD_CONSISTENCY_CHECK_INTERVAL = 5 * time.Minute  // How often the leaderboard cache is checked against the database
+	EVENT_CACHE_TTL                        = 10 * time.Second // How long the current event is remembered
+	REVOCATION_REFRESH_INTERVAL            = 30 * time.Second // How often the token revocation list is reloaded
+
+	// Rotating node codes on station screens
+	NODE_CODE_ROTATION_PERIOD = 30 * time.Second // How often a station screen shows a new code
+	NODE_CODE_ROTATION_SKEW   = 1                // Earlier or later codes still accepted, for slow scans and clock drift
+
+	// Personal data exports
+	DATA_EXPORT_CONCURRENCY  = 2                // Exports built at the same time
+	DATA_EXPORT_RETENTION    = 10 * time.Minute // How long a finished export can be downloaded
+	DATA_EXPORT_POLL_SECONDS = 2                // Retry-After while an export is being built
+
+	// Live updates
+	LEADERBOARD_STREAM_INTERVAL  = 2 * time.Second  // How often the live feed checks for leaderboard changes
+	LEADERBOARD_STREAM_KEEPALIVE = 15 * time.Second // Idle time before the live feed sends a keepalive
 )
 
 // ================================
-// NODE CODE MAPPINGS
+// NAME MODERATION
 // ================================
 
-// NodeCodes maps QR code content to node numbers
-var NodeCodes = map[string]int{
-	// Generic format
-	"NODE_001": 1,
-	"NODE_002": 2,
-	"NODE_003": 3,
-	"NODE_004": 4,
-	"NODE_005": 5,
-	"NODE_006": 6,
-	"NODE_007": 7,
+// BlockedNameWords are refused at the start or end of any word in a display
+// name, since names show on the public leaderboard. Matching ignores case and
+// look-alike digits, so short words that begin or end real names are left
+// out. BLOCKED_NAME_WORDS adds more.
+var BlockedNameWords = []string{
+	// Impersonating the organizers
+	"admin", "moderator", "organizer", "organiser",
+	// Abuse
+	"fuck", "bitch", "whore", "faggot", "nigger", "hitler",
 }
 
 // ================================
@@ -67,7 +111,8 @@ var NodeCodes = map[string]int{
 // ================================
 
 const (
-	FUN_CATEGORY_NAME = "Fun" // Name of the fun category
+	FUN_CATEGORY_NAME = "Fun"            // Name of the fun category
+	CARNIVAL_NAME     = "Haoma Carnival" // Shown on printed station signs not tied to an event
 )
 
 // ================================
@@ -0,0 +1,76 @@
+package audit
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Reasons a login attempt was refused
+const (
+	ReasonUnknownAccount    = "unknown_account"
+	ReasonWrongPassword     = "wrong_password"
+	ReasonWrongSecondFactor = "wrong_second_factor"
+	ReasonThrottled         = "throttled"
+)
+
+// LoginFailure records a refused login so organizers can spot password
+// guessing after the fact
+type LoginFailure struct {
+	ID          uuid.UUID  `json:"id" gorm:"type:uuid;primary_key"`
+	Email       string     `json:"email" gorm:"not null;index"`
+	PlayerID    *uuid.UUID `json:"player_id,omitempty" gorm:"type:uuid;index"` // Nil when no account has that email
+	IPAddress   string     `json:"ip_address" gorm:"not null;index"`
+	UserAgent   string     `json:"user_agent"`
+	Reason      string     `json:"reason" gorm:"not null"`
+	AttemptedAt time.Time  `json:"attempted_at" gorm:"not null;index"`
+}
+
+func NewLoginFailure(email string, playerID *uuid.UUID, ipAddress, userAgent, reason string) *LoginFailure {
+	return &LoginFailure{
+		ID:          uuid.New(),
+		Email:       email,
+		PlayerID:    playerID,
+		IPAddress:   ipAddress,
+		UserAgent:   userAgent,
+		Reason:      reason,
+		AttemptedAt: time.Now(),
+	}
+}
+
+// ScanFlag records a node scan whose reported location did not clearly place
+// the player at the node, for organizers to review for cheating
+type ScanFlag struct {
+	ID         uuid.UUID  `json:"id" gorm:"type:uuid;primary_key"`
+	PlayerID   uuid.UUID  `json:"player_id" gorm:"type:uuid;not null;index"`
+	SessionID  *uuid.UUID `json:"session_id,omitempty" gorm:"type:uuid"` // Nil when a refused scan would have started one
+	NodeNumber int        `json:"node_number" gorm:"not null"`
+	Reason     string     `json:"reason" gorm:"not null"` // One of the node.Geofence* problems
+	Latitude   *float64   `json:"latitude,omitempty"`
+	Longitude  *float64   `json:"longitude,omitempty"`
+	Accuracy   *float64   `json:"accuracy_meters,omitempty"`
+	Distance   *float64   `json:"distance_meters,omitempty"`
+	Rejected   bool       `json:"rejected"` // The scan was refused rather than let through
+	ScannedAt  time.Time  `json:"scanned_at" gorm:"not null;index"`
+	ReviewedAt *time.Time `json:"reviewed_at,omitempty"`
+	ReviewedBy *uuid.UUID `json:"reviewed_by,omitempty" gorm:"type:uuid"`
+}
+
+func NewScanFlag(playerID uuid.UUID, sessionID *uuid.UUID, nodeNumber int, reason string, rejected bool) *ScanFlag {
+	return &ScanFlag{
+		ID:         uuid.New(),
+		PlayerID:   playerID,
+		SessionID:  sessionID,
+		NodeNumber: nodeNumber,
+		Reason:     reason,
+		Rejected:   rejected,
+		ScannedAt:  time.Now(),
+	}
+}
+
+// Review marks the flag as looked at by an organizer
+func (flag *ScanFlag) Review(reviewerID uuid.UUID) {
+	now := time.Now()
+	flag.ReviewedAt = &now
+	flag.ReviewedBy = &reviewerID
+}
@@ -0,0 +1,62 @@
+package event
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"haoma/internal/domain/leaderboard"
+)
+
+// Event represents one night of the carnival with its own clock
+type Event struct {
+	ID            uuid.UUID                  `json:"id" gorm:"type:uuid;primary_key"`
+	Title         string                     `json:"title" gorm:"not null"`
+	StartsAt      time.Time                  `json:"starts_at" gorm:"not null"`
+	EndsAt        time.Time                  `json:"ends_at" gorm:"not null"`
+	FreezeMinutes int                        `json:"freeze_minutes" gorm:"default:0"` // 0 disables the freeze
+	UnfrozenAt    *time.Time                 `json:"unfrozen_at,omitempty"`
+	TieBreakers   leaderboard.TieBreakerList `json:"tie_breakers" gorm:"type:json"`
+	CreatedAt     time.Time                  `json:"created_at"`
+	UpdatedAt     time.Time                  `json:"updated_at"`
+}
+
+func NewEvent(title string, startsAt, endsAt time.Time, freezeMinutes int, tieBreakers []leaderboard.TieBreaker) *Event {
+	return &Event{
+		ID:            uuid.New(),
+		Title:         title,
+		StartsAt:      startsAt,
+		EndsAt:        endsAt,
+		FreezeMinutes: freezeMinutes,
+		TieBreakers:   tieBreakers,
+		CreatedAt:     time.Now(),
+		UpdatedAt:     time.Now(),
+	}
+}
+
+// FreezesAt returns the moment the public leaderboard stops moving
+func (event *Event) FreezesAt() time.Time {
+	return event.EndsAt.Add(-time.Duration(event.FreezeMinutes) * time.Minute)
+}
+
+// IsFrozen reports whether players should only see the frozen snapshot
+func (event *Event) IsFrozen(now time.Time) bool {
+	if event.FreezeMinutes <= 0 || event.UnfrozenAt != nil {
+		return false
+	}
+	return !now.Before(event.FreezesAt())
+}
+
+func (event *Event) Unfreeze() {
+	now := time.Now()
+	event.UnfrozenAt = &now
+	event.UpdatedAt = now
+}
+
+// Ranking returns the tie-break chain applied to this event's leaderboard
+func (event *Event) Ranking() []leaderboard.TieBreaker {
+	if len(event.TieBreakers) == 0 {
+		return leaderboard.DefaultTieBreakers
+	}
+	return event.TieBreakers
+}
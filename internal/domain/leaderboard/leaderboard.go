@@ -1,6 +1,13 @@
 package leaderboard
 
 import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -11,12 +18,117 @@ type Entry struct {
 	ID             uuid.UUID     `json:"id" gorm:"type:uuid;primary_key"`
 	PlayerID       uuid.UUID     `json:"player_id" gorm:"type:uuid;not null"`
 	PlayerName     string        `json:"player_name" gorm:"not null"`
+	Guest          bool          `json:"guest" gorm:"default:false"` // Played without an account
 	SessionID      uuid.UUID     `json:"session_id" gorm:"type:uuid;not null"`
 	FinalScore     int           `json:"final_score" gorm:"not null"`
 	CompletionTime time.Duration `json:"completion_time" gorm:"type:bigint"` // For tie-breaking
+	TimePenalty    int           `json:"time_penalty" gorm:"default:0"`
+	NodesCompleted int           `json:"nodes_completed" gorm:"default:0"`
+	PhDTAccuracy   float64       `json:"phdt_accuracy" gorm:"default:0"` // Fraction of PhDT answers that were correct
 	AchievedAt     time.Time     `json:"achieved_at"`
 }
 
+// SameStanding reports whether two versions of an entry rank identically
+func (entry Entry) SameStanding(other Entry) bool {
+	return entry.FinalScore == other.FinalScore &&
+		entry.CompletionTime == other.CompletionTime &&
+		entry.TimePenalty == other.TimePenalty &&
+		entry.NodesCompleted == other.NodesCompleted &&
+		entry.PhDTAccuracy == other.PhDTAccuracy
+}
+
+// TieBreaker names one rule for ordering entries with equal final scores
+type TieBreaker string
+
+const (
+	TieBreakCompletionTime TieBreaker = "completion_time" // Faster journey first
+	TieBreakTimePenalty    TieBreaker = "time_penalty"    // Fewer penalty points first
+	TieBreakNodesCompleted TieBreaker = "nodes_completed" // More nodes completed first
+	TieBreakPhDTAccuracy   TieBreaker = "phdt_accuracy"   // Better phishing detection first
+	TieBreakAchievedAt     TieBreaker = "achieved_at"     // Earliest achievement first
+)
+
+// DefaultTieBreakers is the chain used when an event configures none
+var DefaultTieBreakers = []TieBreaker{TieBreakCompletionTime}
+
+// orderClauses maps each tie-breaker to its SQL ordering
+var orderClauses = map[TieBreaker]string{
+	TieBreakCompletionTime: "completion_time ASC",
+	TieBreakTimePenalty:    "time_penalty ASC",
+	TieBreakNodesCompleted: "nodes_completed DESC",
+	TieBreakPhDTAccuracy:   "phdt_accuracy DESC",
+	TieBreakAchievedAt:     "achieved_at ASC",
+}
+
+// ParseTieBreakers validates a configured chain, rejecting unknown or repeated rules
+func ParseTieBreakers(names []string) ([]TieBreaker, error) {
+	chain := make([]TieBreaker, 0, len(names))
+	seen := make(map[TieBreaker]bool, len(names))
+
+	for _, name := range names {
+		tieBreaker := TieBreaker(strings.TrimSpace(name))
+		if _, known := orderClauses[tieBreaker]; !known {
+			return nil, fmt.Errorf("unknown tie-breaker %q", name)
+		}
+		if seen[tieBreaker] {
+			return nil, errors.New("duplicate tie-breaker " + string(tieBreaker))
+		}
+		seen[tieBreaker] = true
+		chain = append(chain, tieBreaker)
+	}
+
+	return chain, nil
+}
+
+// OrderClause renders the ranking as a SQL ORDER BY expression
+func OrderClause(chain []TieBreaker) string {
+	clauses := []string{"final_score DESC"}
+	for _, tieBreaker := range chain {
+		clauses = append(clauses, orderClauses[tieBreaker])
+	}
+	return strings.Join(clauses, ", ")
+}
+
+// TieBreakerList is a custom type for storing a tie-break chain as JSON in PostgreSQL
+type TieBreakerList []TieBreaker
+
+// Value implements driver.Valuer interface for database serialization
+func (list TieBreakerList) Value() (driver.Value, error) {
+	if list == nil {
+		return nil, nil
+	}
+	return json.Marshal([]TieBreaker(list))
+}
+
+// Scan implements sql.Scanner interface for database deserialization
+func (list *TieBreakerList) Scan(value interface{}) error {
+	if value == nil {
+		*list = nil
+		return nil
+	}
+
+	bytes, ok := value.([]byte)
+	if !ok {
+		return nil
+	}
+
+	return json.Unmarshal(bytes, (*[]TieBreaker)(list))
+}
+
+// Standing is an entry's place on the board; tied entries share a position
+type Standing struct {
+	Position int  `json:"position"`
+	Tied     bool `json:"tied"`
+}
+
+// Label renders the standing the way contests print it, e.g. "3" or "T-3"
+func (standing Standing) Label() string {
+	if standing.Tied {
+		return "T-" + strconv.Itoa(standing.Position)
+	}
+	return strconv.Itoa(standing.Position)
+}
+
 // Leaderboard maintains the eternal witness of glory
 type Leaderboard struct {
 	Entries []Entry `json:"entries"`
@@ -41,3 +153,202 @@ func NewEntry(playerID uuid.UUID, playerName string, sessionID uuid.UUID,
 		AchievedAt:     time.Now(),
 	}
 }
+
+// Compare orders a before b when it returns a negative number, applying the
+// final score first and then each tie-breaker in turn. Zero means a true tie.
+func Compare(a, b Entry, chain []TieBreaker) int {
+	if a.FinalScore != b.FinalScore {
+		return b.FinalScore - a.FinalScore
+	}
+
+	for _, tieBreaker := range chain {
+		if result := compareBy(tieBreaker, a, b); result != 0 {
+			return result
+		}
+	}
+
+	return 0
+}
+
+func compareBy(tieBreaker TieBreaker, a, b Entry) int {
+	switch tieBreaker {
+	case TieBreakCompletionTime:
+		return compareDurations(a.CompletionTime, b.CompletionTime)
+	case TieBreakTimePenalty:
+		return a.TimePenalty - b.TimePenalty
+	case TieBreakNodesCompleted:
+		return b.NodesCompleted - a.NodesCompleted
+	case TieBreakPhDTAccuracy:
+		if a.PhDTAccuracy == b.PhDTAccuracy {
+			return 0
+		}
+		if a.PhDTAccuracy > b.PhDTAccuracy {
+			return -1
+		}
+		return 1
+	case TieBreakAchievedAt:
+		return a.AchievedAt.Compare(b.AchievedAt)
+	default:
+		return 0
+	}
+}
+
+func compareDurations(a, b time.Duration) int {
+	if a < b {
+		return -1
+	}
+	if a > b {
+		return 1
+	}
+	return 0
+}
+
+// Less reports whether a ranks above b under the given tie-break chain
+func Less(a, b Entry, chain []TieBreaker) bool {
+	return Compare(a, b, chain) < 0
+}
+
+// Sort orders entries the same way the database ranks them
+func Sort(entries []Entry, chain []TieBreaker) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		return Less(entries[i], entries[j], chain)
+	})
+}
+
+// Standings assigns competition-style positions to already sorted entries,
+// so two entries tied for third are both "T-3" and the next one is 5th
+func Standings(entries []Entry, chain []TieBreaker) []Standing {
+	standings := make([]Standing, len(entries))
+
+	for i := range entries {
+		standings[i].Position = i + 1
+		if i > 0 && Compare(entries[i-1], entries[i], chain) == 0 {
+			standings[i].Position = standings[i-1].Position
+			standings[i].Tied = true
+			standings[i-1].Tied = true
+		}
+	}
+
+	return standings
+}
+
+// EntryList is a custom type for storing a ranking as JSON in PostgreSQL
+type EntryList []Entry
+
+// Value implements driver.Valuer interface for database serialization
+func (list EntryList) Value() (driver.Value, error) {
+	if list == nil {
+		return nil, nil
+	}
+	return json.Marshal([]Entry(list))
+}
+
+// Scan implements sql.Scanner interface for database deserialization
+func (list *EntryList) Scan(value interface{}) error {
+	if value == nil {
+		*list = nil
+		return nil
+	}
+
+	bytes, ok := value.([]byte)
+	if !ok {
+		return nil
+	}
+
+	return json.Unmarshal(bytes, (*[]Entry)(list))
+}
+
+// Snapshot preserves the ranking players see while the board is frozen
+type Snapshot struct {
+	ID        uuid.UUID `json:"id" gorm:"type:uuid;primary_key"`
+	EventID   uuid.UUID `json:"event_id" gorm:"type:uuid;uniqueIndex;not null"`
+	Entries   EntryList `json:"entries" gorm:"type:json"`
+	TakenAt   time.Time `json:"taken_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// RevealStep describes a single rank change uncovered during the reveal
+type RevealStep struct {
+	Entry    Entry `json:"entry"`
+	FromRank int   `json:"from_rank"` // 0 when the player was not on the frozen board
+	ToRank   int   `json:"to_rank"`
+}
+
+func NewSnapshot(eventID uuid.UUID, entries []Entry, chain []TieBreaker) *Snapshot {
+	frozen := make(EntryList, len(entries))
+	copy(frozen, entries)
+	Sort(frozen, chain)
+
+	return &Snapshot{
+		ID:        uuid.New(),
+		EventID:   eventID,
+		Entries:   frozen,
+		TakenAt:   time.Now(),
+		UpdatedAt: time.Now(),
+	}
+}
+
+// UpdatePlayer copies a player's new name and guest status onto their frozen
+// entries and reports whether any changed
+func (snapshot *Snapshot) UpdatePlayer(playerID uuid.UUID, playerName string, guest bool) bool {
+	changed := false
+	for i := range snapshot.Entries {
+		if snapshot.Entries[i].PlayerID == playerID {
+			snapshot.Entries[i].PlayerName = playerName
+			snapshot.Entries[i].Guest = guest
+			changed = true
+		}
+	}
+	return changed
+}
+
+// RevealNext applies the next hidden change from the live ranking, starting
+// from the bottom of the frozen board like a contest resolver. It returns
+// nil once the snapshot matches the live ranking.
+func (snapshot *Snapshot) RevealNext(live []Entry, chain []TieBreaker) *RevealStep {
+	liveBySession := make(map[uuid.UUID]Entry, len(live))
+	for _, entry := range live {
+		liveBySession[entry.SessionID] = entry
+	}
+
+	frozenSessions := make(map[uuid.UUID]bool, len(snapshot.Entries))
+	for _, entry := range snapshot.Entries {
+		frozenSessions[entry.SessionID] = true
+	}
+
+	// Players who first reached the board after the freeze enter from below
+	for _, entry := range live {
+		if !frozenSessions[entry.SessionID] {
+			snapshot.Entries = append(snapshot.Entries, entry)
+			return snapshot.settle(entry, 0, chain)
+		}
+	}
+
+	for i := len(snapshot.Entries) - 1; i >= 0; i-- {
+		frozen := snapshot.Entries[i]
+		current, exists := liveBySession[frozen.SessionID]
+		if !exists || current.SameStanding(frozen) {
+			continue
+		}
+
+		snapshot.Entries[i] = current
+		return snapshot.settle(current, i+1, chain)
+	}
+
+	return nil
+}
+
+func (snapshot *Snapshot) settle(entry Entry, fromRank int, chain []TieBreaker) *RevealStep {
+	Sort(snapshot.Entries, chain)
+	snapshot.UpdatedAt = time.Now()
+
+	toRank := 0
+	for i, ranked := range snapshot.Entries {
+		if ranked.SessionID == entry.SessionID {
+			toRank = i + 1
+			break
+		}
+	}
+
+	return &RevealStep{Entry: entry, FromRank: fromRank, ToRank: toRank}
+}
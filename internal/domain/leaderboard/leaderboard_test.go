@@ -0,0 +1,114 @@
+package leaderboard
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestSnapshot_RevealNext(t *testing.T) {
+	rostam := *NewEntry(uuid.New(), "Rostam", uuid.New(), 800, 30*time.Minute)
+	sohrab := *NewEntry(uuid.New(), "Sohrab", uuid.New(), 600, 25*time.Minute)
+	tahmineh := *NewEntry(uuid.New(), "Tahmineh", uuid.New(), 500, 20*time.Minute)
+
+	snapshot := NewSnapshot(uuid.New(), []Entry{rostam, sohrab}, DefaultTieBreakers)
+
+	improvedSohrab := sohrab
+	improvedSohrab.FinalScore = 900
+	live := []Entry{improvedSohrab, rostam, tahmineh}
+
+	// New arrivals are revealed first
+	step := snapshot.RevealNext(live, DefaultTieBreakers)
+	if step == nil || step.Entry.PlayerName != "Tahmineh" {
+		t.Fatalf("Expected Tahmineh to be revealed first, got %+v", step)
+	}
+	if step.FromRank != 0 || step.ToRank != 3 {
+		t.Errorf("Expected Tahmineh to move from 0 to 3, got %d to %d", step.FromRank, step.ToRank)
+	}
+
+	step = snapshot.RevealNext(live, DefaultTieBreakers)
+	if step == nil || step.Entry.PlayerName != "Sohrab" {
+		t.Fatalf("Expected Sohrab to be revealed second, got %+v", step)
+	}
+	if step.FromRank != 2 || step.ToRank != 1 {
+		t.Errorf("Expected Sohrab to move from 2 to 1, got %d to %d", step.FromRank, step.ToRank)
+	}
+
+	if step := snapshot.RevealNext(live, DefaultTieBreakers); step != nil {
+		t.Errorf("Expected nothing left to reveal, got %+v", step)
+	}
+}
+
+func TestSort(t *testing.T) {
+	fast := Entry{PlayerName: "fast", FinalScore: 500, CompletionTime: 10 * time.Minute}
+	slow := Entry{PlayerName: "slow", FinalScore: 500, CompletionTime: 20 * time.Minute}
+	best := Entry{PlayerName: "best", FinalScore: 700, CompletionTime: 40 * time.Minute}
+
+	entries := []Entry{slow, fast, best}
+	Sort(entries, DefaultTieBreakers)
+
+	expected := []string{"best", "fast", "slow"}
+	for i, name := range expected {
+		if entries[i].PlayerName != name {
+			t.Errorf("Expected %s at position %d, got %s", name, i, entries[i].PlayerName)
+		}
+	}
+}
+
+func TestSort_TieBreakerChain(t *testing.T) {
+	penalized := Entry{PlayerName: "penalized", FinalScore: 500, TimePenalty: 4, NodesCompleted: 7, CompletionTime: 10 * time.Minute}
+	thorough := Entry{PlayerName: "thorough", FinalScore: 500, TimePenalty: 2, NodesCompleted: 7, CompletionTime: 30 * time.Minute}
+	quick := Entry{PlayerName: "quick", FinalScore: 500, TimePenalty: 2, NodesCompleted: 6, CompletionTime: 20 * time.Minute}
+
+	chain := []TieBreaker{TieBreakTimePenalty, TieBreakNodesCompleted}
+	entries := []Entry{quick, penalized, thorough}
+	Sort(entries, chain)
+
+	expected := []string{"thorough", "quick", "penalized"}
+	for i, name := range expected {
+		if entries[i].PlayerName != name {
+			t.Errorf("Expected %s at position %d, got %s", name, i, entries[i].PlayerName)
+		}
+	}
+}
+
+func TestStandings(t *testing.T) {
+	entries := []Entry{
+		{FinalScore: 900, TimePenalty: 1},
+		{FinalScore: 700, TimePenalty: 2},
+		{FinalScore: 500, TimePenalty: 3},
+		{FinalScore: 500, TimePenalty: 3},
+		{FinalScore: 400, TimePenalty: 1},
+	}
+
+	standings := Standings(entries, []TieBreaker{TieBreakTimePenalty})
+
+	expected := []string{"1", "2", "T-3", "T-3", "5"}
+	for i, label := range expected {
+		if got := standings[i].Label(); got != label {
+			t.Errorf("Expected standing %s at position %d, got %s", label, i, got)
+		}
+	}
+}
+
+func TestParseTieBreakers(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   []string
+		wantErr bool
+	}{
+		{"empty chain", nil, false},
+		{"valid chain", []string{"time_penalty", "achieved_at"}, false},
+		{"unknown rule", []string{"shoe_size"}, true},
+		{"repeated rule", []string{"time_penalty", "time_penalty"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseTieBreakers(tt.input); (err != nil) != tt.wantErr {
+				t.Errorf("ParseTieBreakers(%v) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+		})
+	}
+}
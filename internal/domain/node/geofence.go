@@ -0,0 +1,80 @@
+package node
+
+import (
+	"math"
+
+	"haoma/internal/config"
+)
+
+const earthRadiusMeters = 6371000.0
+
+// Problems a geofence check can find with a scan
+const (
+	GeofenceNoLocation = "location_missing"    // The device reported no location
+	GeofenceInaccurate = "location_inaccurate" // Too vague to tell
+	GeofenceEdge       = "near_geofence_edge"  // Outside, but within the reported accuracy
+	GeofenceOutside    = "outside_geofence"    // Too far away even allowing for accuracy
+)
+
+// Location is where a player's device says it is
+type Location struct {
+	Latitude  float64
+	Longitude float64
+	Accuracy  float64 // Metres, as reported by the device; 0 if unknown
+}
+
+// GeofenceCheck is how a reported location compares to a node's geofence
+type GeofenceCheck struct {
+	Problem  string   // Empty when the player is clearly at the node
+	Distance *float64 // Metres from the node, when a location was reported
+}
+
+// NotAtNode reports whether the scan fails to place the player at the node:
+// they were clearly elsewhere, or sent no usable location. Only scans near
+// the edge get the benefit of the doubt.
+func (check GeofenceCheck) NotAtNode() bool {
+	return check.Problem != "" && check.Problem != GeofenceEdge
+}
+
+func (node *Node) HasGeofence() bool {
+	return node.Radius != nil && node.Latitude != nil && node.Longitude != nil
+}
+
+// CheckLocation compares a reported location with the node's geofence.
+// Devices often report their position loosely indoors, so a scan only
+// counts as outside when it is too far even at the edge of its accuracy.
+// An accuracy beyond GEOFENCE_MAX_ACCURACY_METERS proves nothing, so it
+// cannot stretch the edge either.
+func (node *Node) CheckLocation(location *Location) GeofenceCheck {
+	if !node.HasGeofence() {
+		return GeofenceCheck{}
+	}
+	if location == nil {
+		return GeofenceCheck{Problem: GeofenceNoLocation}
+	}
+
+	distance := DistanceMeters(*node.Latitude, *node.Longitude, location.Latitude, location.Longitude)
+	check := GeofenceCheck{Distance: &distance}
+	switch {
+	case distance <= *node.Radius:
+	case location.Accuracy > config.GEOFENCE_MAX_ACCURACY_METERS:
+		check.Problem = GeofenceInaccurate
+	case distance-location.Accuracy > *node.Radius:
+		check.Problem = GeofenceOutside
+	default:
+		check.Problem = GeofenceEdge
+	}
+	return check
+}
+
+// DistanceMeters is the great-circle distance between two points
+func DistanceMeters(lat1, lon1, lat2, lon2 float64) float64 {
+	toRadians := math.Pi / 180
+	dLat := (lat2 - lat1) * toRadians
+	dLon := (lon2 - lon1) * toRadians
+
+	// Haversine formula
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*toRadians)*math.Cos(lat2*toRadians)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusMeters * math.Asin(math.Sqrt(a))
+}
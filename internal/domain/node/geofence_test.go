@@ -0,0 +1,64 @@
+package node
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDistanceMeters(t *testing.T) {
+	// One thousandth of a degree of latitude is about 111 metres
+	got := DistanceMeters(35.700, 51.350, 35.701, 51.350)
+	if math.Abs(got-111.2) > 0.5 {
+		t.Errorf("Expected about 111.2 m, got %.1f", got)
+	}
+}
+
+func TestNode_CheckLocation(t *testing.T) {
+	lat, lon, radius := 35.700, 51.350, 50.0
+	n := NewNode(1, "", "")
+	n.Latitude, n.Longitude, n.Radius = &lat, &lon, &radius
+
+	tests := []struct {
+		name     string
+		location *Location
+		expected string
+	}{
+		{"at the node", &Location{Latitude: 35.700, Longitude: 51.350, Accuracy: 10}, ""},
+		{"no location", nil, GeofenceNoLocation},
+		{"edge within accuracy", &Location{Latitude: 35.7006, Longitude: 51.350, Accuracy: 30}, GeofenceEdge},
+		{"vague location", &Location{Latitude: 35.7006, Longitude: 51.350, Accuracy: 1000}, GeofenceInaccurate},
+		{"across campus", &Location{Latitude: 35.705, Longitude: 51.350, Accuracy: 20}, GeofenceOutside},
+		{"across campus claiming huge accuracy", &Location{Latitude: 35.705, Longitude: 51.350, Accuracy: 1e9}, GeofenceInaccurate},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := n.CheckLocation(tt.location).Problem; got != tt.expected {
+				t.Errorf("Expected problem %q, got %q", tt.expected, got)
+			}
+		})
+	}
+
+	if got := NewNode(2, "", "").CheckLocation(nil); got.Problem != "" {
+		t.Errorf("Expected no geofence to accept any scan, got %q", got.Problem)
+	}
+}
+
+func TestGeofenceCheck_NotAtNode(t *testing.T) {
+	tests := []struct {
+		problem  string
+		expected bool
+	}{
+		{"", false},
+		{GeofenceEdge, false},
+		{GeofenceNoLocation, true},
+		{GeofenceInaccurate, true},
+		{GeofenceOutside, true},
+	}
+
+	for _, tt := range tests {
+		if got := (GeofenceCheck{Problem: tt.problem}).NotAtNode(); got != tt.expected {
+			t.Errorf("Expected NotAtNode %v for %q, got %v", tt.expected, tt.problem, got)
+		}
+	}
+}
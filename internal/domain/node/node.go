@@ -0,0 +1,85 @@
+package node
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"haoma/internal/config"
+)
+
+// Node is a physical carnival station. Players scan its QR code to unlock
+// the questions of the category their session assigned to it.
+type Node struct {
+	ID         uuid.UUID  `json:"id" gorm:"type:uuid;primary_key"`
+	Number     int        `json:"number" gorm:"uniqueIndex;not null"`
+	Code       string     `json:"code" gorm:"uniqueIndex;not null"` // Static QR content, accepted while codes are unsigned
+	Name       string     `json:"name" gorm:"not null"`
+	Location   string     `json:"location" gorm:"type:text"` // Where organizers set it up, e.g. "Lab 204, second floor"
+	Latitude   *float64   `json:"latitude,omitempty"`
+	Longitude  *float64   `json:"longitude,omitempty"`
+	Radius     *float64   `json:"radius_meters,omitempty"`                // Geofence around the coordinates; nil accepts scans from anywhere
+	CategoryID *uuid.UUID `json:"category_id,omitempty" gorm:"type:uuid"` // Pinned category; nil draws one at random per session
+	Active     bool       `json:"active" gorm:"not null"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+func NewNode(number int, code, name string) *Node {
+	if code == "" {
+		code = DefaultCode(number)
+	}
+	if name == "" {
+		name = DefaultName(number)
+	}
+
+	return &Node{
+		ID:        uuid.New(),
+		Number:    number,
+		Code:      code,
+		Name:      name,
+		Active:    true,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+}
+
+// DefaultCode is the static code a node gets when none is chosen, e.g.
+// NODE_003
+func DefaultCode(number int) string {
+	return fmt.Sprintf("NODE_%03d", number)
+}
+
+// DefaultName is the display name a node gets when none is chosen
+func DefaultName(number int) string {
+	return fmt.Sprintf("Node %d", number)
+}
+
+// Validate checks the fields an organizer can edit. It fails with "invalid
+// node number", "invalid node code", "node name required", "invalid
+// coordinates" or "invalid geofence radius".
+func (node *Node) Validate() error {
+	if node.Number < config.MIN_NODE_NUMBER || node.Number > config.MAX_NODE_NUMBER {
+		return errors.New("invalid node number")
+	}
+	if node.Code == "" || strings.ContainsAny(node.Code, " \t\r\n") {
+		return errors.New("invalid node code")
+	}
+	if strings.TrimSpace(node.Name) == "" {
+		return errors.New("node name required")
+	}
+	// Both or neither, and on the globe
+	if (node.Latitude == nil) != (node.Longitude == nil) {
+		return errors.New("invalid coordinates")
+	}
+	if node.Latitude != nil && (*node.Latitude < -90 || *node.Latitude > 90 || *node.Longitude < -180 || *node.Longitude > 180) {
+		return errors.New("invalid coordinates")
+	}
+	if node.Radius != nil && (node.Latitude == nil || *node.Radius <= 0 || *node.Radius > config.GEOFENCE_MAX_RADIUS_METERS) {
+		return errors.New("invalid geofence radius")
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package node
+
+import "testing"
+
+func TestNode_Validate(t *testing.T) {
+	lat, lon, far := 35.70, 51.35, 200.0
+
+	tests := []struct {
+		name    string
+		mutate  func(n *Node)
+		wantErr bool
+	}{
+		{"defaults", func(n *Node) {}, false},
+		{"with coordinates", func(n *Node) { n.Latitude, n.Longitude = &lat, &lon }, false},
+		{"number zero", func(n *Node) { n.Number = 0 }, true},
+		{"code with space", func(n *Node) { n.Code = "NODE 1" }, true},
+		{"blank name", func(n *Node) { n.Name = "  " }, true},
+		{"latitude alone", func(n *Node) { n.Latitude = &lat }, true},
+		{"longitude out of range", func(n *Node) { n.Latitude, n.Longitude = &lat, &far }, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := NewNode(3, "", "")
+			tt.mutate(n)
+			if err := n.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestNewNode_Defaults(t *testing.T) {
+	n := NewNode(3, "", "")
+	if n.Code != "NODE_003" {
+		t.Errorf("Expected code NODE_003, got %s", n.Code)
+	}
+	if n.Name != "Node 3" {
+		t.Errorf("Expected name Node 3, got %s", n.Name)
+	}
+	if !n.Active {
+		t.Errorf("Expected a new node to be active")
+	}
+}
@@ -0,0 +1,206 @@
+// Package nodecode signs the payloads printed in node QR codes, so that a
+// code cannot be guessed or altered to unlock a different node
+package nodecode
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// A signed code reads HAOMA1.<node>.<event>.<not before>.<not after>.<mac>,
+// with 0 for an open field. A rotating code shown on a station screen reads
+// HAOMAR.<node>.<time step>.<mac>. The MAC is HMAC-SHA256 over everything
+// before it, truncated to 128 bits to keep the QR code small.
+const (
+	version         = "HAOMA1"
+	rotatingVersion = "HAOMAR"
+	macSize         = 16
+	MinKeyLength    = 32
+)
+
+// Payload is what a node QR code vouches for
+type Payload struct {
+	Node      int
+	EventID   *uuid.UUID // Only valid during this event, if set
+	NotBefore time.Time  // Zero for no start
+	NotAfter  time.Time  // Zero for no end
+}
+
+// Signer creates and checks signed node codes with a shared secret
+type Signer struct {
+	key []byte
+}
+
+func NewSigner(key []byte) (*Signer, error) {
+	if len(key) < MinKeyLength {
+		return nil, fmt.Errorf("node code key must be at least %d bytes", MinKeyLength)
+	}
+	return &Signer{key: key}, nil
+}
+
+// IsSigned reports whether a code looks like a signed one, as opposed to a
+// legacy static code such as NODE_001
+func IsSigned(code string) bool {
+	return strings.HasPrefix(code, version+".")
+}
+
+func (s *Signer) Sign(payload Payload) string {
+	event := "0"
+	if payload.EventID != nil {
+		event = strings.ReplaceAll(payload.EventID.String(), "-", "")
+	}
+
+	body := strings.Join([]string{
+		version,
+		strconv.Itoa(payload.Node),
+		event,
+		unixOrZero(payload.NotBefore),
+		unixOrZero(payload.NotAfter),
+	}, ".")
+	return body + "." + s.mac(body)
+}
+
+// Verify checks a code's signature and validity window. It fails with
+// "invalid node code", "node code not yet valid" or "node code expired".
+func (s *Signer) Verify(code string, at time.Time) (*Payload, error) {
+	code = strings.TrimSpace(code)
+	split := strings.LastIndex(code, ".")
+	if !IsSigned(code) || split < 0 {
+		return nil, errors.New("invalid node code")
+	}
+
+	body, mac := code[:split], code[split+1:]
+	if !hmac.Equal([]byte(mac), []byte(s.mac(body))) {
+		return nil, errors.New("invalid node code")
+	}
+
+	fields := strings.Split(body, ".")
+	if len(fields) != 5 {
+		return nil, errors.New("invalid node code")
+	}
+
+	node, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return nil, errors.New("invalid node code")
+	}
+	payload := &Payload{Node: node}
+
+	if fields[2] != "0" {
+		eventID, err := uuid.Parse(fields[2])
+		if err != nil {
+			return nil, errors.New("invalid node code")
+		}
+		payload.EventID = &eventID
+	}
+
+	if payload.NotBefore, err = parseUnix(fields[3]); err != nil {
+		return nil, errors.New("invalid node code")
+	}
+	if payload.NotAfter, err = parseUnix(fields[4]); err != nil {
+		return nil, errors.New("invalid node code")
+	}
+
+	if !payload.NotBefore.IsZero() && at.Before(payload.NotBefore) {
+		return nil, errors.New("node code not yet valid")
+	}
+	if !payload.NotAfter.IsZero() && at.After(payload.NotAfter) {
+		return nil, errors.New("node code expired")
+	}
+
+	return payload, nil
+}
+
+// IsRotating reports whether a code came from a station screen
+func IsRotating(code string) bool {
+	return strings.HasPrefix(strings.TrimSpace(code), rotatingVersion+".")
+}
+
+// Rotating returns a node's code for the time step a moment falls in. A new
+// code replaces it every period.
+func (s *Signer) Rotating(node int, at time.Time, period time.Duration) string {
+	body := fmt.Sprintf("%s.%d.%d", rotatingVersion, node, step(at, period))
+	return body + "." + s.mac(body)
+}
+
+// VerifyRotating checks a station screen code and returns its node. Codes
+// from up to skew steps before or after the current one are accepted, to
+// allow for slow scanning and clock drift. It fails with "invalid node
+// code" or "node code expired".
+func (s *Signer) VerifyRotating(code string, at time.Time, period time.Duration, skew int64) (int, error) {
+	code = strings.TrimSpace(code)
+	split := strings.LastIndex(code, ".")
+	if !IsRotating(code) || split < 0 {
+		return 0, errors.New("invalid node code")
+	}
+
+	body, mac := code[:split], code[split+1:]
+	if !hmac.Equal([]byte(mac), []byte(s.mac(body))) {
+		return 0, errors.New("invalid node code")
+	}
+
+	fields := strings.Split(body, ".")
+	if len(fields) != 3 {
+		return 0, errors.New("invalid node code")
+	}
+	node, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return 0, errors.New("invalid node code")
+	}
+	codeStep, err := strconv.ParseInt(fields[2], 10, 64)
+	if err != nil {
+		return 0, errors.New("invalid node code")
+	}
+
+	if current := step(at, period); codeStep < current-skew || codeStep > current+skew {
+		return 0, errors.New("node code expired")
+	}
+	return node, nil
+}
+
+// RotatesAt returns when the code shown at a moment is replaced
+func RotatesAt(at time.Time, period time.Duration) time.Time {
+	return time.Unix(0, (step(at, period)+1)*int64(period))
+}
+
+// StationKey returns the secret a station screen presents to fetch its
+// node's rotating code
+func (s *Signer) StationKey(node int) string {
+	return s.mac(fmt.Sprintf("station.%d", node))
+}
+
+func (s *Signer) ValidStationKey(node int, key string) bool {
+	return hmac.Equal([]byte(key), []byte(s.StationKey(node)))
+}
+
+func step(at time.Time, period time.Duration) int64 {
+	return at.UnixNano() / int64(period)
+}
+
+func (s *Signer) mac(body string) string {
+	sum := hmac.New(sha256.New, s.key)
+	sum.Write([]byte(body))
+	return base64.RawURLEncoding.EncodeToString(sum.Sum(nil)[:macSize])
+}
+
+func unixOrZero(t time.Time) string {
+	if t.IsZero() {
+		return "0"
+	}
+	return strconv.FormatInt(t.Unix(), 10)
+}
+
+func parseUnix(value string) (time.Time, error) {
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || seconds == 0 {
+		return time.Time{}, err
+	}
+	return time.Unix(seconds, 0), nil
+}
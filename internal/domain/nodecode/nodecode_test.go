@@ -0,0 +1,92 @@
+package nodecode
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestSigner_SignAndVerify(t *testing.T) {
+	signer, err := NewSigner([]byte(strings.Repeat("k", MinKeyLength)))
+	if err != nil {
+		t.Fatalf("NewSigner failed: %v", err)
+	}
+
+	eventID := uuid.New()
+	start := time.Unix(1700000000, 0)
+	code := signer.Sign(Payload{Node: 3, EventID: &eventID, NotBefore: start, NotAfter: start.Add(time.Hour)})
+
+	payload, err := signer.Verify(code, start.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("Expected a valid code, got %v", err)
+	}
+	if payload.Node != 3 || payload.EventID == nil || *payload.EventID != eventID {
+		t.Errorf("Expected node 3 for event %s, got %+v", eventID, payload)
+	}
+
+	tests := []struct {
+		name     string
+		code     string
+		at       time.Time
+		expected string
+	}{
+		{"tampered node", strings.Replace(code, "HAOMA1.3.", "HAOMA1.4.", 1), start, "invalid node code"},
+		{"legacy static code", "NODE_003", start, "invalid node code"},
+		{"before the window", code, start.Add(-time.Second), "node code not yet valid"},
+		{"after the window", code, start.Add(2 * time.Hour), "node code expired"},
+	}
+
+	for _, tt := range tests {
+		if _, err := signer.Verify(tt.code, tt.at); err == nil || err.Error() != tt.expected {
+			t.Errorf("%s: Expected %q, got %v", tt.name, tt.expected, err)
+		}
+	}
+}
+
+func TestSigner_OpenWindow(t *testing.T) {
+	signer, _ := NewSigner([]byte(strings.Repeat("k", MinKeyLength)))
+	code := signer.Sign(Payload{Node: 1})
+
+	payload, err := signer.Verify(code, time.Now())
+	if err != nil || payload.EventID != nil {
+		t.Errorf("Expected an open code for any event, got %+v, %v", payload, err)
+	}
+
+	other, _ := NewSigner([]byte(strings.Repeat("x", MinKeyLength)))
+	if _, err := other.Verify(code, time.Now()); err == nil {
+		t.Errorf("Expected a code signed with another key to be refused")
+	}
+}
+
+func TestSigner_Rotating(t *testing.T) {
+	signer, _ := NewSigner([]byte(strings.Repeat("k", MinKeyLength)))
+	period := 30 * time.Second
+	shownAt := time.Unix(1700000000, 0)
+	code := signer.Rotating(5, shownAt, period)
+
+	tests := []struct {
+		name     string
+		at       time.Time
+		expected string
+	}{
+		{"same step", shownAt, ""},
+		{"next step within skew", shownAt.Add(period), ""},
+		{"two steps later", shownAt.Add(2 * period), "node code expired"},
+	}
+
+	for _, tt := range tests {
+		node, err := signer.VerifyRotating(code, tt.at, period, 1)
+		switch {
+		case tt.expected == "" && (err != nil || node != 5):
+			t.Errorf("%s: Expected node 5, got %d, %v", tt.name, node, err)
+		case tt.expected != "" && (err == nil || err.Error() != tt.expected):
+			t.Errorf("%s: Expected %q, got %v", tt.name, tt.expected, err)
+		}
+	}
+
+	if _, err := signer.VerifyRotating(strings.Replace(code, "HAOMAR.5.", "HAOMAR.6.", 1), shownAt, period, 1); err == nil {
+		t.Errorf("Expected a tampered rotating code to be refused")
+	}
+}
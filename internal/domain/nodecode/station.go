@@ -0,0 +1,26 @@
+package nodecode
+
+// Station is what goes on the printed sign at a node
+type Station struct {
+	Node  int
+	Title string // e.g. "Node 3"
+	Name  string // The node's display name, shown under the title unless the same
+	Theme string // The event, shown under the name
+	Code  string // QR content; empty when the node shows its code on a station screen
+}
+
+// UsesScreen reports whether players scan a station screen rather than the
+// printed sign
+func (s Station) UsesScreen() bool {
+	return s.Code == ""
+}
+
+// FindStation picks one node's station from a list
+func FindStation(stations []Station, node int) (Station, bool) {
+	for _, station := range stations {
+		if station.Node == node {
+			return station, true
+		}
+	}
+	return Station{}, false
+}
@@ -0,0 +1,53 @@
+package otp
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+)
+
+// The SHA-1 test vectors from RFC 6238 appendix B, truncated to six digits
+func TestCode_RFC6238Vectors(t *testing.T) {
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+	tests := []struct {
+		unix     int64
+		expected string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+
+	for _, tt := range tests {
+		code, err := Code(secret, Step(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatalf("Code failed: %v", err)
+		}
+		if code != tt.expected {
+			t.Errorf("Expected %s at %d, got %s", tt.expected, tt.unix, code)
+		}
+	}
+}
+
+func TestValidate_SkewAndReplay(t *testing.T) {
+	secret, _ := GenerateSecret()
+	now := time.Unix(1700000000, 0)
+	previous, _ := Code(secret, Step(now)-1)
+	stale, _ := Code(secret, Step(now)-3)
+
+	step, ok := Validate(secret, previous, now, 0)
+	if !ok || step != Step(now)-1 {
+		t.Errorf("Expected the previous step's code to be accepted for clock drift")
+	}
+
+	if _, ok := Validate(secret, previous, now, step); ok {
+		t.Errorf("Expected a used code to be refused")
+	}
+
+	if _, ok := Validate(secret, stale, now, 0); ok {
+		t.Errorf("Expected a code from three steps ago to be refused")
+	}
+}
@@ -0,0 +1,60 @@
+package player
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Small parameters keep the tests fast; the format is what matters here
+var testParams = Argon2idParams{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+func TestArgon2idHasher_HashAndVerify(t *testing.T) {
+	hasher := NewArgon2idHasher(testParams)
+
+	encoded, err := hasher.Hash("cyber_guardian_2024")
+	if err != nil {
+		t.Fatalf("Hash failed: %v", err)
+	}
+	if !strings.HasPrefix(encoded, "$argon2id$v=19$m=64,t=1,p=1$") {
+		t.Errorf("Expected a self-describing argon2id hash, got %s", encoded)
+	}
+
+	if !hasher.Verify(encoded, "cyber_guardian_2024") {
+		t.Errorf("Expected the right password to verify")
+	}
+	if hasher.Verify(encoded, "cyber_guardian_2025") {
+		t.Errorf("Expected a wrong password to be rejected")
+	}
+	if hasher.NeedsRehash(encoded) {
+		t.Errorf("Expected a fresh hash not to need rehashing")
+	}
+}
+
+func TestArgon2idHasher_NeedsRehash(t *testing.T) {
+	hasher := NewArgon2idHasher(testParams)
+
+	legacy, _ := bcrypt.GenerateFromPassword([]byte("simorgh"), bcrypt.MinCost)
+	weaker, _ := NewArgon2idHasher(Argon2idParams{Memory: 32, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}).Hash("simorgh")
+
+	tests := []struct {
+		name    string
+		encoded string
+	}{
+		{"bcrypt", string(legacy)},
+		{"older parameters", weaker},
+		{"garbage", "$argon2id$v=19$m=64"},
+	}
+
+	for _, tt := range tests {
+		if !hasher.NeedsRehash(tt.encoded) {
+			t.Errorf("Expected %s hash to need rehashing", tt.name)
+		}
+	}
+
+	// Hashes made before the switch keep working until they are upgraded
+	if !hasher.Verify(string(legacy), "simorgh") || !hasher.Verify(weaker, "simorgh") {
+		t.Errorf("Expected outdated hashes to still verify")
+	}
+}
@@ -1,10 +1,24 @@
 package player
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
-	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+
+	"haoma/internal/domain/otp"
+)
+
+// Role decides which carnival operations a player may perform
+type Role string
+
+const (
+	RolePlayer Role = "player" // Plays the carnival
+	RoleStaff  Role = "staff"  // Runs stations and watches the live board
+	RoleAdmin  Role = "admin"  // Manages events, grading and other accounts
 )
 
 // Player represents the brave soul
@@ -13,8 +27,33 @@ type Player struct {
 	Name         string    `json:"name" gorm:"not null"`
 	Email        string    `json:"email" gorm:"unique;not null"`
 	PasswordHash string    `json:"-" gorm:"not null"`
+	Role         Role      `json:"role" gorm:"type:text;not null;default:player"`
 	CreatedAt    time.Time `json:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
+
+	RandomPassword bool `json:"-" gorm:"not null;default:false"` // Generated and never shown to anyone, until the player chooses one
+
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"` // Set once the player deleted their account
+
+	Guest            bool       `json:"guest" gorm:"not null;default:false"` // Known only by a nickname until claimed
+	EmailVerifiedAt  *time.Time `json:"email_verified_at,omitempty"`
+	EmailProvenAt    *time.Time `json:"-"` // Set only once the owner showed they read the address, not when verification was skipped
+	TokensValidAfter *time.Time `json:"-"` // Access tokens issued before this are rejected
+
+	// Roster enrollment; invited players exist before the student claims them
+	StudentID          *string    `json:"student_id,omitempty" gorm:"uniqueIndex"`
+	InvitedAt          *time.Time `json:"invited_at,omitempty"` // Set until the account is activated
+	ActivationCodeHash *string    `json:"-" gorm:"uniqueIndex"`
+
+	// Single sign-on identity this account is linked to, if any
+	OIDCIssuer  *string `json:"-" gorm:"uniqueIndex:idx_players_oidc_identity"`
+	OIDCSubject *string `json:"-" gorm:"uniqueIndex:idx_players_oidc_identity"`
+
+	// Two-factor authentication with an authenticator app. The secret is set
+	// during enrollment and only enforced once TOTPEnabledAt is.
+	TOTPSecret    *string    `json:"-"`
+	TOTPEnabledAt *time.Time `json:"totp_enabled_at,omitempty"`
+	TOTPLastStep  int64      `json:"-" gorm:"not null;default:0"` // Last code used, so it cannot be replayed
 }
 
 // Attempt captures a player's answer in time
@@ -27,8 +66,8 @@ type Attempt struct {
 	AttemptAt  time.Time `json:"attempt_at"`
 }
 
-func NewPlayer(name, email, password string) (*Player, error) {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+func NewPlayer(passwords PasswordHasher, name, email, password string) (*Player, error) {
+	hashedPassword, err := passwords.Hash(password)
 	if err != nil {
 		return nil, err
 	}
@@ -37,27 +76,307 @@ func NewPlayer(name, email, password string) (*Player, error) {
 		ID:           uuid.New(),
 		Name:         name,
 		Email:        email,
-		PasswordHash: string(hashedPassword),
+		PasswordHash: hashedPassword,
+		Role:         RolePlayer,
 		CreatedAt:    time.Now(),
 		UpdatedAt:    time.Now(),
 	}, nil
 }
 
-func (player *Player) ValidatePassword(password string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(player.PasswordHash), []byte(password))
-	return err == nil
+// GuestEmailDomain holds the placeholder addresses of guest players. The
+// .invalid TLD can never receive mail or be signed up with for real.
+const GuestEmailDomain = "guests.invalid"
+
+// DeletedName replaces the name of a player who deleted their account
+const DeletedName = "Deleted player"
+
+// NewPlayerWithoutPassword creates a player with a random password nobody
+// knows, for accounts that log in some other way until they choose one
+func NewPlayerWithoutPassword(passwords PasswordHasher, name, email string) (*Player, error) {
+	placeholder, err := randomPassword()
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := NewPlayer(passwords, name, email, placeholder)
+	if err != nil {
+		return nil, err
+	}
+	p.RandomPassword = true
+	return p, nil
+}
+
+// NewInvitedPlayer pre-registers a rostered student. The account gets a
+// random password nobody knows until the student activates it.
+func NewInvitedPlayer(passwords PasswordHasher, studentID, name, email string) (*Player, error) {
+	invited, err := NewPlayerWithoutPassword(passwords, name, email)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	invited.StudentID = &studentID
+	invited.InvitedAt = &now
+	return invited, nil
+}
+
+// NewGuestPlayer creates a player known only by a nickname. Nobody can log
+// in to it with a password; the guest keeps playing with the tokens issued
+// when it was created until they claim it.
+func NewGuestPlayer(passwords PasswordHasher, nickname string) (*Player, error) {
+	guest, err := NewPlayerWithoutPassword(passwords, nickname, "")
+	if err != nil {
+		return nil, err
+	}
+
+	guest.Email = "guest-" + guest.ID.String() + "@" + GuestEmailDomain
+	guest.Guest = true
+	return guest, nil
+}
+
+func randomPassword() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(raw), nil
+}
+
+func (player *Player) ValidatePassword(passwords PasswordHasher, password string) bool {
+	return passwords.Verify(player.PasswordHash, password)
+}
+
+// PasswordNeedsRehash reports whether the stored hash uses an outdated
+// algorithm or parameters. Only a login, which has the password in hand,
+// can upgrade it.
+func (player *Player) PasswordNeedsRehash(passwords PasswordHasher) bool {
+	return passwords.NeedsRehash(player.PasswordHash)
+}
+
+func (player *Player) UpdatePassword(passwords PasswordHasher, newPassword string) error {
+	hashedPassword, err := passwords.Hash(newPassword)
+	if err != nil {
+		return err
+	}
+	player.PasswordHash = hashedPassword
+	player.RandomPassword = false
+	player.UpdatedAt = time.Now()
+	return nil
+}
+
+// ScramblePassword replaces the password with a random one nobody knows
+func (player *Player) ScramblePassword(passwords PasswordHasher) error {
+	placeholder, err := randomPassword()
+	if err != nil {
+		return err
+	}
+	if err := player.UpdatePassword(passwords, placeholder); err != nil {
+		return err
+	}
+	player.RandomPassword = true
+	return nil
+}
+
+func (player *Player) IsVerified() bool {
+	return player.EmailVerifiedAt != nil
+}
+
+// MarkVerified records that the player proved they own their email address
+func (player *Player) MarkVerified() {
+	if player.IsVerified() {
+		return
+	}
+	now := time.Now()
+	player.EmailVerifiedAt = &now
+	player.UpdatedAt = now
+}
+
+// ProveEmail records that the owner demonstrated control of the address,
+// through an emailed token, single sign-on or a handed-out activation code.
+// It also marks the email verified.
+func (player *Player) ProveEmail() {
+	player.MarkVerified()
+	if player.EmailProvenAt != nil {
+		return
+	}
+	now := time.Now()
+	player.EmailProvenAt = &now
+	player.UpdatedAt = now
+}
+
+// HasProvenEmail reports whether anyone ever proved they own the address.
+// Unlike IsVerified, it is false for accounts verified only because
+// verification was not required.
+func (player *Player) HasProvenEmail() bool {
+	return player.EmailProvenAt != nil
+}
+
+// LinkIdentity ties the account to a single sign-on identity
+func (player *Player) LinkIdentity(issuer, subject string) {
+	player.OIDCIssuer = &issuer
+	player.OIDCSubject = &subject
+	player.UpdatedAt = time.Now()
+}
+
+// IsLinkedTo reports whether the account already belongs to a different
+// identity at the same issuer
+func (player *Player) IsLinkedTo(issuer string) bool {
+	return player.OIDCIssuer != nil && *player.OIDCIssuer == issuer
+}
+
+// IsRostered reports whether the player was imported from a class roster
+func (player *Player) IsRostered() bool {
+	return player.StudentID != nil
+}
+
+// IsInvited reports whether the account still waits for its student
+func (player *Player) IsInvited() bool {
+	return player.InvitedAt != nil
+}
+
+// Enroll attaches a student ID to an account that signed up on its own
+func (player *Player) Enroll(studentID string) {
+	player.StudentID = &studentID
+	player.UpdatedAt = time.Now()
 }
 
-func (player *Player) UpdatePassword(newPassword string) error {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+// SetActivationCode replaces the account's one-time activation code
+func (player *Player) SetActivationCode(codeHash string) {
+	player.ActivationCodeHash = &codeHash
+	player.UpdatedAt = time.Now()
+}
+
+// Activate hands an invited account to its student with their own password
+func (player *Player) Activate(passwords PasswordHasher, password string) error {
+	hashedPassword, err := passwords.Hash(password)
 	if err != nil {
 		return err
 	}
-	player.PasswordHash = string(hashedPassword)
+	player.ActivateWithHash(hashedPassword)
+	return nil
+}
+
+// ActivateWithHash is Activate for a password hashed earlier, while the
+// student was still confirming their email
+func (player *Player) ActivateWithHash(passwordHash string) {
+	player.PasswordHash = passwordHash
+	player.RandomPassword = false
+	player.AcceptInvitation()
+}
+
+// AcceptInvitation marks an invited account as claimed. Any activation code
+// stops working.
+func (player *Player) AcceptInvitation() {
+	player.InvitedAt = nil
+	player.ActivationCodeHash = nil
+	player.UpdatedAt = time.Now()
+}
+
+func (player *Player) Rename(name string) {
+	player.Name = name
+	player.UpdatedAt = time.Now()
+}
+
+// Anonymize strips everything that identifies the player. The row itself
+// stays (soft-deleted) so their sessions still count towards statistics.
+func (player *Player) Anonymize() {
+	player.Name = DeletedName
+	player.Email = "deleted-" + player.ID.String() + "@deleted.invalid"
+	player.PasswordHash = "" // Matches no password
+	player.Role = RolePlayer
+	player.EmailVerifiedAt = nil
+	player.EmailProvenAt = nil
+	player.StudentID = nil
+	player.InvitedAt = nil
+	player.ActivationCodeHash = nil
+	player.OIDCIssuer = nil
+	player.OIDCSubject = nil
+	player.DisableTOTP()
+}
+
+// HasTOTP reports whether logins need a code from the authenticator app
+func (player *Player) HasTOTP() bool {
+	return player.TOTPEnabledAt != nil
+}
+
+// BeginTOTPEnrollment stores a new secret. It is not enforced until the
+// player proves their app produces matching codes.
+func (player *Player) BeginTOTPEnrollment(secret string) {
+	player.TOTPSecret = &secret
+	player.TOTPLastStep = 0
+	player.UpdatedAt = time.Now()
+}
+
+// CheckTOTP validates a code from the authenticator app and spends it
+func (player *Player) CheckTOTP(code string) bool {
+	if player.TOTPSecret == nil {
+		return false
+	}
+
+	step, ok := otp.Validate(*player.TOTPSecret, code, time.Now(), player.TOTPLastStep)
+	if !ok {
+		return false
+	}
+	player.TOTPLastStep = step
 	player.UpdatedAt = time.Now()
+	return true
+}
+
+func (player *Player) EnableTOTP() {
+	now := time.Now()
+	player.TOTPEnabledAt = &now
+	player.UpdatedAt = now
+}
+
+func (player *Player) DisableTOTP() {
+	player.TOTPSecret = nil
+	player.TOTPEnabledAt = nil
+	player.TOTPLastStep = 0
+	player.UpdatedAt = time.Now()
+}
+
+func (player *Player) IsGuest() bool {
+	return player.Guest
+}
+
+// Claim turns a guest into a full account with a real email and password.
+// The ID stays the same, so sessions and leaderboard entries carry over.
+func (player *Player) Claim(passwords PasswordHasher, name, email, password string) error {
+	if err := player.UpdatePassword(passwords, password); err != nil {
+		return err
+	}
+	if name != "" {
+		player.Name = name
+	}
+	player.Email = email
+	player.Guest = false
 	return nil
 }
 
+func ParseRole(value string) (Role, error) {
+	switch role := Role(value); role {
+	case RolePlayer, RoleStaff, RoleAdmin:
+		return role, nil
+	default:
+		return "", errors.New("unknown role")
+	}
+}
+
+// HasRole reports whether the player holds any of the given roles
+func (player *Player) HasRole(roles ...Role) bool {
+	for _, role := range roles {
+		if player.Role == role {
+			return true
+		}
+	}
+	return false
+}
+
+func (player *Player) ChangeRole(role Role) {
+	player.Role = role
+	player.UpdatedAt = time.Now()
+}
+
 func NewAttempt(sessionID, questionID uuid.UUID, answer string, isCorrect bool) *Attempt {
 	return &Attempt{
 		ID:         uuid.New(),
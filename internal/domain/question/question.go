@@ -32,6 +32,7 @@ type Category struct {
 // Node represents a trial tent holding questions
 type Node struct {
 	Number     int        `json:"number"`
+	Name       string     `json:"name"`
 	CategoryID uuid.UUID  `json:"category_id"`
 	Questions  []Question `json:"questions"`
 }
@@ -70,6 +70,7 @@ type Session struct {
 	FinishedAt     *time.Time  `json:"finished_at,omitempty"`
 	CurrentNode    int         `json:"current_node" gorm:"default:1"`
 	Score          Score       `json:"score" gorm:"embedded"`
+	NodesCompleted int         `json:"nodes_completed" gorm:"default:0"`
 	Categories     StringSlice `json:"categories" gorm:"type:json"`
 	NodeStartTimes IntMap      `json:"node_start_times" gorm:"type:json"`
 }
@@ -0,0 +1,220 @@
+package token
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const opaqueTokenBytes = 32
+
+// Activation and recovery codes are printed or written down on paper, so
+// they use Crockford's base32 alphabet (no I, L, O or U) in two groups of five
+const (
+	activationAlphabet   = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+	activationCodeLength = 10
+)
+
+// RefreshToken is an opaque, single-use credential for minting new access
+// tokens. Only its hash is stored; every rotation stays in the same family so
+// reuse of a rotated token can revoke the whole chain.
+type RefreshToken struct {
+	ID        uuid.UUID  `json:"id" gorm:"type:uuid;primary_key"`
+	PlayerID  uuid.UUID  `json:"player_id" gorm:"type:uuid;not null;index"`
+	FamilyID  uuid.UUID  `json:"family_id" gorm:"type:uuid;not null;index"`
+	TokenHash string     `json:"-" gorm:"uniqueIndex;not null"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	RotatedAt *time.Time `json:"rotated_at,omitempty"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+
+	SecondFactor bool `json:"second_factor" gorm:"not null;default:false"` // The login passed two-factor authentication
+}
+
+// RevokedToken blocks a single access token, identified by its jti, until it
+// would have expired anyway
+type RevokedToken struct {
+	ID        string    `json:"id" gorm:"primary_key"` // The token's jti
+	PlayerID  uuid.UUID `json:"player_id" gorm:"type:uuid;not null;index"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"index"`
+	RevokedAt time.Time `json:"revoked_at"`
+}
+
+// PasswordResetToken lets a player who forgot their password choose a new
+// one. It is emailed to them, stored hashed and works only once.
+type PasswordResetToken struct {
+	ID        uuid.UUID  `json:"id" gorm:"type:uuid;primary_key"`
+	PlayerID  uuid.UUID  `json:"player_id" gorm:"type:uuid;not null;index"`
+	TokenHash string     `json:"-" gorm:"uniqueIndex;not null"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// EmailVerificationToken confirms a player owns the address they signed up
+// with. It is emailed to them, stored hashed and works only once.
+type EmailVerificationToken struct {
+	ID                  uuid.UUID  `json:"id" gorm:"type:uuid;primary_key"`
+	PlayerID            uuid.UUID  `json:"player_id" gorm:"type:uuid;not null;index"`
+	TokenHash           string     `json:"-" gorm:"uniqueIndex;not null"`
+	PendingPasswordHash *string    `json:"-"` // Set when signing up claims an invited account; applied once verified
+	ExpiresAt           time.Time  `json:"expires_at"`
+	UsedAt              *time.Time `json:"used_at,omitempty"`
+	CreatedAt           time.Time  `json:"created_at"`
+}
+
+// RecoveryCode lets a player past two-factor authentication once when their
+// authenticator app is unavailable. Only its hash is stored.
+type RecoveryCode struct {
+	ID        uuid.UUID  `json:"id" gorm:"type:uuid;primary_key"`
+	PlayerID  uuid.UUID  `json:"player_id" gorm:"type:uuid;not null;index"`
+	CodeHash  string     `json:"-" gorm:"not null"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// Generate returns a random URL-safe token and the hash to persist for it
+func Generate() (string, string, error) {
+	raw := make([]byte, opaqueTokenBytes)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", err
+	}
+
+	value := base64.RawURLEncoding.EncodeToString(raw)
+	return value, Hash(value), nil
+}
+
+// Hash derives the lookup key stored in place of an opaque token
+func Hash(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
+
+// GenerateActivationCode returns a printable one-time code such as
+// "7KQ2M-XH4PD" and the hash to persist for it
+func GenerateActivationCode() (string, string, error) {
+	return generateCode()
+}
+
+// GenerateRecoveryCode returns a two-factor recovery code, in the same
+// format as activation codes, and the hash to persist for it
+func GenerateRecoveryCode() (string, string, error) {
+	return generateCode()
+}
+
+func generateCode() (string, string, error) {
+	raw := make([]byte, activationCodeLength)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", err
+	}
+
+	code := make([]byte, 0, activationCodeLength+1)
+	for i, b := range raw {
+		if i == activationCodeLength/2 {
+			code = append(code, '-')
+		}
+		code = append(code, activationAlphabet[int(b)%len(activationAlphabet)])
+	}
+
+	return string(code), HashActivationCode(string(code)), nil
+}
+
+// HashActivationCode hashes a code as typed by a player, forgiving case,
+// dashes, spaces and the letters commonly misread as digits
+func HashActivationCode(code string) string {
+	normalized := strings.NewReplacer("-", "", " ", "", "O", "0", "I", "1", "L", "1").
+		Replace(strings.ToUpper(code))
+	return Hash(normalized)
+}
+
+// HashRecoveryCode hashes a recovery code as typed, as forgiving as
+// activation codes
+func HashRecoveryCode(code string) string {
+	return HashActivationCode(code)
+}
+
+func NewRefreshToken(playerID, familyID uuid.UUID, tokenHash string, ttl time.Duration, secondFactor bool) *RefreshToken {
+	return &RefreshToken{
+		ID:           uuid.New(),
+		PlayerID:     playerID,
+		FamilyID:     familyID,
+		TokenHash:    tokenHash,
+		ExpiresAt:    time.Now().Add(ttl),
+		CreatedAt:    time.Now(),
+		SecondFactor: secondFactor,
+	}
+}
+
+func NewRecoveryCode(playerID uuid.UUID, codeHash string) *RecoveryCode {
+	return &RecoveryCode{
+		ID:        uuid.New(),
+		PlayerID:  playerID,
+		CodeHash:  codeHash,
+		CreatedAt: time.Now(),
+	}
+}
+
+func (refresh *RefreshToken) IsExpired() bool {
+	return time.Now().After(refresh.ExpiresAt)
+}
+
+// WasRotated reports whether this token has already been exchanged once
+func (refresh *RefreshToken) WasRotated() bool {
+	return refresh.RotatedAt != nil
+}
+
+func (refresh *RefreshToken) IsRevoked() bool {
+	return refresh.RevokedAt != nil
+}
+
+func NewRevokedToken(tokenID string, playerID uuid.UUID, expiresAt time.Time) *RevokedToken {
+	return &RevokedToken{
+		ID:        tokenID,
+		PlayerID:  playerID,
+		ExpiresAt: expiresAt,
+		RevokedAt: time.Now(),
+	}
+}
+
+func NewPasswordResetToken(playerID uuid.UUID, tokenHash string, ttl time.Duration) *PasswordResetToken {
+	return &PasswordResetToken{
+		ID:        uuid.New(),
+		PlayerID:  playerID,
+		TokenHash: tokenHash,
+		ExpiresAt: time.Now().Add(ttl),
+		CreatedAt: time.Now(),
+	}
+}
+
+func (reset *PasswordResetToken) IsExpired() bool {
+	return time.Now().After(reset.ExpiresAt)
+}
+
+func (reset *PasswordResetToken) IsUsed() bool {
+	return reset.UsedAt != nil
+}
+
+// CanBeUsed reports whether the token may still reset a password
+func (reset *PasswordResetToken) CanBeUsed() bool {
+	return !reset.IsUsed() && !reset.IsExpired()
+}
+
+func NewEmailVerificationToken(playerID uuid.UUID, tokenHash string, ttl time.Duration) *EmailVerificationToken {
+	return &EmailVerificationToken{
+		ID:        uuid.New(),
+		PlayerID:  playerID,
+		TokenHash: tokenHash,
+		ExpiresAt: time.Now().Add(ttl),
+		CreatedAt: time.Now(),
+	}
+}
+
+// CanBeUsed reports whether the token may still verify an email address
+func (verification *EmailVerificationToken) CanBeUsed() bool {
+	return verification.UsedAt == nil && time.Now().Before(verification.ExpiresAt)
+}
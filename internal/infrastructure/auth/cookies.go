@@ -0,0 +1,108 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Cookie mode keeps tokens out of reach of page scripts: browser clients
+// that send AuthModeHeader get their tokens as HttpOnly cookies instead of
+// in the response body. Requests authenticated by cookie must echo the CSRF
+// cookie in CSRFHeader (double-submit), which another site cannot read.
+const (
+	AuthModeHeader = "X-Auth-Mode" // "cookie" asks for cookie mode
+	CSRFHeader     = "X-CSRF-Token"
+
+	AccessCookie  = "haoma_access"
+	RefreshCookie = "haoma_refresh"
+	CSRFCookie    = "haoma_csrf"
+
+	refreshCookiePath = "/api/v1/auth" // Sent only where it is used
+)
+
+// CookieSettings decide whether cookie mode is offered and how its cookies
+// are scoped
+type CookieSettings struct {
+	Enabled  bool
+	SameSite http.SameSite
+	Domain   string // Empty for the API host only
+}
+
+// CookieSettingsFromEnv reads AUTH_COOKIE_MODE, AUTH_COOKIE_SAMESITE (lax,
+// strict or none; lax by default) and AUTH_COOKIE_DOMAIN
+func CookieSettingsFromEnv() (CookieSettings, error) {
+	settings := CookieSettings{
+		Enabled:  os.Getenv("AUTH_COOKIE_MODE") == "true",
+		SameSite: http.SameSiteLaxMode,
+		Domain:   os.Getenv("AUTH_COOKIE_DOMAIN"),
+	}
+
+	switch value := strings.ToLower(os.Getenv("AUTH_COOKIE_SAMESITE")); value {
+	case "", "lax":
+	case "strict":
+		settings.SameSite = http.SameSiteStrictMode
+	case "none":
+		settings.SameSite = http.SameSiteNoneMode
+	default:
+		return settings, fmt.Errorf("AUTH_COOKIE_SAMESITE must be lax, strict or none, got %q", value)
+	}
+
+	return settings, nil
+}
+
+// Wants reports whether a request should get its tokens as cookies
+func (s CookieSettings) Wants(c *gin.Context) bool {
+	return s.Enabled && c.GetHeader(AuthModeHeader) == "cookie"
+}
+
+// SetTokens stores the tokens of a login or refresh in cookies and returns
+// the new CSRF token, which the client reads from the response body
+func (s CookieSettings) SetTokens(c *gin.Context, accessToken string, accessMaxAge int, refreshToken string, refreshMaxAge int) (string, error) {
+	csrf, err := newCSRFToken()
+	if err != nil {
+		return "", err
+	}
+
+	c.SetSameSite(s.SameSite)
+	c.SetCookie(AccessCookie, accessToken, accessMaxAge, "/", s.Domain, true, true)
+	c.SetCookie(RefreshCookie, refreshToken, refreshMaxAge, refreshCookiePath, s.Domain, true, true)
+	// Readable by the client's own scripts, so they can echo it
+	c.SetCookie(CSRFCookie, csrf, refreshMaxAge, "/", s.Domain, true, false)
+	return csrf, nil
+}
+
+// ClearTokens removes every cookie mode cookie
+func (s CookieSettings) ClearTokens(c *gin.Context) {
+	c.SetSameSite(s.SameSite)
+	c.SetCookie(AccessCookie, "", -1, "/", s.Domain, true, true)
+	c.SetCookie(RefreshCookie, "", -1, refreshCookiePath, s.Domain, true, true)
+	c.SetCookie(CSRFCookie, "", -1, "/", s.Domain, true, false)
+}
+
+// ValidCSRF checks the double-submitted CSRF token. Safe methods change
+// nothing and need none.
+func ValidCSRF(c *gin.Context) bool {
+	switch c.Request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+
+	cookie, err := c.Cookie(CSRFCookie)
+	header := c.GetHeader(CSRFHeader)
+	return err == nil && cookie != "" && subtle.ConstantTimeCompare([]byte(cookie), []byte(header)) == 1
+}
+
+func newCSRFToken() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
@@ -0,0 +1,42 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestValidCSRF(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name     string
+		method   string
+		cookie   string
+		header   string
+		expected bool
+	}{
+		{"safe method needs no token", http.MethodGet, "", "", true},
+		{"matching token", http.MethodPost, "abc", "abc", true},
+		{"missing header", http.MethodPost, "abc", "", false},
+		{"mismatched token", http.MethodDelete, "abc", "abd", false},
+		{"missing cookie", http.MethodPost, "", "abc", false},
+	}
+
+	for _, tt := range tests {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(tt.method, "/api/v1/auth/logout", nil)
+		if tt.cookie != "" {
+			c.Request.AddCookie(&http.Cookie{Name: CSRFCookie, Value: tt.cookie})
+		}
+		if tt.header != "" {
+			c.Request.Header.Set(CSRFHeader, tt.header)
+		}
+
+		if got := ValidCSRF(c); got != tt.expected {
+			t.Errorf("%s: Expected %v, got %v", tt.name, tt.expected, got)
+		}
+	}
+}
@@ -6,17 +6,55 @@ import (
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
+
+	"haoma/internal/config"
+	"haoma/internal/domain/player"
 )
 
 type JWTService struct {
-	secretKey []byte
+	keys    *KeySet
+	cutoffs TokenCutoffs
+}
+
+// TokenCutoffs knows since when each player's tokens are accepted, after
+// all of them were revoked at once
+type TokenCutoffs interface {
+	ValidAfter(playerID uuid.UUID) time.Time
 }
 
+// challengeAudience marks the short-lived token that stands between a
+// correct password and the second factor; it is never an access token
+const challengeAudience = "haoma-mfa-challenge"
+
+// Authentication methods (RFC 8176) recorded when a login passed two-factor
+// authentication
+var secondFactorMethods = []string{"mfa", "otp"}
+
 type PlayerClaims struct {
-	PlayerID    uuid.UUID  `json:"player_id"`
-	PlayerName  string     `json:"player_name"`
-	PlayerEmail string     `json:"player_email"`
-	SessionID   *uuid.UUID `json:"session_id,omitempty"`
+	PlayerID    uuid.UUID   `json:"player_id"`
+	PlayerName  string      `json:"player_name"`
+	PlayerEmail string      `json:"player_email"`
+	Role        player.Role `json:"role"`
+	SessionID   *uuid.UUID  `json:"session_id,omitempty"`
+	FamilyID    uuid.UUID   `json:"family_id"`     // Refresh token family this access token was minted from
+	AMR         []string    `json:"amr,omitempty"` // How the player authenticated
+	jwt.RegisteredClaims
+}
+
+// HasSecondFactor reports whether the login passed two-factor authentication
+func (c *PlayerClaims) HasSecondFactor() bool {
+	for _, method := range c.AMR {
+		if method == "otp" {
+			return true
+		}
+	}
+	return false
+}
+
+// ChallengeClaims identify a player who got their password right but still
+// owes a second factor
+type ChallengeClaims struct {
+	PlayerID uuid.UUID `json:"player_id"`
 	jwt.RegisteredClaims
 }
 
@@ -26,28 +64,44 @@ type SessionClaims struct {
 	jwt.RegisteredClaims
 }
 
-func NewJWTService(secretKey string) *JWTService {
+func NewJWTService(keys *KeySet, cutoffs TokenCutoffs) *JWTService {
 	return &JWTService{
-		secretKey: []byte(secretKey),
+		keys:    keys,
+		cutoffs: cutoffs,
 	}
 }
 
-func (j *JWTService) GeneratePlayerToken(playerID uuid.UUID, playerName, playerEmail string) (string, error) {
+// JWKS returns the public keys other services can verify our tokens with
+func (j *JWTService) JWKS() JWKS {
+	return j.keys.JWKS()
+}
+
+// GeneratePlayerToken issues a short-lived access token tied to the refresh
+// token family it was minted from
+func (j *JWTService) GeneratePlayerToken(p *player.Player, familyID uuid.UUID, secondFactor bool) (string, error) {
+	var methods []string
+	if secondFactor {
+		methods = secondFactorMethods
+	}
+
 	claims := PlayerClaims{
-		PlayerID:    playerID,
-		PlayerName:  playerName,
-		PlayerEmail: playerEmail,
+		PlayerID:    p.ID,
+		PlayerName:  p.Name,
+		PlayerEmail: p.Email,
+		Role:        p.Role,
+		FamilyID:    familyID,
+		AMR:         methods,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)), // 24 hour expiry
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(config.JWT_EXPIRY)),
+			IssuedAt:  jwt.NewNumericDate(j.issuedAt(p.ID)),
 			NotBefore: jwt.NewNumericDate(time.Now()),
 			Issuer:    "haoma-carnival",
-			Subject:   playerID.String(),
+			Subject:   p.ID.String(),
+			ID:        uuid.New().String(), // jti, so the token can be revoked on its own
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(j.secretKey)
+	return j.sign(claims)
 }
 
 func (j *JWTService) GenerateSessionToken(playerID, sessionID uuid.UUID) (string, error) {
@@ -63,53 +117,106 @@ func (j *JWTService) GenerateSessionToken(playerID, sessionID uuid.UUID) (string
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(j.secretKey)
+	return j.sign(claims)
+}
+
+// GenerateChallengeToken issues the token a player trades, together with a
+// code from their authenticator app, for real tokens
+func (j *JWTService) GenerateChallengeToken(playerID uuid.UUID) (string, error) {
+	claims := ChallengeClaims{
+		PlayerID: playerID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(config.MFA_CHALLENGE_EXPIRY)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			NotBefore: jwt.NewNumericDate(time.Now()),
+			Issuer:    "haoma-carnival",
+			Subject:   playerID.String(),
+			Audience:  jwt.ClaimStrings{challengeAudience},
+			ID:        uuid.New().String(),
+		},
+	}
+
+	return j.sign(claims)
+}
+
+// issuedAt dates a new access token. A token minted right after the player's
+// tokens were revoked can fall in the same second as the cutoff, which is
+// rounded up; it is dated at the cutoff so it is not rejected with the rest.
+func (j *JWTService) issuedAt(playerID uuid.UUID) time.Time {
+	now := time.Now()
+	if cutoff := j.cutoffs.ValidAfter(playerID); cutoff.After(now) {
+		return cutoff
+	}
+	return now
+}
+
+// sign signs claims with the current key and names it in the kid header
+func (j *JWTService) sign(claims jwt.Claims) (string, error) {
+	key := j.keys.Signing()
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.Key)
+}
+
+// verificationKey picks the key named by the token's kid header, making sure
+// the token was signed with the algorithm that key belongs to
+func (j *JWTService) verificationKey(token *jwt.Token) (interface{}, error) {
+	keyID, _ := token.Header["kid"].(string)
+	key, ok := j.keys.Verification(keyID)
+	if !ok {
+		return nil, errors.New("unknown signing key")
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, errors.New("unexpected signing method")
+	}
+	return key.Key, nil
 }
 
 func (j *JWTService) ValidatePlayerToken(tokenString string) (*PlayerClaims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &PlayerClaims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("unexpected signing method")
-		}
-		return j.secretKey, nil
-	})
+	token, err := jwt.ParseWithClaims(tokenString, &PlayerClaims{}, j.verificationKey)
 
 	if err != nil {
 		return nil, err
 	}
 
 	if claims, ok := token.Claims.(*PlayerClaims); ok && token.Valid {
+		// A challenge token carries a player ID too, but proves only the password
+		for _, audience := range claims.Audience {
+			if audience == challengeAudience {
+				return nil, errors.New("invalid token")
+			}
+		}
 		return claims, nil
 	}
 
 	return nil, errors.New("invalid token")
 }
 
-func (j *JWTService) ValidateSessionToken(tokenString string) (*SessionClaims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &SessionClaims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("unexpected signing method")
-		}
-		return j.secretKey, nil
-	})
+func (j *JWTService) ValidateChallengeToken(tokenString string) (*ChallengeClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &ChallengeClaims{}, j.verificationKey,
+		jwt.WithAudience(challengeAudience))
 
 	if err != nil {
 		return nil, err
 	}
 
-	if claims, ok := token.Claims.(*SessionClaims); ok && token.Valid {
+	if claims, ok := token.Claims.(*ChallengeClaims); ok && token.Valid {
 		return claims, nil
 	}
 
-	return nil, errors.New("invalid session token")
+	return nil, errors.New("invalid challenge token")
 }
 
-func (j *JWTService) RefreshPlayerToken(oldTokenString string) (string, error) {
-	claims, err := j.ValidatePlayerToken(oldTokenString)
+func (j *JWTService) ValidateSessionToken(tokenString string) (*SessionClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &SessionClaims{}, j.verificationKey)
+
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+
+	if claims, ok := token.Claims.(*SessionClaims); ok && token.Valid {
+		return claims, nil
 	}
 
-	return j.GeneratePlayerToken(claims.PlayerID, claims.PlayerName, claims.PlayerEmail)
+	return nil, errors.New("invalid session token")
 }
@@ -0,0 +1,282 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// SigningKey is a private key the server signs tokens with
+type SigningKey struct {
+	ID     string
+	Method jwt.SigningMethod
+	Key    crypto.Signer
+}
+
+// VerificationKey is a public key tokens may have been signed with
+type VerificationKey struct {
+	ID     string
+	Method jwt.SigningMethod
+	Key    crypto.PublicKey
+}
+
+// KeySet holds the current signing key plus every key that is still accepted
+// for verification. Rotating keys means signing with a new key while the
+// previous one stays in the verification set until its tokens have expired.
+type KeySet struct {
+	signing      SigningKey
+	verification map[string]VerificationKey
+}
+
+// JWK is a public key in JSON Web Key format
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+}
+
+// JWKS is the document served at /.well-known/jwks.json
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// NewKeySet builds a key set from a signing key and any older keys that should
+// still verify. The signing key is always part of the verification set.
+func NewKeySet(signer crypto.Signer, previous ...crypto.PublicKey) (*KeySet, error) {
+	signing, err := newVerificationKey(signer.Public())
+	if err != nil {
+		return nil, err
+	}
+
+	keys := &KeySet{
+		signing:      SigningKey{ID: signing.ID, Method: signing.Method, Key: signer},
+		verification: map[string]VerificationKey{signing.ID: signing},
+	}
+
+	for _, public := range previous {
+		key, err := newVerificationKey(public)
+		if err != nil {
+			return nil, err
+		}
+		keys.verification[key.ID] = key
+	}
+
+	return keys, nil
+}
+
+// LoadKeySet reads a PEM signing key and any number of PEM verification keys
+// (public or private) from disk
+func LoadKeySet(signingKeyFile string, verificationKeyFiles []string) (*KeySet, error) {
+	signer, err := loadPrivateKey(signingKeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var previous []crypto.PublicKey
+	for _, file := range verificationKeyFiles {
+		public, err := loadPublicKey(file)
+		if err != nil {
+			return nil, err
+		}
+		previous = append(previous, public)
+	}
+
+	return NewKeySet(signer, previous...)
+}
+
+// LoadKeySetFromEnv loads keys from JWT_SIGNING_KEY_FILE and the
+// comma-separated JWT_VERIFICATION_KEY_FILES. It returns ErrNoSigningKey when
+// no signing key is configured.
+func LoadKeySetFromEnv() (*KeySet, error) {
+	signingKeyFile := os.Getenv("JWT_SIGNING_KEY_FILE")
+	if signingKeyFile == "" {
+		return nil, ErrNoSigningKey
+	}
+
+	var verificationKeyFiles []string
+	for _, file := range strings.Split(os.Getenv("JWT_VERIFICATION_KEY_FILES"), ",") {
+		if file = strings.TrimSpace(file); file != "" {
+			verificationKeyFiles = append(verificationKeyFiles, file)
+		}
+	}
+
+	return LoadKeySet(signingKeyFile, verificationKeyFiles)
+}
+
+// ErrNoSigningKey means JWT_SIGNING_KEY_FILE is not set
+var ErrNoSigningKey = errors.New("JWT_SIGNING_KEY_FILE is not set")
+
+// GenerateEphemeralKeySet creates a throwaway Ed25519 key for development.
+// Tokens signed with it stop verifying when the process restarts.
+func GenerateEphemeralKeySet() (*KeySet, error) {
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return NewKeySet(private)
+}
+
+// Signing returns the key new tokens are signed with
+func (k *KeySet) Signing() SigningKey {
+	return k.signing
+}
+
+// Verification looks up an accepted key by its ID
+func (k *KeySet) Verification(keyID string) (VerificationKey, bool) {
+	key, ok := k.verification[keyID]
+	return key, ok
+}
+
+// JWKS lists every verification key so other services can check our tokens
+func (k *KeySet) JWKS() JWKS {
+	jwks := JWKS{Keys: make([]JWK, 0, len(k.verification))}
+
+	var previous []string
+	for id := range k.verification {
+		if id != k.signing.ID {
+			previous = append(previous, id)
+		}
+	}
+	sort.Strings(previous)
+
+	// Current signing key first, so consumers that only take one pick it
+	jwks.Keys = append(jwks.Keys, toJWK(k.verification[k.signing.ID]))
+	for _, id := range previous {
+		jwks.Keys = append(jwks.Keys, toJWK(k.verification[id]))
+	}
+
+	return jwks
+}
+
+func newVerificationKey(public crypto.PublicKey) (VerificationKey, error) {
+	var method jwt.SigningMethod
+	switch public.(type) {
+	case ed25519.PublicKey:
+		method = jwt.SigningMethodEdDSA
+	case *rsa.PublicKey:
+		method = jwt.SigningMethodRS256
+	default:
+		return VerificationKey{}, fmt.Errorf("unsupported key type %T (use Ed25519 or RSA)", public)
+	}
+
+	key := VerificationKey{Method: method, Key: public}
+	key.ID = thumbprint(toJWK(key))
+	return key, nil
+}
+
+// thumbprint derives a stable key ID from the public key (RFC 7638), so the
+// same key file always gets the same kid
+func thumbprint(jwk JWK) string {
+	var canonical string
+	if jwk.KeyType == "OKP" {
+		canonical = fmt.Sprintf(`{"crv":%q,"kty":%q,"x":%q}`, jwk.Curve, jwk.KeyType, jwk.X)
+	} else {
+		canonical = fmt.Sprintf(`{"e":%q,"kty":%q,"n":%q}`, jwk.E, jwk.KeyType, jwk.N)
+	}
+
+	sum := sha256.Sum256([]byte(canonical))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func toJWK(key VerificationKey) JWK {
+	jwk := JWK{KeyID: key.ID, Use: "sig", Algorithm: key.Method.Alg()}
+
+	switch public := key.Key.(type) {
+	case ed25519.PublicKey:
+		jwk.KeyType = "OKP"
+		jwk.Curve = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(public)
+	case *rsa.PublicKey:
+		jwk.KeyType = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+	}
+
+	return jwk
+}
+
+func loadPrivateKey(file string) (crypto.Signer, error) {
+	block, err := readPEM(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var parsed interface{}
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%s: expected a private key, found %q", file, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+
+	signer, ok := parsed.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("%s: unsupported private key", file)
+	}
+	return signer, nil
+}
+
+func loadPublicKey(file string) (crypto.PublicKey, error) {
+	block, err := readPEM(file)
+	if err != nil {
+		return nil, err
+	}
+
+	switch block.Type {
+	case "PUBLIC KEY":
+		public, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		return public, nil
+	case "RSA PUBLIC KEY":
+		public, err := x509.ParsePKCS1PublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		return public, nil
+	default:
+		// Retired signing keys can be listed as-is
+		signer, err := loadPrivateKey(file)
+		if err != nil {
+			return nil, err
+		}
+		return signer.Public(), nil
+	}
+}
+
+func readPEM(file string) (*pem.Block, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM data found", file)
+	}
+	return block, nil
+}
@@ -0,0 +1,212 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+
+	"haoma/internal/domain/player"
+)
+
+type noCutoffs struct{}
+
+func (noCutoffs) ValidAfter(playerID uuid.UUID) time.Time {
+	return time.Time{}
+}
+
+func writePEM(t *testing.T, blockType string, der []byte) string {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "key.pem")
+	if err := os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+		t.Fatalf("Failed to write key: %v", err)
+	}
+	return file
+}
+
+func TestLoadKeySet_PEMFormats(t *testing.T) {
+	_, edPrivate, _ := ed25519.GenerateKey(rand.Reader)
+	rsaPrivate, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	edPKCS8, _ := x509.MarshalPKCS8PrivateKey(edPrivate)
+	edPublic, _ := x509.MarshalPKIXPublicKey(edPrivate.Public())
+	rsaPKCS8, _ := x509.MarshalPKCS8PrivateKey(rsaPrivate)
+
+	tests := []struct {
+		name         string
+		signing      string
+		verification []string
+		method       string
+		keys         int
+	}{
+		{"Ed25519 PKCS#8", writePEM(t, "PRIVATE KEY", edPKCS8), nil, "EdDSA", 1},
+		{"RSA PKCS#8", writePEM(t, "PRIVATE KEY", rsaPKCS8), nil, "RS256", 1},
+		{"RSA PKCS#1", writePEM(t, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaPrivate)), nil, "RS256", 1},
+		{"previous public key", writePEM(t, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaPrivate)),
+			[]string{writePEM(t, "PUBLIC KEY", edPublic)}, "RS256", 2},
+		{"previous RSA public key", writePEM(t, "PRIVATE KEY", edPKCS8),
+			[]string{writePEM(t, "RSA PUBLIC KEY", x509.MarshalPKCS1PublicKey(&rsaPrivate.PublicKey))}, "EdDSA", 2},
+		{"retired signing key", writePEM(t, "PRIVATE KEY", edPKCS8),
+			[]string{writePEM(t, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaPrivate))}, "EdDSA", 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys, err := LoadKeySet(tt.signing, tt.verification)
+			if err != nil {
+				t.Fatalf("LoadKeySet failed: %v", err)
+			}
+			if alg := keys.Signing().Method.Alg(); alg != tt.method {
+				t.Errorf("Expected signing method %s, got %s", tt.method, alg)
+			}
+			if got := len(keys.JWKS().Keys); got != tt.keys {
+				t.Errorf("Expected %d verification keys, got %d", tt.keys, got)
+			}
+		})
+	}
+}
+
+func TestLoadKeySet_Invalid(t *testing.T) {
+	edPublic, _, _ := ed25519.GenerateKey(rand.Reader)
+	publicDER, _ := x509.MarshalPKIXPublicKey(edPublic)
+
+	notPEM := filepath.Join(t.TempDir(), "key.pem")
+	os.WriteFile(notPEM, []byte("not a key"), 0o600)
+
+	tests := []struct {
+		name string
+		file string
+	}{
+		{"missing file", filepath.Join(t.TempDir(), "missing.pem")},
+		{"no PEM data", notPEM},
+		{"public key as signing key", writePEM(t, "PUBLIC KEY", publicDER)},
+		{"corrupt private key", writePEM(t, "PRIVATE KEY", []byte("garbage"))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := LoadKeySet(tt.file, nil); err == nil {
+				t.Errorf("Expected an error")
+			}
+		})
+	}
+}
+
+func TestThumbprint(t *testing.T) {
+	// RFC 7638, section 3.1
+	jwk := JWK{
+		KeyType: "RSA",
+		N:       "0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw",
+		E:       "AQAB",
+	}
+
+	if kid := thumbprint(jwk); kid != "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs" {
+		t.Errorf("Expected the RFC 7638 thumbprint, got %s", kid)
+	}
+}
+
+func TestKeySet_JWKS(t *testing.T) {
+	_, edPrivate, _ := ed25519.GenerateKey(rand.Reader)
+	rsaPrivate, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	keys, err := NewKeySet(edPrivate, &rsaPrivate.PublicKey)
+	if err != nil {
+		t.Fatalf("NewKeySet failed: %v", err)
+	}
+
+	jwks := keys.JWKS()
+	if len(jwks.Keys) != 2 {
+		t.Fatalf("Expected 2 keys, got %d", len(jwks.Keys))
+	}
+
+	current := jwks.Keys[0]
+	if current.KeyID != keys.Signing().ID {
+		t.Errorf("Expected the signing key first, got %s", current.KeyID)
+	}
+	if current.KeyType != "OKP" || current.Curve != "Ed25519" || current.Algorithm != "EdDSA" || current.Use != "sig" || current.X == "" {
+		t.Errorf("Unexpected Ed25519 JWK: %+v", current)
+	}
+
+	previous := jwks.Keys[1]
+	if previous.KeyType != "RSA" || previous.Algorithm != "RS256" || previous.E != "AQAB" || previous.N == "" {
+		t.Errorf("Unexpected RSA JWK: %+v", previous)
+	}
+	if previous.KeyID != thumbprint(previous) {
+		t.Errorf("Expected the kid to be the key's thumbprint")
+	}
+
+	// The same key always gets the same kid
+	again, _ := NewKeySet(edPrivate)
+	if again.Signing().ID != keys.Signing().ID {
+		t.Errorf("Expected a stable kid, got %s and %s", again.Signing().ID, keys.Signing().ID)
+	}
+}
+
+func TestValidatePlayerToken_KeyMismatch(t *testing.T) {
+	_, edPrivate, _ := ed25519.GenerateKey(rand.Reader)
+	rsaPrivate, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	keys, _ := NewKeySet(edPrivate, &rsaPrivate.PublicKey)
+	jwtService := NewJWTService(keys, noCutoffs{})
+	edKeyID := keys.Signing().ID
+	rsaKeyID := keys.JWKS().Keys[1].KeyID
+
+	p, _ := player.NewPlayer(player.NewArgon2idHasher(player.Argon2idParams{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}), "Kaveh", "kaveh@uni.edu", "blacksmith")
+	valid, err := jwtService.GeneratePlayerToken(p, uuid.New(), false)
+	if err != nil {
+		t.Fatalf("GeneratePlayerToken failed: %v", err)
+	}
+	if _, err := jwtService.ValidatePlayerToken(valid); err != nil {
+		t.Fatalf("Expected a token signed with the current key to verify, got %v", err)
+	}
+
+	_, strangerKey, _ := ed25519.GenerateKey(rand.Reader)
+	claims := PlayerClaims{
+		PlayerID: p.ID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ID:        uuid.New().String(),
+		},
+	}
+
+	sign := func(method jwt.SigningMethod, keyID string, key interface{}) string {
+		token := jwt.NewWithClaims(method, claims)
+		if keyID != "" {
+			token.Header["kid"] = keyID
+		}
+		signed, err := token.SignedString(key)
+		if err != nil {
+			t.Fatalf("Failed to sign: %v", err)
+		}
+		return signed
+	}
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"no kid", sign(jwt.SigningMethodEdDSA, "", edPrivate)},
+		{"unknown kid", sign(jwt.SigningMethodEdDSA, "unknown", edPrivate)},
+		{"unknown key under a known kid", sign(jwt.SigningMethodEdDSA, edKeyID, strangerKey)},
+		{"RS256 under the Ed25519 kid", sign(jwt.SigningMethodRS256, edKeyID, rsaPrivate)},
+		{"EdDSA under the RSA kid", sign(jwt.SigningMethodEdDSA, rsaKeyID, edPrivate)},
+		{"HS256 keyed with the public key", sign(jwt.SigningMethodHS256, edKeyID, []byte(edPrivate.Public().(ed25519.PublicKey)))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := jwtService.ValidatePlayerToken(tt.token); err == nil {
+				t.Errorf("Expected the token to be rejected")
+			}
+		})
+	}
+}
@@ -3,32 +3,58 @@ package auth
 import (
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+
+	"haoma/internal/domain/player"
 )
 
-func JWTMiddleware(jwtService *JWTService) gin.HandlerFunc {
+// RevocationList answers whether an otherwise valid token has been revoked
+type RevocationList interface {
+	IsRevoked(tokenID string, playerID uuid.UUID, issuedAt time.Time) bool
+}
+
+// JWTMiddleware authenticates the player from the Authorization header or,
+// in cookie mode, the access cookie. Cookie requests that change state must
+// carry the CSRF token.
+func JWTMiddleware(jwtService *JWTService, revocations RevocationList) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		var tokenString string
 		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
+		if authHeader != "" {
+			tokenParts := strings.Split(authHeader, " ")
+			if len(tokenParts) != 2 || tokenParts[0] != "Bearer" {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization header format"})
+				c.Abort()
+				return
+			}
+			tokenString = tokenParts[1]
+		} else if cookie, err := c.Cookie(AccessCookie); err == nil && cookie != "" {
+			if !ValidCSRF(c) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Missing or invalid CSRF token"})
+				c.Abort()
+				return
+			}
+			tokenString = cookie
+		} else {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Missing authorization header - Bearer token required"})
 			c.Abort()
 			return
 		}
 
-		tokenParts := strings.Split(authHeader, " ")
-		if len(tokenParts) != 2 || tokenParts[0] != "Bearer" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization header format"})
+		claims, err := jwtService.ValidatePlayerToken(tokenString)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
 			c.Abort()
 			return
 		}
 
-		tokenString := tokenParts[1]
-
-		claims, err := jwtService.ValidatePlayerToken(tokenString)
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+		// Tokens without a jti predate the revocation list and cannot be revoked
+		if claims.ID == "" || claims.IssuedAt == nil ||
+			revocations.IsRevoked(claims.ID, claims.PlayerID, claims.IssuedAt.Time) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token has been revoked"})
 			c.Abort()
 			return
 		}
@@ -36,12 +62,68 @@ func JWTMiddleware(jwtService *JWTService) gin.HandlerFunc {
 		c.Set("player_id", claims.PlayerID)
 		c.Set("player_name", claims.PlayerName)
 		c.Set("player_email", claims.PlayerEmail)
+		role := claims.Role
+		if role == "" {
+			// Tokens minted before roles existed belong to ordinary players
+			role = player.RolePlayer
+		}
+		c.Set("player_role", role)
 		c.Set("player_claims", claims)
 
 		c.Next()
 	}
 }
 
+// RequireRole only lets through players holding one of the given roles. It
+// must run after JWTMiddleware.
+func RequireRole(roles ...player.Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role, exists := c.Get("player_role")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Player not authenticated"})
+			c.Abort()
+			return
+		}
+
+		for _, allowed := range roles {
+			if role.(player.Role) == allowed {
+				c.Next()
+				return
+			}
+		}
+
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to perform this action"})
+		c.Abort()
+	}
+}
+
+// RequireSecondFactor turns away players holding one of the given roles
+// unless their login passed two-factor authentication. It must run after
+// JWTMiddleware.
+func RequireSecondFactor(roles ...player.Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, exists := c.Get("player_claims")
+		role, _ := c.Get("player_role")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Player not authenticated"})
+			c.Abort()
+			return
+		}
+
+		for _, required := range roles {
+			if role == required && !claims.(*PlayerClaims).HasSecondFactor() {
+				c.JSON(http.StatusForbidden, gin.H{
+					"error": "Two-factor authentication is required for your role. Set it up under /auth/mfa/totp and log in again.",
+				})
+				c.Abort()
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
+
 func SessionMiddleware(jwtService *JWTService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		sessionToken := c.GetHeader("X-Session-Token")
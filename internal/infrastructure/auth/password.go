@@ -0,0 +1,45 @@
+package auth
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"haoma/internal/domain/player"
+)
+
+// PasswordHasherFromEnv builds the argon2id hasher, letting
+// PASSWORD_ARGON2_MEMORY_KIB, PASSWORD_ARGON2_ITERATIONS and
+// PASSWORD_ARGON2_PARALLELISM override the defaults. Existing hashes made
+// with other values are upgraded as their players log in.
+func PasswordHasherFromEnv() (*player.Argon2idHasher, error) {
+	params := player.DefaultArgon2idParams
+
+	settings := []struct {
+		name string
+		bits int
+		set  func(uint64)
+	}{
+		{"PASSWORD_ARGON2_MEMORY_KIB", 32, func(v uint64) { params.Memory = uint32(v) }},
+		{"PASSWORD_ARGON2_ITERATIONS", 32, func(v uint64) { params.Iterations = uint32(v) }},
+		{"PASSWORD_ARGON2_PARALLELISM", 8, func(v uint64) { params.Parallelism = uint8(v) }},
+	}
+
+	for _, setting := range settings {
+		value := os.Getenv(setting.name)
+		if value == "" {
+			continue
+		}
+		parsed, err := strconv.ParseUint(value, 10, setting.bits)
+		if err != nil || parsed == 0 {
+			return nil, fmt.Errorf("%s must be a positive integer, got %q", setting.name, value)
+		}
+		setting.set(parsed)
+	}
+
+	if params.Memory < 8*uint32(params.Parallelism) {
+		return nil, fmt.Errorf("PASSWORD_ARGON2_MEMORY_KIB must be at least 8 KiB per thread")
+	}
+
+	return player.NewArgon2idHasher(params), nil
+}
@@ -0,0 +1,63 @@
+package cache
+
+import (
+	"sync"
+	"time"
+
+	"haoma/internal/domain/event"
+)
+
+// EventStore is the persistent event registry the cache writes through to
+type EventStore interface {
+	Save(event *event.Event) error
+	Update(event *event.Event) error
+	FindCurrent() (*event.Event, error)
+}
+
+// EventCache remembers the current event for a short while, since every
+// leaderboard read needs to know whether the board is frozen
+type EventCache struct {
+	store     EventStore
+	ttl       time.Duration
+	mu        sync.Mutex
+	current   *event.Event
+	err       error
+	expiresAt time.Time
+}
+
+func NewEventCache(store EventStore, ttl time.Duration) *EventCache {
+	return &EventCache{store: store, ttl: ttl}
+}
+
+func (c *EventCache) Save(event *event.Event) error {
+	defer c.invalidate()
+	return c.store.Save(event)
+}
+
+func (c *EventCache) Update(event *event.Event) error {
+	defer c.invalidate()
+	return c.store.Update(event)
+}
+
+func (c *EventCache) FindCurrent() (*event.Event, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if time.Now().After(c.expiresAt) {
+		c.current, c.err = c.store.FindCurrent()
+		c.expiresAt = time.Now().Add(c.ttl)
+	}
+
+	if c.err != nil {
+		return nil, c.err
+	}
+
+	current := *c.current
+	return &current, nil
+}
+
+func (c *EventCache) invalidate() {
+	c.mu.Lock()
+	c.expiresAt = time.Time{}
+	c.mu.Unlock()
+}
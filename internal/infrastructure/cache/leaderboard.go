@@ -0,0 +1,308 @@
+package cache
+
+import (
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"haoma/internal/config"
+	"haoma/internal/domain/leaderboard"
+)
+
+// LeaderboardStore is the persistent leaderboard the cache writes through to
+type LeaderboardStore interface {
+	AddEntry(entry *leaderboard.Entry) error
+	UpsertEntry(entry *leaderboard.Entry) error
+	UpdatePlayer(playerID uuid.UUID, playerName string, guest bool) error
+	GetAll(tieBreakers []leaderboard.TieBreaker) ([]leaderboard.Entry, error)
+	SaveSnapshot(snapshot *leaderboard.Snapshot) error
+	CreateSnapshot(snapshot *leaderboard.Snapshot) (*leaderboard.Snapshot, error)
+	FindSnapshotByEvent(eventID uuid.UUID) (*leaderboard.Snapshot, error)
+}
+
+// LeaderboardCache keeps the full ranking in memory so reads never touch
+// the database. Writes go to the store first and are then applied to the
+// in-memory ranking incrementally.
+type LeaderboardCache struct {
+	store       LeaderboardStore
+	writeMu     sync.Mutex // Serializes writes with Rebuild, so a rebuild never drops one
+	mu          sync.RWMutex
+	ranking     []leaderboard.Entry
+	tieBreakers []leaderboard.TieBreaker
+	snapshots   map[uuid.UUID]*leaderboard.Snapshot
+}
+
+func NewLeaderboardCache(store LeaderboardStore) (*LeaderboardCache, error) {
+	cache := &LeaderboardCache{
+		store:       store,
+		tieBreakers: leaderboard.DefaultTieBreakers,
+		snapshots:   make(map[uuid.UUID]*leaderboard.Snapshot),
+	}
+
+	if _, err := cache.Rebuild(); err != nil {
+		return nil, err
+	}
+
+	return cache, nil
+}
+
+func (c *LeaderboardCache) AddEntry(entry *leaderboard.Entry) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if err := c.store.AddEntry(entry); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.insert(*entry)
+	return nil
+}
+
+func (c *LeaderboardCache) UpsertEntry(entry *leaderboard.Entry) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if err := c.store.UpsertEntry(entry); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	updated := *entry
+	if i := c.indexOfSession(entry.SessionID); i >= 0 {
+		// The store keeps the original row, only refreshing its score
+		existing := c.ranking[i]
+		c.ranking = append(c.ranking[:i], c.ranking[i+1:]...)
+		updated.ID = existing.ID
+		updated.PlayerName = existing.PlayerName
+		updated.Guest = existing.Guest
+		updated.AchievedAt = time.Now()
+	}
+
+	c.insert(updated)
+	return nil
+}
+
+func (c *LeaderboardCache) UpdatePlayer(playerID uuid.UUID, playerName string, guest bool) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if err := c.store.UpdatePlayer(playerID, playerName, guest); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i := range c.ranking {
+		if c.ranking[i].PlayerID == playerID {
+			c.ranking[i].PlayerName = playerName
+			c.ranking[i].Guest = guest
+		}
+	}
+	for _, snapshot := range c.snapshots {
+		snapshot.UpdatePlayer(playerID, playerName, guest)
+	}
+	return nil
+}
+
+func (c *LeaderboardCache) GetTop10(tieBreakers []leaderboard.TieBreaker) ([]leaderboard.Entry, error) {
+	c.rankBy(tieBreakers)
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	limit := len(c.ranking)
+	if limit > config.LEADERBOARD_TOP_ENTRIES {
+		limit = config.LEADERBOARD_TOP_ENTRIES
+	}
+
+	entries := make([]leaderboard.Entry, limit)
+	copy(entries, c.ranking[:limit])
+	return entries, nil
+}
+
+func (c *LeaderboardCache) GetAll(tieBreakers []leaderboard.TieBreaker) ([]leaderboard.Entry, error) {
+	c.rankBy(tieBreakers)
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	entries := make([]leaderboard.Entry, len(c.ranking))
+	copy(entries, c.ranking)
+	return entries, nil
+}
+
+func (c *LeaderboardCache) SaveSnapshot(snapshot *leaderboard.Snapshot) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if err := c.store.SaveSnapshot(snapshot); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.snapshots[snapshot.EventID] = copySnapshot(snapshot)
+	return nil
+}
+
+func (c *LeaderboardCache) CreateSnapshot(snapshot *leaderboard.Snapshot) (*leaderboard.Snapshot, error) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	stored, err := c.store.CreateSnapshot(snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.snapshots[stored.EventID] = copySnapshot(stored)
+	return stored, nil
+}
+
+func (c *LeaderboardCache) FindSnapshotByEvent(eventID uuid.UUID) (*leaderboard.Snapshot, error) {
+	c.mu.RLock()
+	cached, exists := c.snapshots[eventID]
+	c.mu.RUnlock()
+	if exists {
+		return copySnapshot(cached), nil
+	}
+
+	snapshot, err := c.store.FindSnapshotByEvent(eventID)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	if _, exists := c.snapshots[eventID]; !exists { // A write may have cached a newer one meanwhile
+		c.snapshots[eventID] = copySnapshot(snapshot)
+	}
+	c.mu.Unlock()
+
+	return snapshot, nil
+}
+
+// Rebuild compares the in-memory ranking with the database, replaces it with
+// the database ranking and returns how many entries had drifted. Writes wait
+// until it is done, so none lands between reading the database and replacing
+// the ranking. Reads carry on against the old ranking meanwhile. Frozen
+// snapshots are kept, since writes already keep them up to date.
+func (c *LeaderboardCache) Rebuild() (int, error) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	c.mu.RLock()
+	tieBreakers := c.tieBreakers
+	c.mu.RUnlock()
+
+	entries, err := c.store.GetAll(tieBreakers)
+	if err != nil {
+		return 0, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// The chain may have changed while the database was being read
+	leaderboard.Sort(entries, c.tieBreakers)
+
+	cached := make(map[uuid.UUID]leaderboard.Entry, len(c.ranking))
+	for _, entry := range c.ranking {
+		cached[entry.SessionID] = entry
+	}
+
+	drift := 0
+	for _, entry := range entries {
+		existing, exists := cached[entry.SessionID]
+		if !exists || existing.FinalScore != entry.FinalScore || existing.CompletionTime != entry.CompletionTime {
+			drift++
+		}
+		delete(cached, entry.SessionID)
+	}
+	drift += len(cached) // Entries only the cache knew about
+
+	c.ranking = entries
+
+	return drift, nil
+}
+
+// StartConsistencyChecks periodically rebuilds the cache from the database
+// and logs any drift it finds
+func (c *LeaderboardCache) StartConsistencyChecks(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			drift, err := c.Rebuild()
+			if err != nil {
+				log.Printf("Leaderboard consistency check failed: %v", err)
+				continue
+			}
+			if drift > 0 {
+				log.Printf("⚠️ Leaderboard cache drifted by %d entries, rebuilt from database", drift)
+			}
+		}
+	}()
+}
+
+// rankBy re-sorts the ranking when an event switches to a different tie-break chain
+func (c *LeaderboardCache) rankBy(tieBreakers []leaderboard.TieBreaker) {
+	c.mu.RLock()
+	unchanged := sameChain(c.tieBreakers, tieBreakers)
+	c.mu.RUnlock()
+	if unchanged {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.tieBreakers = tieBreakers
+	leaderboard.Sort(c.ranking, tieBreakers)
+}
+
+func sameChain(a, b []leaderboard.TieBreaker) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// insert places an entry at its ranked position; callers must hold the lock
+func (c *LeaderboardCache) insert(entry leaderboard.Entry) {
+	i := sort.Search(len(c.ranking), func(i int) bool {
+		return leaderboard.Less(entry, c.ranking[i], c.tieBreakers)
+	})
+
+	c.ranking = append(c.ranking, leaderboard.Entry{})
+	copy(c.ranking[i+1:], c.ranking[i:])
+	c.ranking[i] = entry
+}
+
+func (c *LeaderboardCache) indexOfSession(sessionID uuid.UUID) int {
+	for i, entry := range c.ranking {
+		if entry.SessionID == sessionID {
+			return i
+		}
+	}
+	return -1
+}
+
+func copySnapshot(snapshot *leaderboard.Snapshot) *leaderboard.Snapshot {
+	duplicate := *snapshot
+	duplicate.Entries = make(leaderboard.EntryList, len(snapshot.Entries))
+	copy(duplicate.Entries, snapshot.Entries)
+	return &duplicate
+}
@@ -0,0 +1,148 @@
+package cache
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"haoma/internal/domain/leaderboard"
+)
+
+type memoryStore struct {
+	entries map[uuid.UUID]leaderboard.Entry
+}
+
+func (s *memoryStore) AddEntry(entry *leaderboard.Entry) error {
+	s.entries[entry.SessionID] = *entry
+	return nil
+}
+
+func (s *memoryStore) UpsertEntry(entry *leaderboard.Entry) error {
+	s.entries[entry.SessionID] = *entry
+	return nil
+}
+
+func (s *memoryStore) UpdatePlayer(playerID uuid.UUID, playerName string, guest bool) error {
+	for sessionID, entry := range s.entries {
+		if entry.PlayerID == playerID {
+			entry.PlayerName = playerName
+			entry.Guest = guest
+			s.entries[sessionID] = entry
+		}
+	}
+	return nil
+}
+
+func (s *memoryStore) GetAll(tieBreakers []leaderboard.TieBreaker) ([]leaderboard.Entry, error) {
+	var entries []leaderboard.Entry
+	for _, entry := range s.entries {
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func (s *memoryStore) SaveSnapshot(snapshot *leaderboard.Snapshot) error {
+	return nil
+}
+
+func (s *memoryStore) CreateSnapshot(snapshot *leaderboard.Snapshot) (*leaderboard.Snapshot, error) {
+	return snapshot, nil
+}
+
+func (s *memoryStore) FindSnapshotByEvent(eventID uuid.UUID) (*leaderboard.Snapshot, error) {
+	return nil, errors.New("snapshot not found")
+}
+
+func TestLeaderboardCache_UpsertEntry(t *testing.T) {
+	store := &memoryStore{entries: make(map[uuid.UUID]leaderboard.Entry)}
+	lbCache, err := NewLeaderboardCache(store)
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+
+	rostam := leaderboard.NewEntry(uuid.New(), "Rostam", uuid.New(), 500, 10*time.Minute)
+	sohrab := leaderboard.NewEntry(uuid.New(), "Sohrab", uuid.New(), 400, 10*time.Minute)
+	lbCache.UpsertEntry(rostam)
+	lbCache.UpsertEntry(sohrab)
+
+	// Sohrab overtakes Rostam within the same session
+	lbCache.UpsertEntry(leaderboard.NewEntry(sohrab.PlayerID, "Sohrab", sohrab.SessionID, 900, 20*time.Minute))
+
+	entries, _ := lbCache.GetTop10(leaderboard.DefaultTieBreakers)
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(entries))
+	}
+	if entries[0].PlayerName != "Sohrab" || entries[0].FinalScore != 900 {
+		t.Errorf("Expected Sohrab with 900 on top, got %s with %d", entries[0].PlayerName, entries[0].FinalScore)
+	}
+	if entries[0].ID != sohrab.ID {
+		t.Errorf("Expected the original entry ID to be kept")
+	}
+}
+
+func TestLeaderboardCache_Rebuild(t *testing.T) {
+	store := &memoryStore{entries: make(map[uuid.UUID]leaderboard.Entry)}
+	lbCache, _ := NewLeaderboardCache(store)
+
+	lbCache.UpsertEntry(leaderboard.NewEntry(uuid.New(), "Rostam", uuid.New(), 500, time.Minute))
+
+	// Another writer updates the database behind the cache's back
+	missed := leaderboard.NewEntry(uuid.New(), "Tahmineh", uuid.New(), 700, time.Minute)
+	store.entries[missed.SessionID] = *missed
+
+	drift, err := lbCache.Rebuild()
+	if err != nil {
+		t.Fatalf("Rebuild failed: %v", err)
+	}
+	if drift != 1 {
+		t.Errorf("Expected drift of 1, got %d", drift)
+	}
+
+	entries, _ := lbCache.GetAll(leaderboard.DefaultTieBreakers)
+	if len(entries) != 2 || entries[0].PlayerName != "Tahmineh" {
+		t.Errorf("Expected Tahmineh on top after rebuild, got %+v", entries)
+	}
+}
+
+func TestLeaderboardCache_RebuildKeepsSnapshots(t *testing.T) {
+	store := &memoryStore{entries: make(map[uuid.UUID]leaderboard.Entry)}
+	lbCache, _ := NewLeaderboardCache(store)
+
+	snapshot := &leaderboard.Snapshot{ID: uuid.New(), EventID: uuid.New()}
+	if _, err := lbCache.CreateSnapshot(snapshot); err != nil {
+		t.Fatalf("CreateSnapshot failed: %v", err)
+	}
+
+	if _, err := lbCache.Rebuild(); err != nil {
+		t.Fatalf("Rebuild failed: %v", err)
+	}
+
+	// The store would not find it, so it must still come from the cache
+	if _, err := lbCache.FindSnapshotByEvent(snapshot.EventID); err != nil {
+		t.Errorf("Expected the snapshot to survive the rebuild, got %v", err)
+	}
+}
+
+func TestLeaderboardCache_UpdatePlayer(t *testing.T) {
+	store := &memoryStore{entries: make(map[uuid.UUID]leaderboard.Entry)}
+	lbCache, _ := NewLeaderboardCache(store)
+
+	guest := leaderboard.NewEntry(uuid.New(), "Simorgh", uuid.New(), 500, time.Minute)
+	guest.Guest = true
+	lbCache.UpsertEntry(guest)
+
+	// The guest claims their account under their real name
+	if err := lbCache.UpdatePlayer(guest.PlayerID, "Zal", false); err != nil {
+		t.Fatalf("UpdatePlayer failed: %v", err)
+	}
+
+	entries, _ := lbCache.GetAll(leaderboard.DefaultTieBreakers)
+	if entries[0].PlayerName != "Zal" || entries[0].Guest {
+		t.Errorf("Expected Zal without guest marker, got %s (guest %v)", entries[0].PlayerName, entries[0].Guest)
+	}
+	if stored := store.entries[guest.SessionID]; stored.PlayerName != "Zal" || stored.Guest {
+		t.Errorf("Expected the store to be updated, got %s (guest %v)", stored.PlayerName, stored.Guest)
+	}
+}
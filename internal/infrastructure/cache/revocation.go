@@ -0,0 +1,125 @@
+package cache
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"haoma/internal/domain/token"
+)
+
+// RevocationStore is the persistent revocation list the cache writes through to
+type RevocationStore interface {
+	SaveRevokedToken(revoked *token.RevokedToken) error
+	ListActiveRevokedTokens() ([]token.RevokedToken, error)
+	SetTokensValidAfter(playerID uuid.UUID, validAfter time.Time) error
+	ListTokensValidAfter() (map[uuid.UUID]time.Time, error)
+}
+
+// RevocationCache holds the whole revocation list in memory so the JWT
+// middleware can check every request without a database round trip. It is
+// reloaded periodically to pick up revocations made by other instances.
+type RevocationCache struct {
+	store      RevocationStore
+	mu         sync.RWMutex
+	revoked    map[string]time.Time // jti -> token expiry
+	validAfter map[uuid.UUID]time.Time
+}
+
+func NewRevocationCache(store RevocationStore) (*RevocationCache, error) {
+	cache := &RevocationCache{store: store}
+	if err := cache.Reload(); err != nil {
+		return nil, err
+	}
+	return cache, nil
+}
+
+func (c *RevocationCache) RevokeToken(revoked *token.RevokedToken) error {
+	if err := c.store.SaveRevokedToken(revoked); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.revoked[revoked.ID] = revoked.ExpiresAt
+	c.mu.Unlock()
+	return nil
+}
+
+// RevokeTokensIssuedBefore rejects every token the player received before
+// validAfter. JWT issue times have one-second precision, so the cutoff is
+// rounded up to the next whole second: a token from earlier in the same
+// second must not survive. Tokens issued afterwards are dated no earlier than
+// the cutoff (see ValidAfter), so they stay valid.
+func (c *RevocationCache) RevokeTokensIssuedBefore(playerID uuid.UUID, validAfter time.Time) error {
+	validAfter = validAfter.Add(time.Second - 1).Truncate(time.Second)
+	if err := c.store.SetTokensValidAfter(playerID, validAfter); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.validAfter[playerID] = validAfter
+	c.mu.Unlock()
+	return nil
+}
+
+// ValidAfter returns the player's cutoff; tokens dated before it are
+// rejected. It is zero if their tokens were never revoked wholesale.
+func (c *RevocationCache) ValidAfter(playerID uuid.UUID) time.Time {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.validAfter[playerID]
+}
+
+// IsRevoked reports whether a token was revoked on its own or predates its
+// player's cutoff
+func (c *RevocationCache) IsRevoked(tokenID string, playerID uuid.UUID, issuedAt time.Time) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if _, revoked := c.revoked[tokenID]; revoked {
+		return true
+	}
+
+	cutoff, exists := c.validAfter[playerID]
+	return exists && issuedAt.Before(cutoff)
+}
+
+func (c *RevocationCache) Reload() error {
+	revokedTokens, err := c.store.ListActiveRevokedTokens()
+	if err != nil {
+		return err
+	}
+
+	validAfter, err := c.store.ListTokensValidAfter()
+	if err != nil {
+		return err
+	}
+
+	revoked := make(map[string]time.Time, len(revokedTokens))
+	for _, revokedToken := range revokedTokens {
+		revoked[revokedToken.ID] = revokedToken.ExpiresAt
+	}
+
+	c.mu.Lock()
+	c.revoked = revoked
+	c.validAfter = validAfter
+	c.mu.Unlock()
+	return nil
+}
+
+// StartRefresh periodically reloads the revocation list, which also drops
+// revocations of tokens that have since expired
+func (c *RevocationCache) StartRefresh(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if err := c.Reload(); err != nil {
+				log.Printf("Revocation list refresh failed: %v", err)
+			}
+		}
+	}()
+}
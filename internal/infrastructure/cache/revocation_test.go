@@ -0,0 +1,60 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"haoma/internal/domain/token"
+)
+
+type memoryRevocationStore struct{}
+
+func (s *memoryRevocationStore) SaveRevokedToken(revoked *token.RevokedToken) error {
+	return nil
+}
+
+func (s *memoryRevocationStore) ListActiveRevokedTokens() ([]token.RevokedToken, error) {
+	return nil, nil
+}
+
+func (s *memoryRevocationStore) SetTokensValidAfter(playerID uuid.UUID, validAfter time.Time) error {
+	return nil
+}
+
+func (s *memoryRevocationStore) ListTokensValidAfter() (map[uuid.UUID]time.Time, error) {
+	return make(map[uuid.UUID]time.Time), nil
+}
+
+func TestRevocationCache_RevokeTokensIssuedBefore(t *testing.T) {
+	revocations, err := NewRevocationCache(&memoryRevocationStore{})
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+
+	playerID := uuid.New()
+	revokedAt := time.Date(2026, 3, 21, 12, 0, 0, 600*int(time.Millisecond), time.UTC)
+	if err := revocations.RevokeTokensIssuedBefore(playerID, revokedAt); err != nil {
+		t.Fatalf("RevokeTokensIssuedBefore failed: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		issuedAt time.Time
+		revoked  bool
+	}{
+		{"earlier second", revokedAt.Add(-time.Second).Truncate(time.Second), true},
+		{"same second, before the revocation", revokedAt.Truncate(time.Second), true},
+		{"dated at the cutoff", revocations.ValidAfter(playerID), false},
+		{"later second", revokedAt.Add(2 * time.Second).Truncate(time.Second), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := revocations.IsRevoked(uuid.New().String(), playerID, tt.issuedAt); got != tt.revoked {
+				t.Errorf("Expected revoked %v, got %v", tt.revoked, got)
+			}
+		})
+	}
+}
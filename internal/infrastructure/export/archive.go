@@ -0,0 +1,34 @@
+package export
+
+import (
+	"archive/zip"
+	"io"
+	"time"
+)
+
+// File is one document in a ZIP archive
+type File struct {
+	Name string
+	Data []byte
+}
+
+// WriteZip packs files into a ZIP archive in the given order
+func WriteZip(w io.Writer, files []File) error {
+	archive := zip.NewWriter(w)
+
+	for _, file := range files {
+		entry, err := archive.CreateHeader(&zip.FileHeader{
+			Name:     file.Name,
+			Method:   zip.Deflate,
+			Modified: time.Now(),
+		})
+		if err != nil {
+			return err
+		}
+		if _, err := entry.Write(file.Data); err != nil {
+			return err
+		}
+	}
+
+	return archive.Close()
+}
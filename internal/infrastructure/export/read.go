@@ -0,0 +1,35 @@
+package export
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Read parses an uploaded CSV file or the first sheet of an XLSX workbook
+// into rows of cells, header row included
+func Read(r io.Reader, format string) ([][]string, error) {
+	switch format {
+	case FormatCSV:
+		reader := csv.NewReader(r)
+		reader.FieldsPerRecord = -1 // Spreadsheet exports often drop trailing empty cells
+		reader.TrimLeadingSpace = true
+		return reader.ReadAll()
+	case FormatXLSX:
+		f, err := excelize.OpenReader(r)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		sheets := f.GetSheetList()
+		if len(sheets) == 0 {
+			return nil, errors.New("workbook has no sheets")
+		}
+		return f.GetRows(sheets[0])
+	default:
+		return nil, errors.New("unsupported import format")
+	}
+}
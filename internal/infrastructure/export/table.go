@@ -0,0 +1,87 @@
+package export
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/xuri/excelize/v2"
+)
+
+const (
+	FormatCSV  = "csv"
+	FormatXLSX = "xlsx"
+)
+
+// ContentType returns the MIME type for an export format
+func ContentType(format string) string {
+	if format == FormatXLSX {
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// Write renders a header row and data rows in the requested format
+func Write(w io.Writer, format, sheet string, headers []string, rows [][]interface{}) error {
+	switch format {
+	case FormatCSV:
+		return WriteCSV(w, headers, rows)
+	case FormatXLSX:
+		return WriteXLSX(w, sheet, headers, rows)
+	default:
+		return errors.New("unsupported export format")
+	}
+}
+
+func WriteCSV(w io.Writer, headers []string, rows [][]interface{}) error {
+	writer := csv.NewWriter(w)
+
+	if err := writer.Write(headers); err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		record := make([]string, len(row))
+		for i, value := range row {
+			record[i] = fmt.Sprint(value)
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+func WriteXLSX(w io.Writer, sheet string, headers []string, rows [][]interface{}) error {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	// New workbooks start with a default sheet we can simply rename
+	if err := f.SetSheetName(f.GetSheetName(0), sheet); err != nil {
+		return err
+	}
+
+	headerRow := make([]interface{}, len(headers))
+	for i, header := range headers {
+		headerRow[i] = header
+	}
+
+	if err := f.SetSheetRow(sheet, "A1", &headerRow); err != nil {
+		return err
+	}
+
+	for i, row := range rows {
+		cell, err := excelize.CoordinatesToCellName(1, i+2)
+		if err != nil {
+			return err
+		}
+		if err := f.SetSheetRow(sheet, cell, &row); err != nil {
+			return err
+		}
+	}
+
+	return f.Write(w)
+}
@@ -0,0 +1,98 @@
+// Package jobs runs slow work in the background so requests can poll for the
+// result instead of holding a connection open
+package jobs
+
+import (
+	"sync"
+	"time"
+)
+
+// Status is where a job is in its life
+type Status string
+
+const (
+	StatusPending Status = "pending" // Queued or running
+	StatusReady   Status = "ready"
+	StatusFailed  Status = "failed"
+)
+
+// Job is a snapshot of one piece of background work
+type Job struct {
+	Status     Status
+	Result     []byte
+	Err        error
+	StartedAt  time.Time
+	FinishedAt time.Time
+}
+
+// Runner runs at most a fixed number of jobs at once and keeps finished
+// results in memory for a while. Jobs are keyed, so asking for the same work
+// twice returns the job already under way. Results are lost on restart and
+// are not shared between instances.
+type Runner struct {
+	mu        sync.Mutex
+	jobs      map[string]*Job
+	slots     chan struct{}
+	retention time.Duration
+	now       func() time.Time
+}
+
+// NewRunner runs up to concurrency jobs at a time and forgets finished ones
+// after retention
+func NewRunner(concurrency int, retention time.Duration) *Runner {
+	return &Runner{
+		jobs:      make(map[string]*Job),
+		slots:     make(chan struct{}, concurrency),
+		retention: retention,
+		now:       time.Now,
+	}
+}
+
+// Start returns the job stored under key, starting work in the background
+// when there is none. A failed job is reported once and then forgotten, so
+// the next call tries again.
+func (r *Runner) Start(key string, work func() ([]byte, error)) Job {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sweep()
+
+	if job, exists := r.jobs[key]; exists {
+		if job.Status == StatusFailed {
+			delete(r.jobs, key)
+		}
+		return *job
+	}
+
+	job := &Job{Status: StatusPending, StartedAt: r.now()}
+	r.jobs[key] = job
+	go r.run(job, work)
+	return *job
+}
+
+func (r *Runner) run(job *Job, work func() ([]byte, error)) {
+	r.slots <- struct{}{}
+	defer func() { <-r.slots }()
+
+	result, err := work()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	job.FinishedAt = r.now()
+	if err != nil {
+		job.Status = StatusFailed
+		job.Err = err
+		return
+	}
+	job.Status = StatusReady
+	job.Result = result
+}
+
+// sweep drops finished jobs past their retention; callers must hold the lock
+func (r *Runner) sweep() {
+	cutoff := r.now().Add(-r.retention)
+	for key, job := range r.jobs {
+		if job.Status == StatusReady && job.FinishedAt.Before(cutoff) {
+			delete(r.jobs, key)
+		}
+	}
+}
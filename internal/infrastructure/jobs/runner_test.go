@@ -0,0 +1,74 @@
+package jobs
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// waitFor polls until the job under key leaves the pending state
+func waitFor(t *testing.T, runner *Runner, key string) Job {
+	t.Helper()
+	for i := 0; i < 100; i++ {
+		job := runner.Start(key, func() ([]byte, error) {
+			t.Fatal("Expected the running job to be reused")
+			return nil, nil
+		})
+		if job.Status != StatusPending {
+			return job
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("Job %s never finished", key)
+	return Job{}
+}
+
+func TestRunner_ReusesJobUntilRetentionEnds(t *testing.T) {
+	runner := NewRunner(1, time.Minute)
+	release := make(chan struct{})
+
+	job := runner.Start("rostam.json", func() ([]byte, error) {
+		<-release
+		return []byte("{}"), nil
+	})
+	if job.Status != StatusPending {
+		t.Errorf("Expected a new job to be pending, got %s", job.Status)
+	}
+
+	close(release)
+	job = waitFor(t, runner, "rostam.json")
+	if job.Status != StatusReady || string(job.Result) != "{}" {
+		t.Errorf("Expected the ready result, got %s %q", job.Status, job.Result)
+	}
+
+	// Once the result expires the work runs again
+	runner.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	restarted := false
+	runner.Start("rostam.json", func() ([]byte, error) {
+		restarted = true
+		return nil, nil
+	})
+	waitFor(t, runner, "rostam.json")
+	if !restarted {
+		t.Errorf("Expected an expired job to be started again")
+	}
+}
+
+func TestRunner_FailedJobIsRetried(t *testing.T) {
+	runner := NewRunner(1, time.Minute)
+
+	runner.Start("sohrab.zip", func() ([]byte, error) {
+		return nil, errors.New("database unavailable")
+	})
+	job := waitFor(t, runner, "sohrab.zip")
+	if job.Status != StatusFailed || job.Err == nil {
+		t.Fatalf("Expected a failed job with its error, got %s", job.Status)
+	}
+
+	job = runner.Start("sohrab.zip", func() ([]byte, error) {
+		return []byte("PK"), nil
+	})
+	if job.Status != StatusPending {
+		t.Errorf("Expected the failed job to be started again, got %s", job.Status)
+	}
+}
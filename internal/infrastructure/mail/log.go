@@ -0,0 +1,39 @@
+package mail
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// LogMailer writes mail to a log or file instead of sending it, for local
+// testing
+type LogMailer struct {
+	mu  sync.Mutex
+	out io.Writer
+}
+
+// NewLogMailer prints mail to stdout
+func NewLogMailer() *LogMailer {
+	return &LogMailer{out: os.Stdout}
+}
+
+// NewFileMailer appends mail to the given file
+func NewFileMailer(path string) (*LogMailer, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &LogMailer{out: file}, nil
+}
+
+func (m *LogMailer) Send(to, subject, body string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, err := fmt.Fprintf(m.out, "📧 %s\nTo: %s\nSubject: %s\n\n%s\n----\n",
+		time.Now().Format(time.RFC3339), to, subject, body)
+	return err
+}
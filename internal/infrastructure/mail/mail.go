@@ -0,0 +1,37 @@
+package mail
+
+import (
+	"os"
+)
+
+// Mailer sends plain-text email
+type Mailer interface {
+	Send(to, subject, body string) error
+}
+
+// NewFromEnv sends through SMTP when SMTP_HOST is set. Otherwise mail is
+// written to MAIL_LOG_FILE, or printed when that is unset too.
+func NewFromEnv() (Mailer, error) {
+	if host := os.Getenv("SMTP_HOST"); host != "" {
+		return NewSMTPMailer(
+			host,
+			getEnv("SMTP_PORT", "587"),
+			os.Getenv("SMTP_USERNAME"),
+			os.Getenv("SMTP_PASSWORD"),
+			getEnv("MAIL_FROM", "Haoma Carnival <no-reply@haoma.dev>"),
+		), nil
+	}
+
+	if path := os.Getenv("MAIL_LOG_FILE"); path != "" {
+		return NewFileMailer(path)
+	}
+
+	return NewLogMailer(), nil
+}
+
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
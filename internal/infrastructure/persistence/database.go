@@ -6,10 +6,14 @@ import (
 	"strconv"
 
 	"haoma/internal/config"
+	"haoma/internal/domain/audit"
+	"haoma/internal/domain/event"
 	"haoma/internal/domain/leaderboard"
+	"haoma/internal/domain/node"
 	"haoma/internal/domain/player"
 	"haoma/internal/domain/question"
 	"haoma/internal/domain/session"
+	"haoma/internal/domain/token"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -41,6 +45,11 @@ func NewDatabase() (*Database, error) {
 		return nil, fmt.Errorf("failed to enable UUID extension: %w", err)
 	}
 
+	// Players from before email verification existed never had the chance to
+	// verify; checked before migrating, since that adds the column
+	backfillVerified := db.Migrator().HasTable(&player.Player{}) &&
+		!db.Migrator().HasColumn(&player.Player{}, "EmailVerifiedAt")
+
 	err = db.AutoMigrate(
 		&session.Session{},
 		&question.Question{},
@@ -48,14 +57,62 @@ func NewDatabase() (*Database, error) {
 		&player.Player{},
 		&player.Attempt{},
 		&leaderboard.Entry{},
+		&leaderboard.Snapshot{},
+		&event.Event{},
+		&token.RefreshToken{},
+		&token.RevokedToken{},
+		&token.PasswordResetToken{},
+		&token.EmailVerificationToken{},
+		&token.RecoveryCode{},
+		&audit.LoginFailure{},
+		&audit.ScanFlag{},
+		&node.Node{},
 	)
 	if err != nil {
 		return nil, err
 	}
 
+	if backfillVerified {
+		if err := markExistingPlayersVerified(db); err != nil {
+			return nil, fmt.Errorf("failed to mark existing players verified: %w", err)
+		}
+	}
+
+	if err := seedNodes(db); err != nil {
+		return nil, fmt.Errorf("failed to register the default nodes: %w", err)
+	}
+
 	return &Database{DB: db}, nil
 }
 
+// seedNodes fills an empty node registry with the classic carnival's nodes
+// and their static codes (NODE_001 and so on), so a fresh install works
+// before organizers change anything
+func seedNodes(db *gorm.DB) error {
+	var count int64
+	if err := db.Model(&node.Node{}).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	nodes := make([]*node.Node, 0, config.DEFAULT_NODE_COUNT)
+	for number := 1; number <= config.DEFAULT_NODE_COUNT; number++ {
+		nodes = append(nodes, node.NewNode(number, "", ""))
+	}
+	return db.Create(nodes).Error
+}
+
+// markExistingPlayersVerified treats every account that predates email
+// verification as verified, so turning on REQUIRE_EMAIL_VERIFICATION does not
+// lock them out. It runs once, when the column is added.
+func markExistingPlayersVerified(db *gorm.DB) error {
+	return db.Model(&player.Player{}).
+		Where("email_verified_at IS NULL").
+		UpdateColumn("email_verified_at", gorm.Expr("created_at")).Error
+}
+
 func (d *Database) Close() error {
 	sqlDB, err := d.DB.DB()
 	if err != nil {
@@ -70,7 +127,3 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
-
-func GetJWTSecret() string {
-	return getEnv("JWT_SECRET", "super_secret_key")
-}
@@ -5,15 +5,24 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	"haoma/internal/config"
+	"haoma/internal/domain/audit"
+	"haoma/internal/domain/event"
 	"haoma/internal/domain/leaderboard"
+	"haoma/internal/domain/node"
 	"haoma/internal/domain/player"
 	"haoma/internal/domain/question"
 	"haoma/internal/domain/session"
+	"haoma/internal/domain/token"
 )
 
+// uniqueViolation is the PostgreSQL error code for a unique index conflict
+const uniqueViolation = "23505"
+
 // SessionRepository implements session persistence
 type SessionRepository struct {
 	db *gorm.DB
@@ -40,6 +49,12 @@ func (r *SessionRepository) Update(session *session.Session) error {
 	return r.db.Save(session).Error
 }
 
+func (r *SessionRepository) FindByPlayer(playerID uuid.UUID) ([]session.Session, error) {
+	var sessions []session.Session
+	err := r.db.Where("player_id = ?", playerID).Order("started_at ASC").Find(&sessions).Error
+	return sessions, err
+}
+
 // QuestionRepository implements question persistence
 type QuestionRepository struct {
 	db *gorm.DB
@@ -86,6 +101,15 @@ func (r *QuestionRepository) FindByID(id uuid.UUID) (*question.Question, error)
 	return &foundQuestion, err
 }
 
+func (r *QuestionRepository) FindByIDs(ids []uuid.UUID) ([]question.Question, error) {
+	var questions []question.Question
+	if len(ids) == 0 {
+		return questions, nil
+	}
+	err := r.db.Preload("Category").Where("id IN ?", ids).Find(&questions).Error
+	return questions, err
+}
+
 // PlayerRepository implements player persistence
 type PlayerRepository struct {
 	db *gorm.DB
@@ -99,6 +123,84 @@ func (r *PlayerRepository) Save(player *player.Player) error {
 	return r.db.Create(player).Error
 }
 
+func (r *PlayerRepository) FindByStudentID(studentID string) (*player.Player, error) {
+	var foundPlayer player.Player
+	err := r.db.Where("student_id = ?", studentID).First(&foundPlayer).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("player not found")
+	}
+	return &foundPlayer, err
+}
+
+func (r *PlayerRepository) FindByActivationCodeHash(codeHash string) (*player.Player, error) {
+	var foundPlayer player.Player
+	err := r.db.Where("activation_code_hash = ?", codeHash).First(&foundPlayer).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("player not found")
+	}
+	return &foundPlayer, err
+}
+
+// GetInvited returns rostered players who have not activated their account yet
+func (r *PlayerRepository) GetInvited() ([]player.Player, error) {
+	var players []player.Player
+	err := r.db.Where("invited_at IS NOT NULL").Order("student_id").Find(&players).Error
+	return players, err
+}
+
+func (r *PlayerRepository) FindByOIDCIdentity(issuer, subject string) (*player.Player, error) {
+	var foundPlayer player.Player
+	err := r.db.Where("oidc_issuer = ? AND oidc_subject = ?", issuer, subject).First(&foundPlayer).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("player not found")
+	}
+	return &foundPlayer, err
+}
+
+func (r *PlayerRepository) Update(player *player.Player) error {
+	return r.db.Save(player).Error
+}
+
+// ReplacePasswordHash swaps the stored hash without touching the rest of the
+// row. A password changed since oldHash was read is left alone.
+func (r *PlayerRepository) ReplacePasswordHash(playerID uuid.UUID, oldHash, newHash string) error {
+	return r.db.Model(&player.Player{}).
+		Where("id = ? AND password_hash = ?", playerID, oldHash).
+		Update("password_hash", newHash).Error
+}
+
+// ClaimInvitation stores an activated invitation, but only while the account
+// is still invited (and, given a code hash, still has that code), so two
+// claims racing each other cannot both win
+func (r *PlayerRepository) ClaimInvitation(player *player.Player, activationCodeHash *string) error {
+	query := r.db.Model(player).Where("invited_at IS NOT NULL")
+	if activationCodeHash != nil {
+		query = query.Where("activation_code_hash = ?", *activationCodeHash)
+	}
+
+	result := query.
+		Select("password_hash", "invited_at", "activation_code_hash", "email_verified_at", "email_proven_at", "updated_at").
+		Updates(player)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("invitation not found")
+	}
+	return nil
+}
+
+// Delete saves the player's final (anonymized) state and soft-deletes the
+// row, so lookups no longer find it while sessions keep their reference
+func (r *PlayerRepository) Delete(player *player.Player) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(player).Error; err != nil {
+			return err
+		}
+		return tx.Delete(player).Error
+	})
+}
+
 func (r *PlayerRepository) FindByID(id uuid.UUID) (*player.Player, error) {
 	var foundPlayer player.Player
 	err := r.db.First(&foundPlayer, "id = ?", id).Error
@@ -108,9 +210,11 @@ func (r *PlayerRepository) FindByID(id uuid.UUID) (*player.Player, error) {
 	return &foundPlayer, err
 }
 
+// FindByEmail ignores case, so accounts stored before emails were normalized
+// are still found
 func (r *PlayerRepository) FindByEmail(email string) (*player.Player, error) {
 	var foundPlayer player.Player
-	err := r.db.Where("email = ?", email).First(&foundPlayer).Error
+	err := r.db.Where("LOWER(email) = LOWER(?)", email).First(&foundPlayer).Error
 	if errors.Is(err, gorm.ErrRecordNotFound) {
 		return nil, errors.New("player not found")
 	}
@@ -121,6 +225,14 @@ func (r *PlayerRepository) SaveAttempt(attempt *player.Attempt) error {
 	return r.db.Create(attempt).Error
 }
 
+func (r *PlayerRepository) GetAttemptsBySession(sessionID uuid.UUID) ([]player.Attempt, error) {
+	var attempts []player.Attempt
+	err := r.db.Where("session_id = ?", sessionID).
+		Order("attempt_at ASC").
+		Find(&attempts).Error
+	return attempts, err
+}
+
 func (r *PlayerRepository) GetAttemptsBySessionAndCategory(sessionID, categoryID uuid.UUID) ([]player.Attempt, error) {
 	var attempts []player.Attempt
 	err := r.db.Joins("JOIN questions ON attempts.question_id = questions.id").
@@ -157,6 +269,9 @@ func (r *LeaderboardRepository) UpsertEntry(entry *leaderboard.Entry) error {
 	if err == nil {
 		existingEntry.FinalScore = entry.FinalScore
 		existingEntry.CompletionTime = entry.CompletionTime
+		existingEntry.TimePenalty = entry.TimePenalty
+		existingEntry.NodesCompleted = entry.NodesCompleted
+		existingEntry.PhDTAccuracy = entry.PhDTAccuracy
 		existingEntry.AchievedAt = time.Now()
 		return r.db.Save(&existingEntry).Error
 	} else if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -166,10 +281,449 @@ func (r *LeaderboardRepository) UpsertEntry(entry *leaderboard.Entry) error {
 	}
 }
 
-func (r *LeaderboardRepository) GetTop10() ([]leaderboard.Entry, error) {
+// UpdatePlayer refreshes the name and guest marker on every entry of a
+// player, including frozen snapshots
+func (r *LeaderboardRepository) UpdatePlayer(playerID uuid.UUID, playerName string, guest bool) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&leaderboard.Entry{}).
+			Where("player_id = ?", playerID).
+			Updates(map[string]interface{}{"player_name": playerName, "guest": guest}).Error
+		if err != nil {
+			return err
+		}
+
+		var snapshots []leaderboard.Snapshot
+		if err := tx.Find(&snapshots).Error; err != nil {
+			return err
+		}
+		for i := range snapshots {
+			if snapshots[i].UpdatePlayer(playerID, playerName, guest) {
+				if err := tx.Save(&snapshots[i]).Error; err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+func (r *LeaderboardRepository) GetTop10(tieBreakers []leaderboard.TieBreaker) ([]leaderboard.Entry, error) {
 	var entries []leaderboard.Entry
-	err := r.db.Order("final_score DESC, completion_time ASC").
+	err := r.db.Order(leaderboard.OrderClause(tieBreakers)).
 		Limit(config.LEADERBOARD_TOP_ENTRIES).
 		Find(&entries).Error
 	return entries, err
 }
+
+func (r *LeaderboardRepository) GetAll(tieBreakers []leaderboard.TieBreaker) ([]leaderboard.Entry, error) {
+	var entries []leaderboard.Entry
+	err := r.db.Order(leaderboard.OrderClause(tieBreakers)).
+		Find(&entries).Error
+	return entries, err
+}
+
+func (r *LeaderboardRepository) SaveSnapshot(snapshot *leaderboard.Snapshot) error {
+	return r.db.Save(snapshot).Error
+}
+
+// CreateSnapshot stores an event's first freeze snapshot and returns the
+// stored one. When another request took it first, theirs is kept and
+// returned, so every reader sees the same frozen board.
+func (r *LeaderboardRepository) CreateSnapshot(snapshot *leaderboard.Snapshot) (*leaderboard.Snapshot, error) {
+	err := r.db.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "event_id"}}, DoNothing: true}).
+		Create(snapshot).Error
+	if err != nil {
+		return nil, err
+	}
+	return r.FindSnapshotByEvent(snapshot.EventID)
+}
+
+func (r *LeaderboardRepository) FindSnapshotByEvent(eventID uuid.UUID) (*leaderboard.Snapshot, error) {
+	var snapshot leaderboard.Snapshot
+	err := r.db.Where("event_id = ?", eventID).First(&snapshot).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("snapshot not found")
+	}
+	return &snapshot, err
+}
+
+// EventRepository implements event persistence
+type EventRepository struct {
+	db *gorm.DB
+}
+
+func NewEventRepository(db *gorm.DB) *EventRepository {
+	return &EventRepository{db: db}
+}
+
+func (r *EventRepository) Save(event *event.Event) error {
+	return r.db.Create(event).Error
+}
+
+func (r *EventRepository) Update(event *event.Event) error {
+	return r.db.Save(event).Error
+}
+
+// FindCurrent returns the most recently started event
+func (r *EventRepository) FindCurrent() (*event.Event, error) {
+	var currentEvent event.Event
+	err := r.db.Where("starts_at <= ?", time.Now()).
+		Order("starts_at DESC").
+		First(&currentEvent).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("event not found")
+	}
+	return &currentEvent, err
+}
+
+// NodeRepository implements persistence for the node registry
+type NodeRepository struct {
+	db *gorm.DB
+}
+
+func NewNodeRepository(db *gorm.DB) *NodeRepository {
+	return &NodeRepository{db: db}
+}
+
+func (r *NodeRepository) Save(n *node.Node) error {
+	return nodeConstraintError(r.db.Create(n).Error)
+}
+
+func (r *NodeRepository) Update(n *node.Node) error {
+	return nodeConstraintError(r.db.Save(n).Error)
+}
+
+func (r *NodeRepository) Delete(n *node.Node) error {
+	return r.db.Delete(n).Error
+}
+
+func (r *NodeRepository) FindByID(id uuid.UUID) (*node.Node, error) {
+	return r.findOne("id = ?", id)
+}
+
+func (r *NodeRepository) FindByNumber(number int) (*node.Node, error) {
+	return r.findOne("number = ?", number)
+}
+
+func (r *NodeRepository) FindByCode(code string) (*node.Node, error) {
+	return r.findOne("code = ?", code)
+}
+
+// GetAll returns every registered node, active or not, by number
+func (r *NodeRepository) GetAll() ([]node.Node, error) {
+	var nodes []node.Node
+	err := r.db.Order("number").Find(&nodes).Error
+	return nodes, err
+}
+
+func (r *NodeRepository) findOne(query string, value interface{}) (*node.Node, error) {
+	var n node.Node
+	err := r.db.Where(query, value).First(&n).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("node not found")
+	}
+	return &n, err
+}
+
+// nodeConstraintError reports a node that lost a race for its number or code
+// the same way the service's own check does
+func nodeConstraintError(err error) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Code != uniqueViolation {
+		return err
+	}
+
+	switch pgErr.ConstraintName {
+	case "idx_nodes_number":
+		return errors.New("node number taken")
+	case "idx_nodes_code":
+		return errors.New("node code taken")
+	default:
+		return err
+	}
+}
+
+// TokenRepository implements persistence for refresh, revoked and emailed tokens
+type TokenRepository struct {
+	db *gorm.DB
+}
+
+func NewTokenRepository(db *gorm.DB) *TokenRepository {
+	return &TokenRepository{db: db}
+}
+
+func (r *TokenRepository) SaveRefreshToken(refresh *token.RefreshToken) error {
+	return r.db.Create(refresh).Error
+}
+
+func (r *TokenRepository) FindRefreshTokenByHash(tokenHash string) (*token.RefreshToken, error) {
+	var refresh token.RefreshToken
+	err := r.db.Where("token_hash = ?", tokenHash).First(&refresh).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("refresh token not found")
+	}
+	return &refresh, err
+}
+
+// RotateRefreshToken marks a refresh token as exchanged. Only one caller can
+// do so; the others get an error, as if the token had been rotated before.
+func (r *TokenRepository) RotateRefreshToken(refresh *token.RefreshToken) error {
+	now := time.Now()
+	result := r.db.Model(&token.RefreshToken{}).
+		Where("id = ? AND rotated_at IS NULL AND revoked_at IS NULL", refresh.ID).
+		Update("rotated_at", now)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("refresh token already rotated")
+	}
+
+	refresh.RotatedAt = &now
+	return nil
+}
+
+func (r *TokenRepository) RevokeRefreshFamily(familyID uuid.UUID) error {
+	return r.db.Model(&token.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (r *TokenRepository) RevokePlayerRefreshTokens(playerID uuid.UUID) error {
+	return r.db.Model(&token.RefreshToken{}).
+		Where("player_id = ? AND revoked_at IS NULL", playerID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// DeletePlayerTokens removes every refresh and emailed token a player holds
+func (r *TokenRepository) DeletePlayerTokens(playerID uuid.UUID) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for _, model := range []interface{}{&token.RefreshToken{}, &token.PasswordResetToken{}, &token.EmailVerificationToken{}, &token.RecoveryCode{}} {
+			if err := tx.Where("player_id = ?", playerID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *TokenRepository) SavePasswordResetToken(reset *token.PasswordResetToken) error {
+	return r.db.Create(reset).Error
+}
+
+func (r *TokenRepository) FindPasswordResetTokenByHash(tokenHash string) (*token.PasswordResetToken, error) {
+	var reset token.PasswordResetToken
+	err := r.db.Where("token_hash = ?", tokenHash).First(&reset).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("password reset token not found")
+	}
+	return &reset, err
+}
+
+// UsePasswordResetToken spends a reset token so it cannot be used again
+func (r *TokenRepository) UsePasswordResetToken(reset *token.PasswordResetToken) error {
+	usedAt, err := r.useOnce(&token.PasswordResetToken{}, reset.ID)
+	if err != nil {
+		return err
+	}
+
+	reset.UsedAt = usedAt
+	return nil
+}
+
+// UsePlayerPasswordResetTokens spends every outstanding reset link a player
+// has, so only one of them can ever change the password
+func (r *TokenRepository) UsePlayerPasswordResetTokens(playerID uuid.UUID) error {
+	return r.db.Model(&token.PasswordResetToken{}).
+		Where("player_id = ? AND used_at IS NULL", playerID).
+		Update("used_at", time.Now()).Error
+}
+
+func (r *TokenRepository) SaveEmailVerificationToken(verification *token.EmailVerificationToken) error {
+	return r.db.Create(verification).Error
+}
+
+func (r *TokenRepository) FindEmailVerificationTokenByHash(tokenHash string) (*token.EmailVerificationToken, error) {
+	var verification token.EmailVerificationToken
+	err := r.db.Where("token_hash = ?", tokenHash).First(&verification).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("email verification token not found")
+	}
+	return &verification, err
+}
+
+// UseEmailVerificationToken spends a verification token so it cannot be used again
+func (r *TokenRepository) UseEmailVerificationToken(verification *token.EmailVerificationToken) error {
+	usedAt, err := r.useOnce(&token.EmailVerificationToken{}, verification.ID)
+	if err != nil {
+		return err
+	}
+
+	verification.UsedAt = usedAt
+	return nil
+}
+
+// ReplaceRecoveryCodes swaps the player's recovery codes for a new set, so
+// codes from an earlier set stop working
+func (r *TokenRepository) ReplaceRecoveryCodes(playerID uuid.UUID, codes []*token.RecoveryCode) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("player_id = ?", playerID).Delete(&token.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		if len(codes) == 0 {
+			return nil
+		}
+		return tx.Create(codes).Error
+	})
+}
+
+// UseRecoveryCode spends one of the player's unused recovery codes
+func (r *TokenRepository) UseRecoveryCode(playerID uuid.UUID, codeHash string) error {
+	result := r.db.Model(&token.RecoveryCode{}).
+		Where("player_id = ? AND code_hash = ? AND used_at IS NULL", playerID, codeHash).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("recovery code not found")
+	}
+	return nil
+}
+
+func (r *TokenRepository) CountUnusedRecoveryCodes(playerID uuid.UUID) (int, error) {
+	var count int64
+	err := r.db.Model(&token.RecoveryCode{}).
+		Where("player_id = ? AND used_at IS NULL", playerID).
+		Count(&count).Error
+	return int(count), err
+}
+
+// useOnce stamps a single-use token as used. The conditional update makes
+// sure two concurrent requests cannot both spend it.
+func (r *TokenRepository) useOnce(model interface{}, id uuid.UUID) (*time.Time, error) {
+	now := time.Now()
+	result := r.db.Model(model).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", now)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, errors.New("token already used")
+	}
+
+	return &now, nil
+}
+
+func (r *TokenRepository) SaveRevokedToken(revoked *token.RevokedToken) error {
+	return r.db.Save(revoked).Error
+}
+
+// ListActiveRevokedTokens returns revocations for tokens that have not expired yet
+func (r *TokenRepository) ListActiveRevokedTokens() ([]token.RevokedToken, error) {
+	var revoked []token.RevokedToken
+	err := r.db.Where("expires_at > ?", time.Now()).Find(&revoked).Error
+	return revoked, err
+}
+
+func (r *TokenRepository) SetTokensValidAfter(playerID uuid.UUID, validAfter time.Time) error {
+	return r.db.Model(&player.Player{}).
+		Where("id = ?", playerID).
+		Update("tokens_valid_after", validAfter).Error
+}
+
+func (r *TokenRepository) ListTokensValidAfter() (map[uuid.UUID]time.Time, error) {
+	// Deleted players are included; their last tokens must stay rejected
+	var players []player.Player
+	err := r.db.Unscoped().Select("id", "tokens_valid_after").
+		Where("tokens_valid_after IS NOT NULL").
+		Find(&players).Error
+	if err != nil {
+		return nil, err
+	}
+
+	validAfter := make(map[uuid.UUID]time.Time, len(players))
+	for _, p := range players {
+		validAfter[p.ID] = *p.TokensValidAfter
+	}
+	return validAfter, nil
+}
+
+// AuditRepository implements security audit trail persistence
+type AuditRepository struct {
+	db *gorm.DB
+}
+
+func NewAuditRepository(db *gorm.DB) *AuditRepository {
+	return &AuditRepository{db: db}
+}
+
+func (r *AuditRepository) SaveLoginFailure(failure *audit.LoginFailure) error {
+	return r.db.Create(failure).Error
+}
+
+// DeleteLoginFailures removes the failed logins for an account or its email
+func (r *AuditRepository) DeleteLoginFailures(playerID uuid.UUID, email string) error {
+	return r.db.Where("player_id = ? OR LOWER(email) = LOWER(?)", playerID, email).
+		Delete(&audit.LoginFailure{}).Error
+}
+
+// SaveScanFlagOnce records a flagged scan unless the player already has one
+// for the same session and node with the same outcome, so scanning a node
+// again while answering it does not pile up flags
+func (r *AuditRepository) SaveScanFlagOnce(flag *audit.ScanFlag) error {
+	query := r.db.Model(&audit.ScanFlag{}).
+		Where("player_id = ? AND node_number = ? AND rejected = ?", flag.PlayerID, flag.NodeNumber, flag.Rejected)
+	if flag.SessionID != nil {
+		query = query.Where("session_id = ?", *flag.SessionID)
+	} else {
+		query = query.Where("session_id IS NULL")
+	}
+
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	return r.db.Create(flag).Error
+}
+
+func (r *AuditRepository) UpdateScanFlag(flag *audit.ScanFlag) error {
+	return r.db.Save(flag).Error
+}
+
+func (r *AuditRepository) FindScanFlag(id uuid.UUID) (*audit.ScanFlag, error) {
+	var flag audit.ScanFlag
+	err := r.db.Where("id = ?", id).First(&flag).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("scan flag not found")
+	}
+	return &flag, err
+}
+
+// GetScanFlags returns flagged scans, newest first, optionally only those
+// nobody has reviewed yet
+func (r *AuditRepository) GetScanFlags(pendingOnly bool) ([]audit.ScanFlag, error) {
+	query := r.db.Order("scanned_at DESC")
+	if pendingOnly {
+		query = query.Where("reviewed_at IS NULL")
+	}
+
+	var flags []audit.ScanFlag
+	err := query.Find(&flags).Error
+	return flags, err
+}
+
+func (r *AuditRepository) GetPlayerScanFlags(playerID uuid.UUID) ([]audit.ScanFlag, error) {
+	var flags []audit.ScanFlag
+	err := r.db.Where("player_id = ?", playerID).Order("scanned_at").Find(&flags).Error
+	return flags, err
+}
+
+// DeleteScanFlags removes a player's flagged scans, locations included
+func (r *AuditRepository) DeleteScanFlags(playerID uuid.UUID) error {
+	return r.db.Where("player_id = ?", playerID).Delete(&audit.ScanFlag{}).Error
+}
@@ -0,0 +1,52 @@
+package qr
+
+import (
+	"bytes"
+	"fmt"
+
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// PNG renders content as a square QR code image, size pixels wide
+func PNG(content string, size int) ([]byte, error) {
+	return qrcode.Encode(content, qrcode.Medium, size)
+}
+
+// SVG renders content as a QR code that scales to any print size, one unit
+// per module
+func SVG(content string) ([]byte, error) {
+	modules, err := Modules(content)
+	if err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" shape-rendering="crispEdges">`, len(modules), len(modules))
+	fmt.Fprintf(&out, `<rect width="%d" height="%d" fill="#fff"/><path fill="#000" d="`, len(modules), len(modules))
+	for y, row := range modules {
+		for x := 0; x < len(row); x++ {
+			if !row[x] {
+				continue
+			}
+			// One rectangle per run of dark modules keeps the file small
+			run := 1
+			for x+run < len(row) && row[x+run] {
+				run++
+			}
+			fmt.Fprintf(&out, "M%d %dh%dv1h-%dz", x, y, run, run)
+			x += run - 1
+		}
+	}
+	out.WriteString(`"/></svg>`)
+	return out.Bytes(), nil
+}
+
+// Modules returns the QR code for content as rows of dark (true) and light
+// modules, quiet zone included
+func Modules(content string) ([][]bool, error) {
+	code, err := qrcode.New(content, qrcode.Medium)
+	if err != nil {
+		return nil, err
+	}
+	return code.Bitmap(), nil
+}
@@ -0,0 +1,82 @@
+package qr
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"haoma/internal/domain/nodecode"
+	"haoma/internal/infrastructure/export"
+)
+
+// Station output formats
+const (
+	FormatPDF = "pdf"
+	FormatPNG = "png"
+	FormatSVG = "svg"
+
+	printSize = 1024 // PNG pixels, enough for a full-page print
+)
+
+// ContentType returns the MIME type for what WriteStations produces
+func ContentType(format string, stations int) string {
+	if stations > 1 && format != FormatPDF {
+		return "application/zip"
+	}
+	switch format {
+	case FormatPDF:
+		return "application/pdf"
+	case FormatSVG:
+		return "image/svg+xml"
+	default:
+		return "image/png"
+	}
+}
+
+// Extension returns the file extension for what WriteStations produces
+func Extension(format string, stations int) string {
+	if stations > 1 && format != FormatPDF {
+		return "zip"
+	}
+	return format
+}
+
+// WriteStations writes printable station material: a PDF with one sheet per
+// station, or the bare QR code images, zipped when there are several.
+// Stations that show their code on a screen have no image to print.
+func WriteStations(w io.Writer, format string, stations []nodecode.Station) error {
+	if format == FormatPDF {
+		return WriteSheets(w, stations)
+	}
+	if format != FormatPNG && format != FormatSVG {
+		return errors.New("unsupported station format")
+	}
+
+	var files []export.File
+	for _, station := range stations {
+		if station.UsesScreen() {
+			continue
+		}
+
+		var data []byte
+		var err error
+		if format == FormatSVG {
+			data, err = SVG(station.Code)
+		} else {
+			data, err = PNG(station.Code, printSize)
+		}
+		if err != nil {
+			return fmt.Errorf("node %d: %w", station.Node, err)
+		}
+		files = append(files, export.File{Name: fmt.Sprintf("node-%d.%s", station.Node, format), Data: data})
+	}
+
+	if len(files) == 0 {
+		return errors.New("no printed codes to render")
+	}
+	if len(stations) == 1 {
+		_, err := w.Write(files[0].Data)
+		return err
+	}
+	return export.WriteZip(w, files)
+}
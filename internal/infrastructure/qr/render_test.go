@@ -0,0 +1,42 @@
+package qr
+
+import (
+	"bytes"
+	"testing"
+
+	"haoma/internal/domain/nodecode"
+)
+
+func TestWriteStations(t *testing.T) {
+	printed := nodecode.Station{Node: 1, Title: "Node 1", Theme: "Haoma Carnival", Code: "NODE_001"}
+	other := nodecode.Station{Node: 2, Title: "Node 2", Theme: "Haoma Carnival", Code: "NODE_002"}
+	screen := nodecode.Station{Node: 3, Title: "Node 3", Theme: "Haoma Carnival"}
+
+	tests := []struct {
+		name     string
+		format   string
+		stations []nodecode.Station
+		prefix   string
+		wantErr  bool
+	}{
+		{"sheets", FormatPDF, []nodecode.Station{printed, screen}, "%PDF", false},
+		{"one png", FormatPNG, []nodecode.Station{printed}, "\x89PNG", false},
+		{"one svg", FormatSVG, []nodecode.Station{printed}, "<svg", false},
+		{"several zipped", FormatSVG, []nodecode.Station{printed, other, screen}, "PK", false},
+		{"only a screen", FormatPNG, []nodecode.Station{screen}, "", true},
+		{"unknown format", "gif", []nodecode.Station{printed}, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := WriteStations(&buf, tt.format, tt.stations)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			if !bytes.HasPrefix(buf.Bytes(), []byte(tt.prefix)) {
+				t.Errorf("Expected output starting with %q, got %q", tt.prefix, buf.Bytes()[:min(8, buf.Len())])
+			}
+		})
+	}
+}
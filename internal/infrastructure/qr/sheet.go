@@ -0,0 +1,87 @@
+package qr
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/jung-kurt/gofpdf"
+
+	"haoma/internal/domain/nodecode"
+)
+
+// A4 portrait, in millimetres
+const (
+	pageWidth = 210.0
+	margin    = 20.0
+	qrSize    = 130.0 // Large enough to scan from a couple of metres
+)
+
+// WriteSheets writes a printable PDF with one page per station: node
+// number and name, theme, QR code and instructions. The QR code is drawn as vector
+// shapes, so it stays sharp at any print size.
+func WriteSheets(w io.Writer, stations []nodecode.Station) error {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(margin, margin, margin)
+	pdf.SetAutoPageBreak(false, margin)
+	text := pdf.UnicodeTranslatorFromDescriptor("") // Core fonts only cover cp1252
+	width := pageWidth - 2*margin
+
+	for _, station := range stations {
+		pdf.AddPage()
+
+		pdf.SetFont("Helvetica", "B", 44)
+		pdf.CellFormat(width, 22, text(station.Title), "", 1, "C", false, 0, "")
+		if station.Name != "" && station.Name != station.Title {
+			pdf.SetFont("Helvetica", "B", 24)
+			pdf.CellFormat(width, 14, text(station.Name), "", 1, "C", false, 0, "")
+		}
+		pdf.SetFont("Helvetica", "", 18)
+		pdf.CellFormat(width, 10, text(station.Theme), "", 1, "C", false, 0, "")
+		pdf.Ln(8)
+
+		if station.UsesScreen() {
+			pdf.SetFont("Helvetica", "", 18)
+			pdf.MultiCell(width, 10, text(fmt.Sprintf(
+				"Scan the code on the station screen to start %s. It changes every few seconds, so a photo of it will not work later.",
+				station.Title,
+			)), "", "C", false)
+			continue
+		}
+
+		modules, err := Modules(station.Code)
+		if err != nil {
+			return fmt.Errorf("node %d: %w", station.Node, err)
+		}
+		top := pdf.GetY()
+		drawModules(pdf, modules, (pageWidth-qrSize)/2, top, qrSize)
+		pdf.SetY(top + qrSize + 6)
+
+		pdf.SetFont("Helvetica", "", 18)
+		pdf.MultiCell(width, 10, text(fmt.Sprintf("Scan this code in the Haoma app to start %s.", station.Title)), "", "C", false)
+		pdf.Ln(4)
+		// For typing in when a camera will not cooperate
+		pdf.SetFont("Courier", "", 9)
+		pdf.MultiCell(width, 5, station.Code, "", "C", false)
+	}
+
+	return pdf.Output(w)
+}
+
+func drawModules(pdf *gofpdf.Fpdf, modules [][]bool, left, top, size float64) {
+	cell := size / float64(len(modules))
+	pdf.SetFillColor(0, 0, 0)
+	for y, row := range modules {
+		for x := 0; x < len(row); x++ {
+			if !row[x] {
+				continue
+			}
+			// One rectangle per run of dark modules keeps the file small
+			run := 1
+			for x+run < len(row) && row[x+run] {
+				run++
+			}
+			pdf.Rect(left+float64(x)*cell, top+float64(y)*cell, float64(run)*cell, cell, "F")
+			x += run - 1
+		}
+	}
+}
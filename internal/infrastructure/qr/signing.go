@@ -0,0 +1,59 @@
+// Package qr produces what goes on the printed node QR codes
+package qr
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"haoma/internal/config"
+	"haoma/internal/domain/nodecode"
+)
+
+// SignerFromEnv builds the node code signer from NODE_CODE_SECRET. It
+// reports false when the secret is unset, meaning static codes are in use.
+func SignerFromEnv() (*nodecode.Signer, bool, error) {
+	secret := os.Getenv("NODE_CODE_SECRET")
+	if secret == "" {
+		return nil, false, nil
+	}
+
+	signer, err := nodecode.NewSigner([]byte(secret))
+	if err != nil {
+		return nil, false, err
+	}
+	return signer, true, nil
+}
+
+// RotatingNodesFromEnv reads ROTATING_NODES, a comma-separated list of node
+// numbers, or "all" for every node in the registry. Other nodes keep their
+// printed codes.
+func RotatingNodesFromEnv() ([]int, bool, error) {
+	value := os.Getenv("ROTATING_NODES")
+	if strings.TrimSpace(value) == "all" {
+		return nil, true, nil
+	}
+
+	var nodes []int
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		node, err := strconv.Atoi(item)
+		if err != nil || node < config.MIN_NODE_NUMBER || node > config.MAX_NODE_NUMBER {
+			return nil, false, fmt.Errorf("invalid ROTATING_NODES entry %q", item)
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes, false, nil
+}
+
+// RotationPeriodFromEnv reads NODE_CODE_ROTATION_SECONDS
+func RotationPeriodFromEnv() time.Duration {
+	if seconds, err := strconv.Atoi(os.Getenv("NODE_CODE_ROTATION_SECONDS")); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return config.NODE_CODE_ROTATION_PERIOD
+}
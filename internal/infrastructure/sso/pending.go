@@ -0,0 +1,68 @@
+package sso
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"sync"
+	"time"
+)
+
+// loginTimeout is how long a player has to finish logging in at the provider
+const loginTimeout = 10 * time.Minute
+
+type pendingLogin struct {
+	nonce        string
+	codeVerifier string
+	expiresAt    time.Time
+}
+
+// pendingLogins remembers logins that were sent to the provider. They live in
+// process memory, so the callback must reach the instance that started the
+// login.
+type pendingLogins struct {
+	mu     sync.Mutex
+	logins map[string]pendingLogin
+}
+
+func newPendingLogins() *pendingLogins {
+	return &pendingLogins{logins: make(map[string]pendingLogin)}
+}
+
+func (p *pendingLogins) put(state string, login pendingLogin) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	for key, existing := range p.logins {
+		if now.After(existing.expiresAt) {
+			delete(p.logins, key)
+		}
+	}
+
+	login.expiresAt = now.Add(loginTimeout)
+	p.logins[state] = login
+}
+
+func (p *pendingLogins) take(state string) (pendingLogin, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	login, ok := p.logins[state]
+	if !ok {
+		return pendingLogin{}, false
+	}
+	delete(p.logins, state)
+
+	if time.Now().After(login.expiresAt) {
+		return pendingLogin{}, false
+	}
+	return login, true
+}
+
+func randomString() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
@@ -0,0 +1,141 @@
+package sso
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+// Identity is what the identity provider vouches for about a user
+type Identity struct {
+	Issuer        string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// Config points at an OpenID Connect provider
+type Config struct {
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string // Our callback, e.g. http://localhost:8080/api/v1/auth/oidc/callback
+}
+
+// ConfigFromEnv reads OIDC_ISSUER_URL, OIDC_CLIENT_ID, OIDC_CLIENT_SECRET and
+// OIDC_REDIRECT_URL. It returns false when no issuer is configured.
+func ConfigFromEnv() (Config, bool) {
+	cfg := Config{
+		IssuerURL:    os.Getenv("OIDC_ISSUER_URL"),
+		ClientID:     os.Getenv("OIDC_CLIENT_ID"),
+		ClientSecret: os.Getenv("OIDC_CLIENT_SECRET"),
+		RedirectURL:  os.Getenv("OIDC_REDIRECT_URL"),
+	}
+	return cfg, cfg.IssuerURL != ""
+}
+
+// Provider runs the authorization code flow with PKCE against one issuer
+type Provider struct {
+	oauth    oauth2.Config
+	verifier *oidc.IDTokenVerifier
+	pending  *pendingLogins
+}
+
+// NewProvider discovers the issuer's endpoints and signing keys
+func NewProvider(ctx context.Context, cfg Config) (*Provider, error) {
+	if cfg.ClientID == "" || cfg.RedirectURL == "" {
+		return nil, errors.New("OIDC client ID and redirect URL are required")
+	}
+
+	provider, err := oidc.NewProvider(ctx, strings.TrimSuffix(cfg.IssuerURL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("discovering %s: %w", cfg.IssuerURL, err)
+	}
+
+	return &Provider{
+		oauth: oauth2.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			RedirectURL:  cfg.RedirectURL,
+			Endpoint:     provider.Endpoint(),
+			Scopes:       []string{oidc.ScopeOpenID, "email", "profile"},
+		},
+		verifier: provider.Verifier(&oidc.Config{ClientID: cfg.ClientID}),
+		pending:  newPendingLogins(),
+	}, nil
+}
+
+// Begin starts a login and returns the provider URL to send the browser to,
+// plus the state the callback must carry. The PKCE code verifier and nonce
+// stay on the server, keyed by state; only the S256 challenge goes to the
+// provider.
+func (p *Provider) Begin() (string, string, error) {
+	state, err := randomString()
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := randomString()
+	if err != nil {
+		return "", "", err
+	}
+	codeVerifier := oauth2.GenerateVerifier()
+
+	p.pending.put(state, pendingLogin{nonce: nonce, codeVerifier: codeVerifier})
+	return p.oauth.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(codeVerifier)), state, nil
+}
+
+// Complete finishes the login the provider redirected back with. Each state
+// can be completed once.
+func (p *Provider) Complete(ctx context.Context, state, code string) (*Identity, error) {
+	login, ok := p.pending.take(state)
+	if !ok {
+		return nil, errors.New("unknown or expired login state")
+	}
+
+	return p.exchange(ctx, code, login.codeVerifier, login.nonce)
+}
+
+// exchange trades the authorization code for an ID token and checks it was
+// issued to us for this login attempt
+func (p *Provider) exchange(ctx context.Context, code, codeVerifier, nonce string) (*Identity, error) {
+	token, err := p.oauth.Exchange(ctx, code, oauth2.VerifierOption(codeVerifier))
+	if err != nil {
+		return nil, fmt.Errorf("exchanging code: %w", err)
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, errors.New("provider returned no ID token")
+	}
+
+	idToken, err := p.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("verifying ID token: %w", err)
+	}
+	if idToken.Nonce != nonce {
+		return nil, errors.New("ID token nonce mismatch")
+	}
+
+	var claims struct {
+		Email         string `json:"email"`
+		EmailVerified bool   `json:"email_verified"`
+		Name          string `json:"name"`
+	}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("reading ID token claims: %w", err)
+	}
+
+	return &Identity{
+		Issuer:        idToken.Issuer,
+		Subject:       idToken.Subject,
+		Email:         claims.Email,
+		EmailVerified: claims.EmailVerified,
+		Name:          claims.Name,
+	}, nil
+}
@@ -0,0 +1,94 @@
+package sso
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"haoma/internal/infrastructure/sso/mockidp"
+)
+
+func newMockProvider(t *testing.T) *Provider {
+	t.Helper()
+
+	server := httptest.NewUnstartedServer(nil)
+	idp, err := mockidp.New("http://" + server.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to create mock IdP: %v", err)
+	}
+	server.Config.Handler = idp.Handler()
+	server.Start()
+	t.Cleanup(server.Close)
+
+	provider, err := NewProvider(context.Background(), Config{
+		IssuerURL:    server.URL,
+		ClientID:     "haoma",
+		ClientSecret: "secret",
+		RedirectURL:  "http://localhost:8080/api/v1/auth/oidc/callback",
+	})
+	if err != nil {
+		t.Fatalf("Failed to discover mock IdP: %v", err)
+	}
+	return provider
+}
+
+// approve follows the authorize URL as the mock IdP user and returns the
+// callback parameters
+func approve(t *testing.T, authURL, email string) url.Values {
+	t.Helper()
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(authURL + "&login_hint=" + url.QueryEscape(email))
+	if err != nil {
+		t.Fatalf("Authorize request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	callback, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil || resp.StatusCode != http.StatusFound {
+		t.Fatalf("Expected a redirect back to the callback, got %d", resp.StatusCode)
+	}
+	return callback.Query()
+}
+
+func TestProvider_CodeFlowWithPKCE(t *testing.T) {
+	provider := newMockProvider(t)
+
+	authURL, state, err := provider.Begin()
+	if err != nil {
+		t.Fatalf("Begin failed: %v", err)
+	}
+
+	params := approve(t, authURL, "rostam@ut.ac.ir")
+	if params.Get("state") != state {
+		t.Fatalf("Expected state %q, got %q", state, params.Get("state"))
+	}
+
+	identity, err := provider.Complete(context.Background(), state, params.Get("code"))
+	if err != nil {
+		t.Fatalf("Complete failed: %v", err)
+	}
+	if identity.Email != "rostam@ut.ac.ir" || !identity.EmailVerified {
+		t.Errorf("Expected verified rostam@ut.ac.ir, got %q (verified=%v)", identity.Email, identity.EmailVerified)
+	}
+	if identity.Subject == "" {
+		t.Error("Expected a subject")
+	}
+
+	// A state can only be completed once
+	if _, err := provider.Complete(context.Background(), state, params.Get("code")); err == nil {
+		t.Error("Expected replaying the callback to fail")
+	}
+}
+
+func TestProvider_UnknownState(t *testing.T) {
+	provider := newMockProvider(t)
+
+	if _, err := provider.Complete(context.Background(), "forged", "code"); err == nil {
+		t.Error("Expected an unknown state to be rejected")
+	}
+}
@@ -0,0 +1,133 @@
+package throttle
+
+import (
+	"sync"
+	"time"
+)
+
+// Policy describes how quickly repeated failures are slowed down and locked out
+type Policy struct {
+	FreeAttempts     int           // Failures allowed before any delay
+	BackoffBase      time.Duration // Delay after the first failure past FreeAttempts, doubled for each one after
+	BackoffMax       time.Duration
+	LockoutThreshold int // Failures that trigger a lockout
+	LockoutDuration  time.Duration
+	Window           time.Duration // Failures are forgotten after this long without a new one
+}
+
+// Delay returns how long a key must wait after its nth consecutive failure
+func (p Policy) Delay(failures int) time.Duration {
+	if failures >= p.LockoutThreshold {
+		return p.LockoutDuration
+	}
+	if failures <= p.FreeAttempts {
+		return 0
+	}
+
+	delay := p.BackoffBase
+	for i := p.FreeAttempts + 1; i < failures; i++ {
+		delay *= 2
+		if delay >= p.BackoffMax {
+			return p.BackoffMax
+		}
+	}
+	return delay
+}
+
+type record struct {
+	failures     int
+	lastFailure  time.Time
+	blockedUntil time.Time
+}
+
+// MemoryTracker counts failed attempts per key in process memory. Counts are
+// lost on restart and are not shared between instances.
+type MemoryTracker struct {
+	mu        sync.Mutex
+	policy    Policy
+	records   map[string]*record
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func NewMemoryTracker(policy Policy) *MemoryTracker {
+	return &MemoryTracker{
+		policy:  policy,
+		records: make(map[string]*record),
+		now:     time.Now,
+	}
+}
+
+// Reserve checks and counts an attempt in one step: a key that must back off
+// gets the wait, otherwise the attempt is counted as a failure up front, so
+// parallel attempts cannot all slip in before the first one fails. Release
+// takes back the attempt if it succeeds.
+func (t *MemoryTracker) Reserve(key string) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	if rec, exists := t.records[key]; exists {
+		if wait := rec.blockedUntil.Sub(now); wait > 0 {
+			return wait
+		}
+	}
+
+	t.recordFailure(key, now)
+	return 0
+}
+
+// Release uncounts an attempt reserved with Reserve that turned out fine
+func (t *MemoryTracker) Release(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	rec, exists := t.records[key]
+	if !exists {
+		return
+	}
+
+	rec.failures--
+	if rec.failures <= 0 {
+		delete(t.records, key)
+		return
+	}
+	rec.blockedUntil = rec.lastFailure.Add(t.policy.Delay(rec.failures))
+}
+
+// Reset forgets a key's failures, e.g. after a successful login
+func (t *MemoryTracker) Reset(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.records, key)
+}
+
+func (t *MemoryTracker) recordFailure(key string, now time.Time) {
+	t.sweep(now)
+
+	rec, exists := t.records[key]
+	if !exists || now.Sub(rec.lastFailure) > t.policy.Window {
+		rec = &record{}
+		t.records[key] = rec
+	}
+
+	rec.failures++
+	rec.lastFailure = now
+	rec.blockedUntil = now.Add(t.policy.Delay(rec.failures))
+}
+
+// sweep drops keys that have been quiet for a whole window so the map does
+// not grow without bound. It runs at most once per window.
+func (t *MemoryTracker) sweep(now time.Time) {
+	if now.Sub(t.lastSweep) < t.policy.Window {
+		return
+	}
+	t.lastSweep = now
+
+	for key, rec := range t.records {
+		if now.Sub(rec.lastFailure) > t.policy.Window && now.After(rec.blockedUntil) {
+			delete(t.records, key)
+		}
+	}
+}
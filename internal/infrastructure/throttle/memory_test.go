@@ -0,0 +1,177 @@
+package throttle
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var testPolicy = Policy{
+	FreeAttempts:     3,
+	BackoffBase:      time.Second,
+	BackoffMax:       20 * time.Second,
+	LockoutThreshold: 10,
+	LockoutDuration:  15 * time.Minute,
+	Window:           time.Hour,
+}
+
+func TestPolicy_Delay(t *testing.T) {
+	tests := []struct {
+		failures int
+		expected time.Duration
+	}{
+		{1, 0},
+		{3, 0},
+		{4, time.Second},
+		{5, 2 * time.Second},
+		{6, 4 * time.Second},
+		{8, 16 * time.Second},
+		{9, 20 * time.Second}, // 32s is past the cap
+		{10, 15 * time.Minute},
+		{25, 15 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		if delay := testPolicy.Delay(tt.failures); delay != tt.expected {
+			t.Errorf("Expected delay %v after %d failures, got %v", tt.expected, tt.failures, delay)
+		}
+	}
+}
+
+func TestMemoryTracker_BackoffAndReset(t *testing.T) {
+	now := time.Now()
+	tracker := NewMemoryTracker(testPolicy)
+	tracker.now = func() time.Time { return now }
+
+	for i := 0; i < 4; i++ {
+		if wait := tracker.Reserve("rostam@haoma.dev"); wait != 0 {
+			t.Errorf("Expected no backoff within the free attempts, got %v", wait)
+		}
+	}
+
+	if wait := tracker.Reserve("rostam@haoma.dev"); wait != time.Second {
+		t.Errorf("Expected 1s backoff, got %v", wait)
+	}
+	if wait := tracker.Reserve("sohrab@haoma.dev"); wait != 0 {
+		t.Errorf("Expected other keys to be unaffected, got %v", wait)
+	}
+
+	now = now.Add(2 * time.Second)
+	if wait := tracker.Reserve("rostam@haoma.dev"); wait != 0 {
+		t.Errorf("Expected backoff to have passed, got %v", wait)
+	}
+	if wait := tracker.Reserve("rostam@haoma.dev"); wait != 2*time.Second {
+		t.Errorf("Expected the backoff to double, got %v", wait)
+	}
+
+	tracker.Reset("rostam@haoma.dev")
+	for i := 0; i < 4; i++ {
+		if wait := tracker.Reserve("rostam@haoma.dev"); wait != 0 {
+			t.Errorf("Expected a reset key to start over, got %v", wait)
+		}
+	}
+}
+
+func TestMemoryTracker_WindowForgetsFailures(t *testing.T) {
+	now := time.Now()
+	tracker := NewMemoryTracker(testPolicy)
+	tracker.now = func() time.Time { return now }
+
+	// Spaced out past each backoff, one short of the lockout
+	for i := 0; i < 9; i++ {
+		if wait := tracker.Reserve("10.0.0.7"); wait != 0 {
+			t.Fatalf("Expected reservation %d to go through, got %v", i+1, wait)
+		}
+		now = now.Add(time.Minute)
+	}
+
+	now = now.Add(2 * time.Hour)
+	tracker.Reserve("10.0.0.7")
+	if wait := tracker.Reserve("10.0.0.7"); wait != 0 {
+		t.Errorf("Expected failures outside the window to be forgotten, got %v", wait)
+	}
+}
+
+func TestMemoryTracker_ConcurrentReservations(t *testing.T) {
+	tracker := NewMemoryTracker(testPolicy)
+
+	var admitted atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if tracker.Reserve("rostam@haoma.dev") == 0 {
+				admitted.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Only the free attempts and the one that triggers the backoff get in
+	if got := admitted.Load(); got != int32(testPolicy.FreeAttempts+1) {
+		t.Errorf("Expected %d reservations to go through, got %d", testPolicy.FreeAttempts+1, got)
+	}
+}
+
+func TestMemoryTracker_ConcurrentReservationsLockOut(t *testing.T) {
+	now := time.Now()
+	tracker := NewMemoryTracker(Policy{
+		FreeAttempts:     100, // No backoff, so only the lockout stops them
+		LockoutThreshold: 10,
+		LockoutDuration:  15 * time.Minute,
+		Window:           time.Hour,
+	})
+	tracker.now = func() time.Time { return now }
+
+	var admitted atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if tracker.Reserve("10.0.0.7") == 0 {
+				admitted.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := admitted.Load(); got != 10 {
+		t.Errorf("Expected exactly the lockout threshold of 10 to go through, got %d", got)
+	}
+	if wait := tracker.Reserve("10.0.0.7"); wait != 15*time.Minute {
+		t.Errorf("Expected the key to be locked out, got %v", wait)
+	}
+}
+
+func TestMemoryTracker_ReserveCountsUpFront(t *testing.T) {
+	now := time.Now()
+	tracker := NewMemoryTracker(testPolicy)
+	tracker.now = func() time.Time { return now }
+
+	// Parallel guesses reserve before any of them has failed
+	for i := 0; i < 4; i++ {
+		if wait := tracker.Reserve("10.0.0.7"); wait != 0 {
+			t.Errorf("Expected reservation %d to go through, got %v", i+1, wait)
+		}
+	}
+	if wait := tracker.Reserve("10.0.0.7"); wait != time.Second {
+		t.Errorf("Expected the fifth reservation to back off 1s, got %v", wait)
+	}
+
+	tracker.Release("10.0.0.7")
+	now = now.Add(time.Second)
+	if wait := tracker.Reserve("10.0.0.7"); wait != 0 {
+		t.Errorf("Expected a released attempt to free a slot, got %v", wait)
+	}
+
+	tracker.Release("10.0.0.7")
+	tracker.Release("10.0.0.7")
+	tracker.Release("10.0.0.7")
+	tracker.Release("10.0.0.7")
+	if wait := tracker.Reserve("10.0.0.7"); wait != 0 {
+		t.Errorf("Expected releasing every attempt to clear the key, got %v", wait)
+	}
+}
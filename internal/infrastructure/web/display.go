@@ -0,0 +1,56 @@
+package web
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+	"os"
+
+	"github.com/gin-gonic/gin"
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// The projector page ships inside the binary because venue Wi-Fi cannot be
+// trusted to reach a CDN
+//
+//go:embed display
+var displayFiles embed.FS
+
+const signupQRSize = 512 // pixels
+
+// RegisterDisplay serves the full-screen leaderboard page for the hall
+// projector and the station screen page
+func RegisterDisplay(router *gin.Engine) {
+	assets, err := fs.Sub(displayFiles, "display")
+	if err != nil {
+		panic(err) // The embedded directory is part of the build
+	}
+
+	router.GET("/display", func(c *gin.Context) {
+		c.FileFromFS("/", http.FS(assets))
+	})
+	router.StaticFS("/display/assets", http.FS(assets))
+	router.GET("/display/signup-qr.png", signupQRHandler)
+
+	// Station screens showing a node's rotating code
+	router.GET("/station", func(c *gin.Context) {
+		c.FileFromFS("/station.html", http.FS(assets))
+	})
+}
+
+func signupQRHandler(c *gin.Context) {
+	signupURL := os.Getenv("SIGNUP_URL")
+	if signupURL == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "SIGNUP_URL is not configured"})
+		return
+	}
+
+	png, err := qrcode.Encode(signupURL, qrcode.Medium, signupQRSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate QR code"})
+		return
+	}
+
+	c.Header("Cache-Control", "public, max-age=3600")
+	c.Data(http.StatusOK, "image/png", png)
+}
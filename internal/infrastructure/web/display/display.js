@@ -0,0 +1,206 @@
+(function () {
+  "use strict";
+
+  var STREAM_URL = "/api/v1/leaderboard/stream";
+  var EVENT_URL = "/api/v1/events/current";
+  var EVENT_REFRESH_MS = 60 * 1000;
+
+  var list = document.getElementById("entries");
+  var empty = document.getElementById("empty");
+  var connection = document.getElementById("connection");
+  var frozenBadge = document.getElementById("frozen-badge");
+  var countdown = document.getElementById("countdown");
+  var countdownLabel = document.getElementById("countdown-label");
+
+  var endsAt = null;
+  var previousRanks = {};
+
+  // Players may share a display name, so repeat names get an occurrence suffix
+  function keysFor(entries) {
+    var seen = {};
+    return entries.map(function (entry) {
+      seen[entry.player_name] = (seen[entry.player_name] || 0) + 1;
+      return entry.player_name + "#" + seen[entry.player_name];
+    });
+  }
+
+  function formatDuration(value) {
+    // Go durations look like "1h2m3.45s"; keep whole units only
+    var match = /^(?:(\d+)h)?(?:(\d+)m)?(?:([\d.]+)s)?$/.exec(value || "");
+    if (!match) {
+      return value;
+    }
+    var hours = parseInt(match[1] || "0", 10);
+    var minutes = parseInt(match[2] || "0", 10);
+    var seconds = Math.floor(parseFloat(match[3] || "0"));
+    var total = hours * 60 + minutes;
+    return total + ":" + String(seconds).padStart(2, "0");
+  }
+
+  function renderRow(row, entry, delta) {
+    row.innerHTML = "";
+
+    var rank = document.createElement("span");
+    rank.className = "rank";
+    rank.textContent = entry.rank_label || entry.rank;
+
+    var name = document.createElement("span");
+    name.className = "name";
+    name.textContent = entry.player_name;
+
+    if (delta !== 0) {
+      var arrow = document.createElement("span");
+      arrow.className = "delta " + (delta > 0 ? "up" : "down");
+      arrow.textContent = (delta > 0 ? "▲" : "▼") + Math.abs(delta);
+      name.appendChild(arrow);
+      requestAnimationFrame(function () {
+        arrow.classList.add("show");
+      });
+      setTimeout(function () {
+        arrow.classList.remove("show");
+      }, 6000);
+    }
+
+    var score = document.createElement("span");
+    score.className = "score";
+    score.textContent = entry.final_score;
+
+    var time = document.createElement("span");
+    time.className = "time";
+    time.textContent = formatDuration(entry.completion_time);
+
+    row.appendChild(rank);
+    row.appendChild(name);
+    row.appendChild(score);
+    row.appendChild(time);
+  }
+
+  // FLIP: remember where rows were, re-render, then animate from old to new
+  function renderBoard(board) {
+    var entries = board.entries || [];
+    var keys = keysFor(entries);
+
+    var oldTops = {};
+    Array.prototype.forEach.call(list.children, function (row) {
+      oldTops[row.dataset.key] = row.getBoundingClientRect().top;
+    });
+
+    var existing = {};
+    Array.prototype.forEach.call(list.children, function (row) {
+      existing[row.dataset.key] = row;
+    });
+
+    var fragment = document.createDocumentFragment();
+    var nextRanks = {};
+    entries.forEach(function (entry, i) {
+      var key = keys[i];
+      var row = existing[key] || document.createElement("li");
+      row.className = "row";
+      row.dataset.key = key;
+
+      var before = previousRanks[key];
+      var delta = before === undefined ? 0 : before - entry.rank;
+      renderRow(row, entry, delta);
+      if (before === undefined && Object.keys(previousRanks).length > 0) {
+        row.classList.add("flash");
+      }
+
+      nextRanks[key] = entry.rank;
+      fragment.appendChild(row);
+    });
+
+    list.innerHTML = "";
+    list.appendChild(fragment);
+    previousRanks = nextRanks;
+
+    Array.prototype.forEach.call(list.children, function (row) {
+      var oldTop = oldTops[row.dataset.key];
+      if (oldTop === undefined) {
+        return;
+      }
+      var shift = oldTop - row.getBoundingClientRect().top;
+      if (shift === 0) {
+        return;
+      }
+      row.classList.remove("moved");
+      row.style.transform = "translateY(" + shift + "px)";
+      requestAnimationFrame(function () {
+        row.classList.add("moved");
+        row.style.transform = "";
+      });
+    });
+
+    empty.hidden = entries.length > 0;
+    frozenBadge.hidden = !board.frozen;
+  }
+
+  function tick() {
+    if (!endsAt) {
+      countdown.textContent = "--:--:--";
+      return;
+    }
+
+    var remaining = Math.max(0, Math.floor((endsAt - Date.now()) / 1000));
+    if (remaining === 0) {
+      countdownLabel.textContent = "Closed";
+    } else {
+      countdownLabel.textContent = "Closes in";
+    }
+
+    var hours = Math.floor(remaining / 3600);
+    var minutes = Math.floor((remaining % 3600) / 60);
+    var seconds = remaining % 60;
+    countdown.textContent = [hours, minutes, seconds]
+      .map(function (part) {
+        return String(part).padStart(2, "0");
+      })
+      .join(":");
+  }
+
+  function refreshEvent() {
+    fetch(EVENT_URL)
+      .then(function (response) {
+        return response.ok ? response.json() : null;
+      })
+      .then(function (currentEvent) {
+        if (!currentEvent) {
+          return;
+        }
+        document.getElementById("event-title").textContent = currentEvent.title;
+        document.title = currentEvent.title + " — Haoma";
+        endsAt = Date.parse(currentEvent.ends_at);
+        tick();
+      })
+      .catch(function () {
+        // Keep the last known event while the network recovers
+      });
+  }
+
+  function connect() {
+    var source = new EventSource(STREAM_URL);
+
+    source.addEventListener("leaderboard", function (message) {
+      renderBoard(JSON.parse(message.data));
+    });
+
+    source.onopen = function () {
+      connection.textContent = "live";
+      connection.className = "connection live";
+    };
+
+    // EventSource reconnects on its own; just show that we are behind
+    source.onerror = function () {
+      connection.textContent = "reconnecting…";
+      connection.className = "connection lost";
+    };
+  }
+
+  document.getElementById("signup-qr").addEventListener("error", function () {
+    document.getElementById("signup").hidden = true;
+  });
+
+  refreshEvent();
+  setInterval(refreshEvent, EVENT_REFRESH_MS);
+  setInterval(tick, 1000);
+  connect();
+})();
@@ -0,0 +1,79 @@
+(function () {
+  "use strict";
+
+  // The page is opened as /station?node=3#key=..., the link printed by
+  // cmd/node-codes for each station screen. The key stays in the fragment and
+  // travels in a header, so it never shows up in a URL the server logs.
+  var params = new URLSearchParams(window.location.search);
+  var fragment = new URLSearchParams(window.location.hash.slice(1));
+  var node = params.get("node");
+  var key = fragment.get("key") || "";
+  var base = "/api/v1/stations/" + encodeURIComponent(node) + "/code";
+  var headers = { "X-Station-Key": key };
+  var RETRY_MS = 5 * 1000;
+
+  var image = document.getElementById("code");
+  var countdown = document.getElementById("countdown");
+  var error = document.getElementById("error");
+  var rotatesAt = null;
+
+  document.getElementById("node").textContent = node || "?";
+
+  function showError(message) {
+    error.textContent = message;
+    error.hidden = false;
+  }
+
+  function load(url) {
+    return fetch(url, { cache: "no-store", headers: headers }).then(function (response) {
+      if (response.ok) {
+        return response;
+      }
+      return response.json().then(function (body) {
+        throw new Error(body.error || "Failed to load the station code");
+      });
+    });
+  }
+
+  // An <img> cannot send headers, so the image is fetched and shown as a blob
+  function showImage(response) {
+    return response.blob().then(function (blob) {
+      var previous = image.src;
+      image.src = URL.createObjectURL(blob);
+      if (previous.indexOf("blob:") === 0) {
+        URL.revokeObjectURL(previous);
+      }
+    });
+  }
+
+  function refresh() {
+    load(base)
+      .then(function (response) {
+        return response.json();
+      })
+      .then(function (body) {
+        // The image is rendered from the same code, so a brief mismatch at
+        // the boundary is covered by the server's skew window
+        return load(base + ".png").then(showImage).then(function () {
+          return body;
+        });
+      })
+      .then(function (body) {
+        error.hidden = true;
+        rotatesAt = new Date(body.rotates_at);
+        setTimeout(refresh, Math.max(rotatesAt - Date.now(), 250));
+      })
+      .catch(function (err) {
+        showError(err.message);
+        setTimeout(refresh, RETRY_MS);
+      });
+  }
+
+  setInterval(function () {
+    if (rotatesAt) {
+      countdown.textContent = Math.max(0, Math.ceil((rotatesAt - Date.now()) / 1000)) + "s";
+    }
+  }, 250);
+
+  refresh();
+})();
@@ -1,24 +1,36 @@
 package web
 
 import (
+	"os"
+	"strings"
+
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 )
 
-func NewRouter() *gin.Engine {
+func NewRouter() (*gin.Engine, error) {
 	gin.SetMode(gin.ReleaseMode)
 
 	router := gin.New()
 
+	// Client IPs feed login throttling and the audit trail, so forwarded
+	// headers only count when a known proxy sets them
+	if err := router.SetTrustedProxies(TrustedProxiesFromEnv()); err != nil {
+		return nil, err
+	}
+
 	// Middleware
 	router.Use(gin.Logger())
 	router.Use(gin.Recovery())
-	router.Use(CORSMiddleware())
+	router.Use(CORSMiddleware(AllowedOriginsFromEnv()))
 
 	// Swagger endpoint
 	router.GET("/docs/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
+	// Projector leaderboard page
+	RegisterDisplay(router)
+
 	// Health check
 	router.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{
@@ -27,15 +39,56 @@ func NewRouter() *gin.Engine {
 		})
 	})
 
-	return router
+	return router, nil
+}
+
+// TrustedProxiesFromEnv reads TRUSTED_PROXIES, a comma-separated list of IPs
+// or CIDR ranges of reverse proxies whose X-Forwarded-For is believed. Empty
+// trusts none and uses the connecting address.
+func TrustedProxiesFromEnv() []string {
+	var proxies []string
+	for _, proxy := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+	return proxies
 }
 
-func CORSMiddleware() gin.HandlerFunc {
+// AllowedOriginsFromEnv reads CORS_ALLOWED_ORIGINS, a comma-separated list
+// of web client origins such as https://carnival.example.edu
+func AllowedOriginsFromEnv() []string {
+	var origins []string
+	for _, origin := range strings.Split(os.Getenv("CORS_ALLOWED_ORIGINS"), ",") {
+		if origin = strings.TrimRight(strings.TrimSpace(origin), "/"); origin != "" {
+			origins = append(origins, origin)
+		}
+	}
+	return origins
+}
+
+// CORSMiddleware lets browsers call the API from other origins. Without an
+// allow-list any origin may call it, but without credentials, so cookie mode
+// only works from listed origins (or the API's own).
+func CORSMiddleware(allowedOrigins []string) gin.HandlerFunc {
+	allowed := make(map[string]bool, len(allowedOrigins))
+	for _, origin := range allowedOrigins {
+		allowed[origin] = true
+	}
+
 	return func(c *gin.Context) {
-		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
-		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE")
+		header := c.Writer.Header()
+		if len(allowed) == 0 {
+			header.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			header.Add("Vary", "Origin")
+			if origin := c.GetHeader("Origin"); allowed[origin] {
+				header.Set("Access-Control-Allow-Origin", origin)
+				header.Set("Access-Control-Allow-Credentials", "true")
+			}
+		}
+		header.Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, X-Auth-Mode, Authorization, accept, origin, Cache-Control, X-Requested-With")
+		header.Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, PATCH, DELETE")
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)